@@ -2,7 +2,9 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"strings"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
@@ -21,13 +23,51 @@ type Config struct {
 
 	CosmosGRPCAddr string `json:"cosmos_grpc_addr" envconfig:"COSMOS_GRPC_ADDR"`
 	ChainID        string `json:"chain_id" envconfig:"CHAIN_ID"`
+	// Chains - additional chains served by the worker in form of "chainID=grpcAddr,chainID2=grpcAddr2"
+	Chains string `json:"chains" envconfig:"CHAINS"`
 
 	ManagerURL string `json:"managers" envconfig:"MANAGER_URL" default:"ws://0.0.0.0:8085"`
 
+	// ManagerTransport of the manager connection, ws (websocket on ManagerURL), grpc (on ManagerGRPCAddr)
+	// or http - pull mode, worker doesn't connect to manager and serves the data on HTTPAddress instead
+	ManagerTransport string `json:"manager_transport" envconfig:"MANAGER_TRANSPORT" default:"ws"`
+	ManagerGRPCAddr  string `json:"manager_grpc_addr" envconfig:"MANAGER_GRPC_ADDR" default:"0.0.0.0:8086"`
+	HTTPAddress      string `json:"http_address" envconfig:"HTTP_ADDRESS" default:"0.0.0.0:8087"`
+
+	// Credentials of the manager connection, HMAC signature is used if the key is set
+	ManagerAuthToken  string `json:"manager_auth_token" envconfig:"MANAGER_AUTH_TOKEN"`
+	ManagerAuthKey    string `json:"manager_auth_key" envconfig:"MANAGER_AUTH_KEY"`
+	ManagerAuthSecret string `json:"manager_auth_secret" envconfig:"MANAGER_AUTH_SECRET"`
+
+	// ManagerEncoding of the manager connection (jsonrpc-json or jsonrpc-cbor)
+	ManagerEncoding    string `json:"manager_encoding" envconfig:"MANAGER_ENCODING" default:"jsonrpc-json"`
+	ManagerCompression bool   `json:"manager_compression" envconfig:"MANAGER_COMPRESSION" default:"false"`
+
 	TimeoutBlockCall       time.Duration `json:"timeout_block_call" envconfig:"TIMEOUT_BLOCK_CALL" default:"30s"`
 	TimeoutTransactionCall time.Duration `json:"timeout_transaction_call" envconfig:"TIMEOUT_TRANSACTION_CALL" default:"30s"`
 }
 
+// ChainAddrs returns the grpc addresses of all the chains served by the worker
+func (c *Config) ChainAddrs() (map[string]string, error) {
+	addrs := make(map[string]string)
+	if c.CosmosGRPCAddr != "" {
+		addrs[c.ChainID] = c.CosmosGRPCAddr
+	}
+
+	if c.Chains == "" {
+		return addrs, nil
+	}
+
+	for _, ch := range strings.Split(c.Chains, ",") {
+		kv := strings.SplitN(strings.TrimSpace(ch), "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("malformed chain definition %q, expected chainID=grpcAddr", ch)
+		}
+		addrs[kv[0]] = kv[1]
+	}
+	return addrs, nil
+}
+
 // FromFile reads the config from a file
 func FromFile(path string, config *Config) error {
 	data, err := ioutil.ReadFile(path)
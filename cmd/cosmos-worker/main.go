@@ -4,19 +4,32 @@ import (
 	"context"
 	"flag"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/figment-networks/graph-demo/cmd/common/logger"
 	"github.com/figment-networks/graph-demo/cmd/cosmos-worker/config"
+	"github.com/figment-networks/graph-demo/connectivity/auth"
+	wsapi "github.com/figment-networks/graph-demo/connectivity/ws"
+	apiTransportGRPC "github.com/figment-networks/graph-demo/cosmos-worker/api/transport/grpc"
+	apiTransportHTTP "github.com/figment-networks/graph-demo/cosmos-worker/api/transport/http"
 	apiTransportWS "github.com/figment-networks/graph-demo/cosmos-worker/api/transport/ws"
 	"github.com/figment-networks/graph-demo/cosmos-worker/client"
+	"github.com/figment-networks/graph-demo/manager/structs"
 
 	"go.uber.org/zap"
 	grpc "google.golang.org/grpc"
 )
 
+// managerTransport connects worker to the manager and persists fetched data through it
+type managerTransport interface {
+	Connect(ctx context.Context, address, name string, opts wsapi.DialOptions) error
+	StoreBlock(ctx context.Context, block structs.Block) error
+	StoreTransactions(ctx context.Context, txs []structs.Transaction) error
+}
+
 type flags struct {
 	configPath string
 }
@@ -47,36 +60,78 @@ func main() {
 
 	log := logger.GetLogger()
 
-	if cfg.CosmosGRPCAddr == "" {
-		log.Error("cosmos grpc address is not set")
+	chains, err := cfg.ChainAddrs()
+	if err != nil {
+		log.Error("error reading chains", zap.Error(err))
 		return
 	}
-	grpcConn, dialErr := grpc.DialContext(ctx, cfg.CosmosGRPCAddr, grpc.WithInsecure())
-	if dialErr != nil {
-		log.Error("error dialing grpc: %w", zap.Error(dialErr))
+
+	if len(chains) == 0 {
+		log.Error("cosmos grpc address is not set")
 		return
 	}
-	defer grpcConn.Close()
 
 	cliCfg := &client.ClientConfig{
 		TimeoutBlockCall:    cfg.TimeoutBlockCall,
 		TimeoutSearchTxCall: cfg.TimeoutTransactionCall,
 	}
 
-	apiClient := client.NewClient(logger.GetLogger(), grpcConn, cliCfg)
-	wstr := apiTransportWS.NewProcessHandler(logger.GetLogger(), apiClient)
-	apiClient.LinkPersistor(wstr)
+	apiClients := make(map[string]*client.Client, len(chains))
+	for chainID, addr := range chains {
+		grpcConn, dialErr := grpc.DialContext(ctx, addr, grpc.WithInsecure())
+		if dialErr != nil {
+			log.Error("error dialing grpc: %w", zap.Error(dialErr), zap.String("chainID", chainID))
+			return
+		}
+		defer grpcConn.Close()
 
-	if err := wstr.Connect(ctx, cfg.ManagerURL); err != nil {
-		log.Error("error connecting to manager ", zap.Error(err), zap.String("address", cfg.ManagerURL))
-		return
+		apiClients[chainID] = client.NewClient(logger.GetLogger(), grpcConn, cliCfg)
 	}
 
-	if err := wstr.Register(ctx, cfg.ChainID); err != nil {
-		log.Error("error registering  to manager ", zap.Error(err), zap.String("chainID", cfg.ChainID))
+	var (
+		tr      managerTransport
+		address string
+	)
+	switch cfg.ManagerTransport {
+	case "grpc":
+		clients := make(map[string]apiTransportGRPC.CosmosClient, len(apiClients))
+		for chainID, c := range apiClients {
+			clients[chainID] = c
+		}
+		tr, address = apiTransportGRPC.NewProcessHandler(logger.GetLogger(), clients), cfg.ManagerGRPCAddr
+	case "http":
+		clients := make(map[string]apiTransportHTTP.CosmosClient, len(apiClients))
+		for chainID, c := range apiClients {
+			clients[chainID] = c
+		}
+		mux := http.NewServeMux()
+		apiTransportHTTP.NewHandler(clients).AttachToMux(mux)
+		s := &http.Server{Addr: cfg.HTTPAddress, Handler: mux}
+		defer s.Shutdown(ctx)
+		go func() {
+			log.Info("serving data in pull mode", zap.String("address", cfg.HTTPAddress))
+			if err := s.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error("error serving http", zap.Error(err))
+			}
+		}()
+	case "ws", "":
+		clients := make(map[string]apiTransportWS.CosmosClient, len(apiClients))
+		for chainID, c := range apiClients {
+			clients[chainID] = c
+		}
+		tr, address = apiTransportWS.NewProcessHandler(logger.GetLogger(), clients), cfg.ManagerURL
+	default:
+		log.Error("unknown manager transport", zap.String("transport", cfg.ManagerTransport))
 		return
 	}
 
+	// in pull mode manager stores the data itself
+	if tr != nil {
+		if err := connect(ctx, log, cfg, tr, address, apiClients); err != nil {
+			return
+		}
+	}
+
 	osSig := make(chan os.Signal, 1)
 	signal.Notify(osSig, syscall.SIGTERM)
 	signal.Notify(osSig, syscall.SIGINT)
@@ -86,6 +141,31 @@ func main() {
 	logger.Info("Canceled context, gracefully stopping grpc")
 }
 
+// connect links clients with the manager transport and connects to the manager
+func connect(ctx context.Context, log *zap.Logger, cfg *config.Config, tr managerTransport, address string, apiClients map[string]*client.Client) error {
+	for _, apiClient := range apiClients {
+		apiClient.LinkPersistor(tr)
+	}
+
+	onState := func(state wsapi.ConnState, err error) {
+		log.Info("manager connection state changed", zap.String("state", string(state)), zap.String("address", address), zap.Error(err))
+	}
+
+	creds := auth.ClientCredentials{Token: cfg.ManagerAuthToken, Key: cfg.ManagerAuthKey, Secret: cfg.ManagerAuthSecret}
+	if err := tr.Connect(ctx, address, config.Name, wsapi.DialOptions{
+		Header:      creds.Header,
+		Encoding:    cfg.ManagerEncoding,
+		Compression: cfg.ManagerCompression,
+		OnState:     onState,
+		Version:     config.Version,
+	}); err != nil {
+		log.Error("error connecting to manager ", zap.Error(err), zap.String("address", address))
+		return err
+	}
+
+	return nil
+}
+
 func initConfig(path string) (*config.Config, error) {
 	cfg := &config.Config{}
 	if path != "" {
@@ -94,7 +174,7 @@ func initConfig(path string) (*config.Config, error) {
 		}
 	}
 
-	if cfg.CosmosGRPCAddr != "" {
+	if cfg.CosmosGRPCAddr != "" || cfg.Chains != "" {
 		return cfg, nil
 	}
 
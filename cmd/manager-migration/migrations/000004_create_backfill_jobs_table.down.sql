@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_bfj_chain_status;
+
+DROP TABLE IF EXISTS backfill_jobs;
@@ -0,0 +1,22 @@
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+CREATE TABLE IF NOT EXISTS backfill_jobs
+(
+    id         uuid DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE,
+
+    chain_id    VARCHAR(100) NOT NULL,
+    height_from DECIMAL(65, 0) NOT NULL,
+    height_to   DECIMAL(65, 0) NOT NULL,
+    current     DECIMAL(65, 0) NOT NULL,
+    status      VARCHAR(20) NOT NULL,
+
+    last_error  TEXT,
+    error_count DECIMAL(65, 0) NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (id)
+);
+
+
+CREATE INDEX idx_bfj_chain_status on backfill_jobs (chain_id, status);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_tx_ev_parties;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS parties;
@@ -0,0 +1,3 @@
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS parties TEXT[];
+
+CREATE INDEX IF NOT EXISTS idx_tx_ev_parties on transactions USING GIN (parties);
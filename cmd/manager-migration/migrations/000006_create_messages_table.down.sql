@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_msg_ch_module_height;
+DROP INDEX IF EXISTS idx_msg_ch_type_height;
+DROP INDEX IF EXISTS idx_msg_ch_height;
+DROP INDEX IF EXISTS idx_msg_tx;
+
+DROP TABLE IF EXISTS messages;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS messages
+(
+    id         uuid DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    chain_id    VARCHAR(100) NOT NULL,
+    height      DECIMAL(65, 0) NOT NULL,
+    tx_hash     TEXT NOT NULL,
+    msg_index   INT NOT NULL,
+    type_url    TEXT NOT NULL,
+    module      TEXT NOT NULL,
+    value       JSONB,
+
+    PRIMARY KEY (id)
+);
+
+
+CREATE UNIQUE INDEX idx_msg_tx on messages (chain_id, tx_hash, msg_index);
+CREATE INDEX idx_msg_ch_height on messages (chain_id, height);
+CREATE INDEX idx_msg_ch_type_height on messages (chain_id, type_url, height);
+CREATE INDEX idx_msg_ch_module_height on messages (chain_id, module, height);
@@ -0,0 +1,2 @@
+ALTER TABLE progress DROP COLUMN IF EXISTS slimmed_below;
+ALTER TABLE progress DROP COLUMN IF EXISTS pruned_below;
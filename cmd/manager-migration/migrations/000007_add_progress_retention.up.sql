@@ -0,0 +1,2 @@
+ALTER TABLE progress ADD COLUMN IF NOT EXISTS pruned_below DECIMAL(65, 0) NOT NULL DEFAULT 0;
+ALTER TABLE progress ADD COLUMN IF NOT EXISTS slimmed_below DECIMAL(65, 0) NOT NULL DEFAULT 0;
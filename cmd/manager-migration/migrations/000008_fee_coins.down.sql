@@ -0,0 +1,9 @@
+-- only the first coin of fee is kept
+UPDATE transactions
+SET auth_info = jsonb_set(jsonb_set(auth_info, '{fee,currency}', COALESCE(auth_info->'fee'->'amount'->0->'denom', '""'::jsonb)),
+    '{fee,amount}', auth_info->'fee'->'amount'->0->'amount')
+WHERE jsonb_typeof(auth_info->'fee'->'amount') = 'array' AND jsonb_array_length(auth_info->'fee'->'amount') > 0;
+
+UPDATE transactions
+SET auth_info = jsonb_set(auth_info #- '{fee,amount}', '{fee,currency}', '""'::jsonb)
+WHERE jsonb_typeof(auth_info->'fee') = 'object' AND NOT auth_info->'fee' ? 'currency';
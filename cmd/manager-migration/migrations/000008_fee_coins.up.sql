@@ -0,0 +1,9 @@
+-- single fee amount and currency become the list of coins
+UPDATE transactions
+SET auth_info = jsonb_set(auth_info #- '{fee,currency}', '{fee,amount}',
+    jsonb_build_array(jsonb_build_object('denom', COALESCE(auth_info->'fee'->>'currency', ''), 'amount', auth_info->'fee'->'amount')))
+WHERE jsonb_typeof(auth_info->'fee'->'amount') = 'number';
+
+UPDATE transactions
+SET auth_info = auth_info #- '{fee,currency}'
+WHERE auth_info->'fee' ? 'currency';
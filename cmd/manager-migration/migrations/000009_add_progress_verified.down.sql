@@ -0,0 +1 @@
+ALTER TABLE progress DROP COLUMN IF EXISTS verified_below;
@@ -0,0 +1 @@
+ALTER TABLE progress ADD COLUMN IF NOT EXISTS verified_below DECIMAL(65, 0) NOT NULL DEFAULT 0;
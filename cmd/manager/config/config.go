@@ -2,8 +2,13 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/figment-networks/graph-demo/manager/structs"
 	"github.com/kelseyhightower/envconfig"
 )
 
@@ -21,10 +26,109 @@ const (
 
 // Config holds the configuration data
 type Config struct {
-	AppEnv        string `json:"app_env" envconfig:"APP_ENV" default:"development"`
-	Address       string `json:"address" envconfig:"ADDRESS" default:"127.0.0.1:8085"`
-	DatabaseURL   string `json:"database_url" envconfig:"DATABASE_URL"`
-	LowestHeights string `json:"lowest_heights" envconfig:"LOWEST_HEIGHTS"`
+	AppEnv  string `json:"app_env" envconfig:"APP_ENV" default:"development"`
+	Address string `json:"address" envconfig:"ADDRESS" default:"127.0.0.1:8085"`
+	// GRPCAddress is an address of gRPC server for workers, disabled if empty. Websocket endpoint for workers is always served.
+	GRPCAddress string `json:"grpc_address" envconfig:"GRPC_ADDRESS"`
+	// HTTPWorkers - workers in pull mode, manager fetches the data from them over HTTP and stores it itself.
+	// In form of "chainID=http://worker:8087,chainID2=http://worker2:8087"
+	HTTPWorkers string `json:"http_workers" envconfig:"HTTP_WORKERS"`
+	// HTTPWorkerTimeout is a timeout of a single request to pull-mode worker
+	HTTPWorkerTimeout time.Duration `json:"http_worker_timeout" envconfig:"HTTP_WORKER_TIMEOUT" default:"2m"`
+	DatabaseURL       string        `json:"database_url" envconfig:"DATABASE_URL"`
+	LowestHeights     string        `json:"lowest_heights" envconfig:"LOWEST_HEIGHTS"`
+
+	// StoreDriver is a storage of indexed data: "postgres" at DatabaseURL or "memory", keeping the data only while manager runs
+	StoreDriver string `json:"store_driver" envconfig:"STORE_DRIVER" default:"postgres"`
+
+	// SchedulerLeaseSize is a number of heights leased to a worker at once
+	SchedulerLeaseSize uint64 `json:"scheduler_lease_size" envconfig:"SCHEDULER_LEASE_SIZE" default:"10"`
+
+	// StoreBatchHeights is a number of backfilled heights written to the database at once,
+	// the batch is also written at the end of every lease
+	StoreBatchHeights int `json:"store_batch_heights" envconfig:"STORE_BATCH_HEIGHTS" default:"10"`
+
+	// ConsistencyCheckInterval is an interval of scanning stored heights for gaps
+	ConsistencyCheckInterval time.Duration `json:"consistency_check_interval" envconfig:"CONSISTENCY_CHECK_INTERVAL" default:"10m"`
+	// ConsistencyFullCheckEvery is a number of consistency checks after which all the stored heights are scanned again,
+	// the other checks scan only the heights above the last verified one
+	ConsistencyFullCheckEvery int `json:"consistency_full_check_every" envconfig:"CONSISTENCY_FULL_CHECK_EVERY" default:"24"`
+
+	// Retention - per chain retention policies, heights are kept forever if it's empty.
+	// In form of "chainID=keep_last:100000;slim_after:1000,chainID2=keep_from:5000000"
+	Retention string `json:"retention" envconfig:"RETENTION"`
+	// PruneInterval is an interval of applying retention policies
+	PruneInterval time.Duration `json:"prune_interval" envconfig:"PRUNE_INTERVAL" default:"1h"`
+
+	// AuthConfigFile is a path to json file with credentials of workers and runners, endpoints are open if it's empty
+	AuthConfigFile string `json:"auth_config_file" envconfig:"AUTH_CONFIG_FILE"`
+
+	// AllowLegacyPeers accepts workers and runners that don't do the handshake, they're not checked for compatibility
+	AllowLegacyPeers bool `json:"allow_legacy_peers" envconfig:"ALLOW_LEGACY_PEERS" default:"false"`
+}
+
+// HTTPWorker is a pull-mode worker serving given chain
+type HTTPWorker struct {
+	ChainID string
+	Address string
+}
+
+// HTTPWorkerAddrs returns the pull-mode workers, a chain may be served by many of them
+func (c *Config) HTTPWorkerAddrs() ([]HTTPWorker, error) {
+	if c.HTTPWorkers == "" {
+		return nil, nil
+	}
+
+	var workers []HTTPWorker
+	for _, w := range strings.Split(c.HTTPWorkers, ",") {
+		kv := strings.SplitN(strings.TrimSpace(w), "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("malformed worker definition %q, expected chainID=address", w)
+		}
+		workers = append(workers, HTTPWorker{ChainID: kv[0], Address: strings.TrimSuffix(kv[1], "/")})
+	}
+	return workers, nil
+}
+
+// RetentionPolicies returns the retention policy of every chain listed in Retention
+func (c *Config) RetentionPolicies() (map[string]structs.RetentionPolicy, error) {
+	policies := make(map[string]structs.RetentionPolicy)
+	if c.Retention == "" {
+		return policies, nil
+	}
+
+	for _, p := range strings.Split(c.Retention, ",") {
+		kv := strings.SplitN(strings.TrimSpace(p), "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("malformed retention policy %q, expected chainID=rule:value;rule:value", p)
+		}
+
+		var policy structs.RetentionPolicy
+		for _, r := range strings.Split(kv[1], ";") {
+			rv := strings.SplitN(strings.TrimSpace(r), ":", 2)
+			if len(rv) != 2 {
+				return nil, fmt.Errorf("malformed retention rule %q of chain %s, expected rule:value", r, kv[0])
+			}
+
+			v, err := strconv.ParseUint(rv[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("malformed value of retention rule %q of chain %s: %w", r, kv[0], err)
+			}
+
+			switch rv[0] {
+			case "keep_last":
+				policy.KeepLast = v
+			case "keep_from":
+				policy.KeepFrom = v
+			case "slim_after":
+				policy.SlimAfter = v
+			default:
+				return nil, fmt.Errorf("unknown retention rule %q of chain %s, expected keep_last, keep_from or slim_after", rv[0], kv[0])
+			}
+		}
+		policies[kv[0]] = policy
+	}
+	return policies, nil
 }
 
 // FromFile reads the config from a file
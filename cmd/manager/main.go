@@ -7,30 +7,46 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/figment-networks/graph-demo/cmd/common/logger"
 	"github.com/figment-networks/graph-demo/cmd/manager/config"
 	"github.com/figment-networks/graph-demo/connectivity"
+	"github.com/figment-networks/graph-demo/connectivity/auth"
+	"github.com/figment-networks/graph-demo/connectivity/grpc/workerpb"
+	"github.com/figment-networks/graph-demo/connectivity/handshake"
 	connWS "github.com/figment-networks/graph-demo/connectivity/ws"
 	"github.com/figment-networks/graph-demo/manager/api"
+	adminHTTP "github.com/figment-networks/graph-demo/manager/api/admin/transport/http"
 	runnerHTTP "github.com/figment-networks/graph-demo/manager/api/runner/transport/http"
 	runnerWSAPI "github.com/figment-networks/graph-demo/manager/api/runner/transport/ws"
+	workerGRPCAPI "github.com/figment-networks/graph-demo/manager/api/worker/transport/grpc"
 	workerWSAPI "github.com/figment-networks/graph-demo/manager/api/worker/transport/ws"
+	"github.com/figment-networks/graph-demo/manager/backfill"
 	"github.com/figment-networks/graph-demo/manager/client"
+	workerHTTP "github.com/figment-networks/graph-demo/manager/client/transport/http"
+	"github.com/figment-networks/graph-demo/manager/consistency"
+	"github.com/figment-networks/graph-demo/manager/retention"
 	"github.com/figment-networks/graph-demo/manager/scheduler"
 	"github.com/figment-networks/graph-demo/manager/store"
+	"github.com/figment-networks/graph-demo/manager/store/memory"
 	"github.com/figment-networks/graph-demo/manager/store/postgres"
+	"github.com/figment-networks/graph-demo/manager/structs"
 	"github.com/figment-networks/graph-demo/manager/subscription"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	_ "github.com/lib/pq"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
 type flags struct {
@@ -66,18 +82,23 @@ func main() {
 
 	log := logger.GetLogger()
 
-	dbDriver, err := postgres.NewDriver(ctx, log, cfg.DatabaseURL)
+	dbDriver, err := newDriver(ctx, log, cfg)
 	if err != nil {
 		log.Fatal("Error while creating database driver", zap.Error(err))
 
 	}
 
-	st := store.NewStore(dbDriver)
+	st := store.NewWriter(store.NewStore(dbDriver), cfg.StoreBatchHeights)
 	mux := http.NewServeMux()
 	sc := subscription.NewSubscriptions()
 
 	reg := connWS.NewRegistry()
+	reg.OnRemove(func(info connWS.SessionInfo) {
+		log.Info("session removed", zap.String("id", info.ID), zap.String("role", info.Role))
+		sc.Remove(info.ID)
+	})
 	client := client.NewClient(log, st, sc)
+	client.LinkWriter(st)
 
 	lhs := strings.Split(cfg.LowestHeights, ",")
 	lheights := make(map[string]uint64)
@@ -91,17 +112,81 @@ func main() {
 		}
 	}
 
-	sched := scheduler.NewScheduler(log, client, lheights)
+	sched := scheduler.NewScheduler(ctx, log, client, lheights, cfg.SchedulerLeaseSize)
+	reg.OnRemove(func(info connWS.SessionInfo) {
+		if info.Role == connWS.RoleWorker {
+			sched.RemoveWorker(info.ID)
+		}
+	})
+
+	jobs := backfill.NewJobs(log, st, sched)
+	sched.LinkJobReporter(jobs)
+	if err := jobs.Restore(ctx); err != nil {
+		log.Fatal("Error while restoring backfill jobs", zap.Error(err))
+	}
+
+	policies, err := cfg.RetentionPolicies()
+	if err != nil {
+		log.Fatal("Error while reading retention policies", zap.Error(err))
+	}
+	pruner := retention.NewPruner(log, st, policies)
+	if err := pruner.Load(ctx); err != nil {
+		log.Fatal("Error while loading pruned heights", zap.Error(err))
+	}
+	go pruner.Run(ctx, cfg.PruneInterval)
+
+	checker := consistency.NewChecker(log, st, jobs, lheights)
+	checker.SetFullCheckEvery(cfg.ConsistencyFullCheckEvery)
+	go checker.Run(ctx, cfg.ConsistencyCheckInterval)
+
+	var authn auth.Authenticator = auth.Open{}
+	if cfg.AuthConfigFile != "" {
+		authCfg, err := auth.FromFile(cfg.AuthConfigFile)
+		if err != nil {
+			log.Fatal("Error while reading auth config", zap.Error(err))
+		}
+		authn = auth.NewCredentials(authCfg)
+	} else {
+		log.Warn("Auth config is not set, websocket endpoints are open to everyone and admin endpoints are disabled")
+	}
+
+	adminHTTP.NewHandler(jobs, checker, reg, authn).AttachMux(mux)
 
 	serv := api.NewService(st)
+	queries := api.NewQueryVersions()
+	queries.Add(api.DefaultQueryVersion, serv)
+
+	// peers have to complete the handshake before they register or subscribe, unless legacy peers are allowed
+	var workerGated, runnerGated []string
+	if cfg.AllowLegacyPeers {
+		log.Warn("Legacy peers are allowed, workers and runners skipping the handshake are not checked for compatibility")
+	} else {
+		workerGated, runnerGated = []string{"register"}, []string{"subscribe"}
+	}
+
 	wProc := workerWSAPI.NewProcessHandler(log, serv, sched, reg)
-	linkWorker(ctx, log, reg, wProc, mux)
+	wGate := handshake.NewGate(wProc, workerGated)
+	wProc.Add(handshake.Method, handshake.NewHandler(workerHello, workerMethods, onHandshake(log, reg, wGate)))
+	linkWorker(ctx, log, reg, authn, wGate, mux)
+
+	httpWorkers, err := cfg.HTTPWorkerAddrs()
+	if err != nil {
+		log.Fatal("Error while reading http workers", zap.Error(err))
+	}
+	linkHTTPWorkers(ctx, log, reg, sched, serv, httpWorkers, cfg.HTTPWorkerTimeout)
 
-	proc := runnerWSAPI.NewProcessHandler(log, serv, reg, sc)
-	linkRunner(ctx, log, reg, proc, mux)
+	proc := runnerWSAPI.NewProcessHandler(log, queries, reg, sc)
+	proc.LinkPruneGuard(pruner)
+	rGate := handshake.NewGate(proc, runnerGated)
+	proc.Add(handshake.Method, handshake.NewHandler(runnerHello(queries), runnerMethods, onHandshake(log, reg, rGate)))
+	linkRunner(ctx, log, reg, authn, rGate, mux)
 
-	ms := api.NewService(st)
-	handler := runnerHTTP.NewHandler(ms)
+	reg.OnRemove(func(info connWS.SessionInfo) {
+		wGate.Forget(info.ID)
+		rGate.Forget(info.ID)
+	})
+
+	handler := runnerHTTP.NewHandler(queries)
 	handler.AttachMux(mux)
 
 	s := &http.Server{
@@ -112,18 +197,30 @@ func main() {
 		},
 	}
 
-	osSig := make(chan os.Signal)
-	exit := make(chan string, 2)
+	osSig := make(chan os.Signal, 1)
+	exit := make(chan string, 3)
 	signal.Notify(osSig, syscall.SIGTERM)
 	signal.Notify(osSig, syscall.SIGINT)
 
 	go runHTTP(s, cfg.Address, log, exit)
 
+	var gs *grpc.Server
+	if cfg.GRPCAddress != "" {
+		gs = grpc.NewServer()
+		gSrv := workerGRPCAPI.NewServer(log, serv, sched, reg, authn, workerHello, workerMethods)
+		gSrv.SetAllowLegacy(cfg.AllowLegacyPeers)
+		workerpb.RegisterManagerServer(gs, gSrv)
+		go runGRPC(gs, cfg.GRPCAddress, log, exit)
+	}
+
 RunLoop:
 	for {
 		select {
 		case <-osSig:
 			s.Shutdown(ctx)
+			if gs != nil {
+				gs.GracefulStop()
+			}
 			break RunLoop
 		case <-exit:
 			break RunLoop
@@ -131,6 +228,17 @@ RunLoop:
 	}
 }
 
+func newDriver(ctx context.Context, log *zap.Logger, cfg config.Config) (store.Storager, error) {
+	switch cfg.StoreDriver {
+	case "postgres":
+		return postgres.NewDriver(ctx, log, cfg.DatabaseURL)
+	case "memory":
+		log.Warn("Using in-memory store, indexed data is lost when manager stops")
+		return memory.NewDriver(), nil
+	}
+	return nil, fmt.Errorf("%w: %q", store.ErrDriverDoesNotExists, cfg.StoreDriver)
+}
+
 func initConfig(path string) (config.Config, error) {
 	cfg := &config.Config{}
 
@@ -158,38 +266,138 @@ func runHTTP(s *http.Server, address string, logger *zap.Logger, exit chan<- str
 	exit <- "http"
 }
 
+func runGRPC(s *grpc.Server, address string, logger *zap.Logger, exit chan<- string) {
+	defer logger.Sync()
+
+	lis, err := net.Listen("tcp", address)
+	if err != nil {
+		logger.Error("[GRPC] failed to listen", zap.Error(err))
+		exit <- "grpc"
+		return
+	}
+
+	logger.Info(fmt.Sprintf("[GRPC] Listening on %s", address))
+
+	if err := s.Serve(lis); err != nil {
+		logger.Error("[GRPC] failed to serve", zap.Error(err))
+	}
+	exit <- "grpc"
+}
+
+// linkHTTPWorkers adds pull-mode workers to the scheduler, they're listed in registry like connected sessions
+func linkHTTPWorkers(ctx context.Context, l *zap.Logger, reg *connWS.Registry, sched *scheduler.Scheduler, st workerHTTP.Storer, workers []config.HTTPWorker, timeout time.Duration) {
+	cli := &http.Client{Timeout: timeout}
+	for _, w := range workers {
+		id := uuid.NewString()
+		reg.AddInfo(connWS.SessionInfo{
+			ID:       id,
+			Role:     connWS.RoleWorker,
+			Name:     w.Address,
+			ChainIDs: []string{w.ChainID},
+			Metadata: map[string]string{"transport": "http", "chains": w.ChainID},
+		})
+
+		nc := client.NewBreakerClient(workerHTTP.NewCosmosHTTPTransport(w.Address, w.ChainID, cli, st, l), client.DefaultBreakerConfig)
+		sched.AddWorker(ctx, nc, id, w.ChainID)
+		l.Info("Added http worker", zap.String("address", w.Address), zap.String("chain_id", w.ChainID))
+	}
+}
+
 var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
+	ReadBufferSize:    1024,
+	WriteBufferSize:   1024,
+	Subprotocols:      connWS.Subprotocols(),
+	EnableCompression: true,
 }
 
 var ErrConnectionClosed = errors.New("connection closed")
 
-func linkWorker(ctx context.Context, l *zap.Logger, reg *connWS.Registry, callH connectivity.FunctionCallHandler, mux *http.ServeMux) {
-	mux.HandleFunc("/work", func(w http.ResponseWriter, r *http.Request) {
-		uConn, err := upgrader.Upgrade(w, r, nil)
+// roleMethods lists the methods sessions of given role are allowed to call, admin is allowed to call all of them
+var roleMethods = map[string][]string{
+	connWS.RoleWorker: {handshake.Method, "register", "store_block", "store_transactions"},
+	connWS.RoleRunner: {handshake.Method, "query", "subscribe", "unsubscribe"},
+}
+
+// workerMethods and runnerMethods are the methods manager calls in workers and runners
+var (
+	workerMethods = []string{"get_all", "get_latest"}
+	runnerMethods = []string{"event"}
+)
+
+// workerHello describes manager in handshakes with workers
+var workerHello = handshake.Hello{
+	Component: "manager",
+	Version:   config.Version,
+	Protocol:  handshake.ProtocolVersion,
+	Methods:   roleMethods[connWS.RoleWorker],
+}
+
+// runnerHello describes manager in handshakes with runners
+func runnerHello(queries *api.QueryVersions) handshake.Hello {
+	return handshake.Hello{
+		Component:     "manager",
+		Version:       config.Version,
+		Protocol:      handshake.ProtocolVersion,
+		Methods:       roleMethods[connWS.RoleRunner],
+		Events:        structs.EventSchemaVersions,
+		QueryVersions: queries.Versions(),
+	}
+}
+
+// onHandshake records the versions of accepted peer in its session and opens the gated methods for it
+func onHandshake(l *zap.Logger, reg *connWS.Registry, gate *handshake.Gate) func(connID string, res handshake.Result) {
+	return func(connID string, res handshake.Result) {
+		gate.Accept(connID)
+		l.Info("handshake accepted", zap.String("id", connID), zap.String("component", res.Remote.Component), zap.String("version", res.Remote.Version), zap.String("protocol", res.Remote.Protocol))
+		reg.SetMetadata(connID, "component", res.Remote.Component)
+		reg.SetMetadata(connID, "version", res.Remote.Version)
+		reg.SetMetadata(connID, "protocol", res.Remote.Protocol)
+
+		events := make([]string, 0, len(res.Events))
+		for name, v := range res.Events {
+			events = append(events, name+":"+v)
+		}
+		sort.Strings(events)
+		reg.SetMetadata(connID, "events", strings.Join(events, ","))
+	}
+}
+
+func linkWorker(ctx context.Context, l *zap.Logger, reg *connWS.Registry, authn auth.Authenticator, callH connectivity.FunctionCallHandler, mux *http.ServeMux) {
+	linkSessions(ctx, l, reg, authn, "/work", connWS.RoleWorker, callH, mux)
+}
+
+func linkRunner(ctx context.Context, l *zap.Logger, reg *connWS.Registry, authn auth.Authenticator, callH connectivity.FunctionCallHandler, mux *http.ServeMux) {
+	linkSessions(ctx, l, reg, authn, "/runner", connWS.RoleRunner, callH, mux)
+}
+
+// linkSessions accepts websocket sessions of clients authenticated with given role (or admin),
+// clients authenticated without role are given the role of the endpoint
+func linkSessions(ctx context.Context, l *zap.Logger, reg *connWS.Registry, authn auth.Authenticator, path, role string, callH connectivity.FunctionCallHandler, mux *http.ServeMux) {
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		id, err := authn.Authenticate(r)
 		if err != nil {
-			l.Warn("Error upgrading connection", zap.Error(err))
+			l.Warn("Error authenticating connection", zap.String("path", path), zap.String("remote", r.RemoteAddr), zap.Error(err))
+			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
 
-		sess := connWS.NewSession(ctx, uConn, l, callH)
-		reg.Add(sess.ID, sess)
-		go sess.Recv()
-		go sess.Req()
-	})
-}
+		if id.Role == "" {
+			id.Role = role
+		}
+		if id.Role != role && id.Role != connWS.RoleAdmin {
+			l.Warn("Connection role not allowed", zap.String("path", path), zap.String("name", id.Name), zap.String("role", id.Role))
+			http.Error(w, auth.ErrForbidden.Error(), http.StatusForbidden)
+			return
+		}
 
-func linkRunner(ctx context.Context, l *zap.Logger, reg *connWS.Registry, callH connectivity.FunctionCallHandler, mux *http.ServeMux) {
-	mux.HandleFunc("/runner", func(w http.ResponseWriter, r *http.Request) {
 		uConn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
 			l.Warn("Error upgrading connection", zap.Error(err))
 			return
 		}
 
-		sess := connWS.NewSession(ctx, uConn, l, callH)
-		reg.Add(sess.ID, sess)
+		sess := connWS.NewSession(ctx, uConn, l, auth.NewGuard(callH, roleMethods[id.Role]))
+		reg.AddSession(sess, connWS.SessionInfo{Role: role, Name: id.Name, ChainIDs: id.ChainIDs})
 		go sess.Recv()
 		go sess.Req()
 	})
@@ -23,6 +23,15 @@ type Config struct {
 	// A comma separated list of paths to subgraph folders
 	Subgraphs  string `json:"subgraphs" envconfig:"SUBGRAPHS"`
 	ManagerURL string `json:"manager_url" envconfig:"MANAGER_URL" default:"ws://0.0.0.0:8085/runner"`
+
+	// Credentials of the manager connection, HMAC signature is used if the key is set
+	ManagerAuthToken  string `json:"manager_auth_token" envconfig:"MANAGER_AUTH_TOKEN"`
+	ManagerAuthKey    string `json:"manager_auth_key" envconfig:"MANAGER_AUTH_KEY"`
+	ManagerAuthSecret string `json:"manager_auth_secret" envconfig:"MANAGER_AUTH_SECRET"`
+
+	// ManagerEncoding of the manager connection (jsonrpc-json or jsonrpc-cbor)
+	ManagerEncoding    string `json:"manager_encoding" envconfig:"MANAGER_ENCODING" default:"jsonrpc-json"`
+	ManagerCompression bool   `json:"manager_compression" envconfig:"MANAGER_COMPRESSION" default:"false"`
 }
 
 // FromFile reads the config from a file
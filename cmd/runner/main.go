@@ -14,6 +14,8 @@ import (
 
 	"github.com/figment-networks/graph-demo/cmd/common/logger"
 	"github.com/figment-networks/graph-demo/cmd/runner/config"
+	"github.com/figment-networks/graph-demo/connectivity/auth"
+	wsapi "github.com/figment-networks/graph-demo/connectivity/ws"
 	"github.com/figment-networks/graph-demo/runner/api/service"
 	transportHTTP "github.com/figment-networks/graph-demo/runner/api/transport/http"
 	runnerClient "github.com/figment-networks/graph-demo/runner/client"
@@ -66,7 +68,19 @@ func main() {
 	rqstr.AddDestination("cosmos", wst)
 
 	ngc := runnerClient.NewNetworkGraphClient(l, loader)
-	if err := wst.Connect(context.Background(), cfg.ManagerURL, ngc); err != nil {
+	ngc.LinkTracker(wst)
+
+	onState := func(state wsapi.ConnState, err error) {
+		l.Info("manager connection state changed", zap.String("state", string(state)), zap.String("address", cfg.ManagerURL), zap.Error(err))
+	}
+	creds := auth.ClientCredentials{Token: cfg.ManagerAuthToken, Key: cfg.ManagerAuthKey, Secret: cfg.ManagerAuthSecret}
+	if err := wst.Connect(context.Background(), cfg.ManagerURL, ngc, wsapi.DialOptions{
+		Header:      creds.Header,
+		Encoding:    cfg.ManagerEncoding,
+		Compression: cfg.ManagerCompression,
+		OnState:     onState,
+		Version:     config.Version,
+	}); err != nil {
 		l.Fatal("error conectiong to websocket", zap.Error(err))
 	}
 
@@ -91,7 +105,7 @@ func main() {
 		WriteTimeout: 40 * time.Second,
 	}
 
-	osSig := make(chan os.Signal)
+	osSig := make(chan os.Signal, 1)
 	exit := make(chan string, 2)
 	signal.Notify(osSig, syscall.SIGTERM)
 	signal.Notify(osSig, syscall.SIGINT)
@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	HeaderAuthorization = "Authorization"
+	HeaderKey           = "X-Auth-Key"
+	HeaderTimestamp     = "X-Auth-Timestamp"
+	HeaderNonce         = "X-Auth-Nonce"
+	HeaderSignature     = "X-Auth-Signature"
+
+	// MaxClockSkew is the maximum difference between the signed timestamp and the server time
+	MaxClockSkew = 5 * time.Minute
+
+	// nonceSize is the number of random bytes of the nonce
+	nonceSize = 16
+)
+
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrForbidden          = errors.New("forbidden")
+)
+
+// Identity of the authenticated connection
+type Identity struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+	// ChainIDs the connection is allowed to work with, all chains if empty
+	ChainIDs []string `json:"chain_ids"`
+}
+
+// Authenticator authenticates websocket upgrade request
+type Authenticator interface {
+	Authenticate(r *http.Request) (Identity, error)
+}
+
+// Open is an Authenticator that accepts every request with given role.
+// Requests accepted with empty role get the least-privileged role of the endpoint they're made to.
+type Open struct {
+	Role string
+}
+
+func (o Open) Authenticate(r *http.Request) (Identity, error) {
+	return Identity{Name: "anonymous", Role: o.Role}, nil
+}
+
+// RequireRole authenticates the requests of h, only the ones with given role are let through
+func RequireRole(authn Authenticator, role string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := authn.Authenticate(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		if id.Role != role {
+			http.Error(w, ErrForbidden.Error(), http.StatusForbidden)
+			return
+		}
+		h(w, r)
+	}
+}
+
+type TokenCredential struct {
+	Identity
+	Token string `json:"token"`
+}
+
+type HMACCredential struct {
+	Identity
+	Key    string `json:"key"`
+	Secret string `json:"secret"`
+}
+
+// Config lists credentials of all clients
+type Config struct {
+	Tokens []TokenCredential `json:"tokens"`
+	HMAC   []HMACCredential  `json:"hmac"`
+}
+
+// FromFile reads credentials from json file
+func FromFile(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Credentials authenticates requests using shared tokens (`Authorization: Bearer <token>`)
+// or HMAC-signed headers (see SignedHeader). Every signature is accepted once,
+// its nonce is remembered for as long as its timestamp is valid.
+type Credentials struct {
+	tokens map[string]Identity
+	hmac   map[string]HMACCredential
+	now    func() time.Time
+
+	// nonces maps key and nonce of accepted signatures to the time they expire
+	nonces map[string]time.Time
+	nl     sync.Mutex
+}
+
+func NewCredentials(cfg *Config) *Credentials {
+	c := &Credentials{
+		tokens: make(map[string]Identity),
+		hmac:   make(map[string]HMACCredential),
+		now:    time.Now,
+		nonces: make(map[string]time.Time),
+	}
+
+	for _, t := range cfg.Tokens {
+		c.tokens[t.Token] = t.Identity
+	}
+	for _, h := range cfg.HMAC {
+		c.hmac[h.Key] = h
+	}
+	return c
+}
+
+func (c *Credentials) Authenticate(r *http.Request) (Identity, error) {
+	if key := r.Header.Get(HeaderKey); key != "" {
+		return c.authenticateHMAC(key, r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderNonce), r.Header.Get(HeaderSignature))
+	}
+
+	authz := r.Header.Get(HeaderAuthorization)
+	if authz == "" {
+		return Identity{}, ErrMissingCredentials
+	}
+
+	token := strings.TrimPrefix(authz, "Bearer ")
+	for t, id := range c.tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			return id, nil
+		}
+	}
+	return Identity{}, ErrInvalidCredentials
+}
+
+func (c *Credentials) authenticateHMAC(key, timestamp, nonce, signature string) (Identity, error) {
+	cred, ok := c.hmac[key]
+	if !ok || nonce == "" {
+		return Identity{}, ErrInvalidCredentials
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return Identity{}, ErrInvalidCredentials
+	}
+
+	now := c.now()
+	signed := time.Unix(ts, 0)
+	skew := now.Sub(signed)
+	if skew > MaxClockSkew || skew < -MaxClockSkew {
+		return Identity{}, ErrInvalidCredentials
+	}
+
+	expected := sign(cred.Secret, key, timestamp, nonce)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return Identity{}, ErrInvalidCredentials
+	}
+
+	if !c.useNonce(key+"\n"+nonce, now, signed.Add(MaxClockSkew)) {
+		return Identity{}, ErrInvalidCredentials
+	}
+	return cred.Identity, nil
+}
+
+// useNonce remembers the nonce until expiry, it returns false if the nonce was used already
+func (c *Credentials) useNonce(nonce string, now, expiry time.Time) bool {
+	c.nl.Lock()
+	defer c.nl.Unlock()
+
+	for n, exp := range c.nonces {
+		if now.After(exp) {
+			delete(c.nonces, n)
+		}
+	}
+
+	if _, ok := c.nonces[nonce]; ok {
+		return false
+	}
+	c.nonces[nonce] = expiry
+	return true
+}
+
+func sign(secret, key, timestamp, nonce string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(key + "\n" + timestamp + "\n" + nonce))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// TokenHeader returns header authenticating client with shared token
+func TokenHeader(token string) http.Header {
+	h := http.Header{}
+	h.Set(HeaderAuthorization, "Bearer "+token)
+	return h
+}
+
+// SignedHeader returns header authenticating client with HMAC signature of the key, current time and random nonce.
+// The header authenticates a single request, it's rejected when reused.
+func SignedHeader(key, secret string, now time.Time) http.Header {
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	b := make([]byte, nonceSize)
+	rand.Read(b)
+	nonce := hex.EncodeToString(b)
+
+	h := http.Header{}
+	h.Set(HeaderKey, key)
+	h.Set(HeaderTimestamp, ts)
+	h.Set(HeaderNonce, nonce)
+	h.Set(HeaderSignature, sign(secret, key, ts, nonce))
+	return h
+}
+
+// ClientCredentials are credentials of the client connecting to manager
+type ClientCredentials struct {
+	Token  string
+	Key    string
+	Secret string
+}
+
+// Header returns authentication header, HMAC signed one if key is set, nil if there are no credentials
+func (cc ClientCredentials) Header() http.Header {
+	switch {
+	case cc.Key != "":
+		return SignedHeader(cc.Key, cc.Secret, time.Now())
+	case cc.Token != "":
+		return TokenHeader(cc.Token)
+	default:
+		return nil
+	}
+}
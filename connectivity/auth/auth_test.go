@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCredentials(now time.Time) *Credentials {
+	c := NewCredentials(&Config{
+		Tokens: []TokenCredential{{Identity: Identity{Name: "worker-1", Role: "worker"}, Token: "secret-token"}},
+		HMAC:   []HMACCredential{{Identity: Identity{Name: "runner-1", Role: "runner"}, Key: "runner-1", Secret: "shared-secret"}},
+	})
+	c.now = func() time.Time { return now }
+	return c
+}
+
+func request(h http.Header) *http.Request {
+	return &http.Request{Header: h}
+}
+
+func TestAuthenticateToken(t *testing.T) {
+	c := newTestCredentials(time.Now())
+
+	id, err := c.Authenticate(request(TokenHeader("secret-token")))
+	require.NoError(t, err)
+	assert.Equal(t, "worker-1", id.Name)
+
+	_, err = c.Authenticate(request(TokenHeader("other-token")))
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = c.Authenticate(request(http.Header{}))
+	require.ErrorIs(t, err, ErrMissingCredentials)
+}
+
+func TestAuthenticateHMAC(t *testing.T) {
+	now := time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		header func() http.Header
+		err    error
+	}{
+		{name: "valid", header: func() http.Header { return SignedHeader("runner-1", "shared-secret", now) }},
+		{name: "within clock skew", header: func() http.Header { return SignedHeader("runner-1", "shared-secret", now.Add(-4*time.Minute)) }},
+		{name: "expired", header: func() http.Header { return SignedHeader("runner-1", "shared-secret", now.Add(-6*time.Minute)) }, err: ErrInvalidCredentials},
+		{name: "unknown key", header: func() http.Header { return SignedHeader("runner-2", "shared-secret", now) }, err: ErrInvalidCredentials},
+		{name: "wrong secret", header: func() http.Header { return SignedHeader("runner-1", "other-secret", now) }, err: ErrInvalidCredentials},
+		{name: "changed nonce", header: func() http.Header {
+			h := SignedHeader("runner-1", "shared-secret", now)
+			h.Set(HeaderNonce, "00")
+			return h
+		}, err: ErrInvalidCredentials},
+		{name: "missing nonce", header: func() http.Header {
+			h := SignedHeader("runner-1", "shared-secret", now)
+			h.Del(HeaderNonce)
+			return h
+		}, err: ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := newTestCredentials(now).Authenticate(request(tt.header()))
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "runner", id.Role)
+		})
+	}
+}
+
+func TestAuthenticateHMACReplay(t *testing.T) {
+	now := time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC)
+	c := newTestCredentials(now)
+
+	h := SignedHeader("runner-1", "shared-secret", now)
+	_, err := c.Authenticate(request(h))
+	require.NoError(t, err)
+
+	_, err = c.Authenticate(request(h))
+	require.ErrorIs(t, err, ErrInvalidCredentials, "signature is accepted once")
+
+	_, err = c.Authenticate(request(SignedHeader("runner-1", "shared-secret", now)))
+	require.NoError(t, err, "new signature is accepted")
+
+	// nonces are forgotten once their signatures expire
+	c.now = func() time.Time { return now.Add(MaxClockSkew + time.Second) }
+	_, err = c.Authenticate(request(SignedHeader("runner-1", "shared-secret", c.now())))
+	require.NoError(t, err)
+	assert.Len(t, c.nonces, 1)
+}
+
+func TestRequireRole(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	tests := []struct {
+		name   string
+		authn  Authenticator
+		header http.Header
+		status int
+	}{
+		{name: "open without role", authn: Open{}, status: http.StatusForbidden},
+		{name: "open with role", authn: Open{Role: "admin"}, status: http.StatusOK},
+		{name: "missing credentials", authn: newTestCredentials(time.Now()), status: http.StatusUnauthorized},
+		{name: "other role", authn: newTestCredentials(time.Now()), header: TokenHeader("secret-token"), status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/admin/jobs", nil)
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+
+			RequireRole(tt.authn, "admin", h)(rec, req)
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
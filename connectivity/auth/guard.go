@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/figment-networks/graph-demo/connectivity"
+	"github.com/figment-networks/graph-demo/connectivity/jsonrpc"
+)
+
+// ErrUnauthorized is returned for the methods the session is not allowed to call
+var ErrUnauthorized = &jsonrpc.Error{Code: -32010, Message: "Unauthorized"}
+
+// Guard limits the methods of FunctionCallHandler to the ones allowed for the role of the session
+type Guard struct {
+	connectivity.FunctionCallHandler
+	allowed map[string]bool
+}
+
+// NewGuard creates Guard, nil methods allow every method
+func NewGuard(fch connectivity.FunctionCallHandler, methods []string) *Guard {
+	g := &Guard{FunctionCallHandler: fch}
+	if methods != nil {
+		g.allowed = make(map[string]bool, len(methods))
+		for _, m := range methods {
+			g.allowed[m] = true
+		}
+	}
+	return g
+}
+
+func (g *Guard) Get(name string) (h connectivity.Handler, ok bool) {
+	h, ok = g.FunctionCallHandler.Get(name)
+	if !ok || g.allowed == nil || g.allowed[name] {
+		return h, ok
+	}
+
+	return func(ctx context.Context, req connectivity.Request, resp connectivity.Response) {
+		resp.Send(nil, ErrUnauthorized)
+	}, true
+}
@@ -24,6 +24,8 @@ type Response interface {
 type Request interface {
 	ConnID() string
 	Arguments() []json.RawMessage
+	// Decode decodes i-th argument into v using the encoding of the connection
+	Decode(i int, v interface{}) error
 }
 
 type FunctionCallHandler interface {
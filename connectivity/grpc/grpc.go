@@ -0,0 +1,141 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/figment-networks/graph-demo/connectivity/grpc/workerpb"
+
+	"google.golang.org/grpc/metadata"
+)
+
+var ErrConnectionClosed = errors.New("connection closed")
+
+// DefaultCallTimeout is the time Call waits for the result if the context has no deadline
+var DefaultCallTimeout = 2 * time.Minute
+
+// Pending routes results received on the stream to the requests waiting for them.
+// Both sides of the stream send requests with their own ids, results carry the id of the request.
+type Pending struct {
+	waiting map[uint64]chan *workerpb.Result
+	newID   uint64
+	l       sync.Mutex
+
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func NewPending() *Pending {
+	return &Pending{
+		waiting: make(map[uint64]chan *workerpb.Result),
+		done:    make(chan struct{}),
+	}
+}
+
+// Call sends request with new id using send and waits for its result until ctx is done or the stream is closed.
+// Requests with ctx without deadline are given DefaultCallTimeout.
+// Error of the result is returned as *workerpb.Error.
+func (p *Pending) Call(ctx context.Context, method string, send func(id uint64) error) (*workerpb.Result, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultCallTimeout)
+		defer cancel()
+	}
+
+	ch := make(chan *workerpb.Result, 1)
+	p.l.Lock()
+	p.newID++
+	id := p.newID
+	p.waiting[id] = ch
+	p.l.Unlock()
+
+	// result that comes after that is dropped
+	defer func() {
+		p.l.Lock()
+		delete(p.waiting, id)
+		p.l.Unlock()
+	}()
+
+	if err := send(id); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrConnectionClosed, err.Error())
+	}
+
+	select {
+	case <-p.done:
+		return nil, ErrConnectionClosed
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %s", ctx.Err(), method)
+	case res := <-ch:
+		if res.GetError() != nil {
+			return res, res.GetError()
+		}
+		return res, nil
+	}
+}
+
+// Resolve passes the result to the request waiting for it, it returns false if there is none
+func (p *Pending) Resolve(id uint64, res *workerpb.Result) bool {
+	p.l.Lock()
+	ch, ok := p.waiting[id]
+	delete(p.waiting, id)
+	p.l.Unlock()
+
+	if ok {
+		ch <- res
+	}
+	return ok
+}
+
+// Close fails all waiting requests, it's called once the stream is closed
+func (p *Pending) Close(reason error) {
+	p.closeOnce.Do(func() {
+		if reason == nil {
+			reason = ErrConnectionClosed
+		}
+		p.closeErr = reason
+		close(p.done)
+	})
+}
+
+// Done is closed when the stream is closed
+func (p *Pending) Done() <-chan struct{} {
+	return p.done
+}
+
+// Err returns the reason the stream was closed with
+func (p *Pending) Err() error {
+	select {
+	case <-p.done:
+		return p.closeErr
+	default:
+		return nil
+	}
+}
+
+// HeaderToMetadata converts http header (i.e. credentials) into metadata of the stream
+func HeaderToMetadata(h http.Header) metadata.MD {
+	md := metadata.MD{}
+	for k, v := range h {
+		md.Append(strings.ToLower(k), v...)
+	}
+	return md
+}
+
+// RequestFromMetadata returns http request with the header from incoming metadata,
+// so the stream can be authenticated the same way as websocket upgrade
+func RequestFromMetadata(ctx context.Context) *http.Request {
+	r := &http.Request{Header: http.Header{}}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for k, v := range md {
+		for _, vv := range v {
+			r.Header.Add(k, vv)
+		}
+	}
+	return r
+}
@@ -0,0 +1,298 @@
+package workerpb
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Conversions between protocol messages and manager structs.
+// CreatedAt and UpdatedAt are set by the store, so they're not transferred.
+
+func RegisterToPB(reg structs.Register) *Register {
+	r := &Register{Name: reg.Name, Chains: make([]*RegisterChain, len(reg.Chains))}
+	for i, ch := range reg.Chains {
+		r.Chains[i] = &RegisterChain{ChainId: ch.ChainID, Capabilities: ch.Capabilities}
+	}
+	return r
+}
+
+func RegisterFromPB(r *Register) structs.Register {
+	reg := structs.Register{Name: r.GetName(), Chains: make([]structs.RegisterChain, len(r.GetChains()))}
+	for i, ch := range r.GetChains() {
+		reg.Chains[i] = structs.RegisterChain{ChainID: ch.GetChainId(), Capabilities: ch.GetCapabilities()}
+	}
+	return reg
+}
+
+func BlockToPB(b structs.Block) *Block {
+	h := b.Header
+	pb := &Block{
+		Hash:    b.Hash,
+		Height:  b.Height,
+		Time:    toTimestamp(b.Time),
+		ChainId: b.ChainID,
+		Header: &BlockHeader{
+			VersionBlock:       h.Version.Block,
+			VersionApp:         h.Version.App,
+			ChainId:            h.ChainID,
+			Height:             h.Height,
+			Time:               toTimestamp(h.Time),
+			LastBlockId:        blockIDToPB(h.LastBlockId),
+			LastCommitHash:     h.LastCommitHash,
+			DataHash:           h.DataHash,
+			ValidatorsHash:     h.ValidatorsHash,
+			NextValidatorsHash: h.NextValidatorsHash,
+			ConsensusHash:      h.ConsensusHash,
+			AppHash:            h.AppHash,
+			LastResultsHash:    h.LastResultsHash,
+			EvidenceHash:       h.EvidenceHash,
+			ProposerAddress:    h.ProposerAddress,
+		},
+		Txs: b.Data.Txs,
+	}
+
+	for _, e := range b.Evidence {
+		pb.Evidence = append(pb.Evidence, string(e))
+	}
+
+	if c := b.LastCommit; c != nil {
+		pb.LastCommit = &Commit{Height: c.Height, Round: c.Round, BlockId: blockIDToPB(c.BlockID)}
+		for _, s := range c.Signatures {
+			pb.LastCommit.Signatures = append(pb.LastCommit.Signatures, &CommitSig{
+				BlockIdFlag:      s.BlockIdFlag,
+				ValidatorAddress: s.ValidatorAddress,
+				Timestamp:        toTimestamp(s.Timestamp),
+				Signature:        s.Signature,
+			})
+		}
+	}
+	return pb
+}
+
+func BlockFromPB(pb *Block) structs.Block {
+	h := pb.GetHeader()
+	b := structs.Block{
+		Hash:    pb.GetHash(),
+		Height:  pb.GetHeight(),
+		Time:    fromTimestamp(pb.GetTime()),
+		ChainID: pb.GetChainId(),
+		Header: structs.BlockHeader{
+			Version:            structs.Consensus{Block: h.GetVersionBlock(), App: h.GetVersionApp()},
+			ChainID:            h.GetChainId(),
+			Height:             h.GetHeight(),
+			Time:               fromTimestamp(h.GetTime()),
+			LastBlockId:        blockIDFromPB(h.GetLastBlockId()),
+			LastCommitHash:     h.GetLastCommitHash(),
+			DataHash:           h.GetDataHash(),
+			ValidatorsHash:     h.GetValidatorsHash(),
+			NextValidatorsHash: h.GetNextValidatorsHash(),
+			ConsensusHash:      h.GetConsensusHash(),
+			AppHash:            h.GetAppHash(),
+			LastResultsHash:    h.GetLastResultsHash(),
+			EvidenceHash:       h.GetEvidenceHash(),
+			ProposerAddress:    h.GetProposerAddress(),
+		},
+		Data: structs.BlockData{Txs: pb.GetTxs()},
+	}
+
+	for _, e := range pb.GetEvidence() {
+		b.Evidence = append(b.Evidence, structs.BlockEvidence(e))
+	}
+
+	if c := pb.GetLastCommit(); c != nil {
+		b.LastCommit = &structs.Commit{Height: c.GetHeight(), Round: c.GetRound(), BlockID: blockIDFromPB(c.GetBlockId())}
+		for _, s := range c.GetSignatures() {
+			b.LastCommit.Signatures = append(b.LastCommit.Signatures, structs.CommitSig{
+				BlockIdFlag:      s.GetBlockIdFlag(),
+				ValidatorAddress: s.GetValidatorAddress(),
+				Timestamp:        fromTimestamp(s.GetTimestamp()),
+				Signature:        s.GetSignature(),
+			})
+		}
+	}
+	return b
+}
+
+func TransactionsToPB(txs []structs.Transaction) []*Transaction {
+	pbs := make([]*Transaction, len(txs))
+	for i, tx := range txs {
+		pb := &Transaction{
+			ChainId:                     tx.ChainID,
+			Height:                      tx.Height,
+			Hash:                        tx.Hash,
+			BlockHash:                   tx.BlockHash,
+			Time:                        toTimestamp(tx.Time),
+			CodeSpace:                   tx.CodeSpace,
+			Code:                        tx.Code,
+			GasWanted:                   tx.GasWanted,
+			GasUsed:                     tx.GasUsed,
+			Info:                        tx.Info,
+			Memo:                        tx.Memo,
+			Result:                      tx.Result,
+			Signatures:                  tx.Signatures,
+			ExtensionOptions:            anysToPB(tx.ExtensionOptions),
+			Messages:                    anysToPB(tx.Messages),
+			NonCriticalExtensionOptions: anysToPB(tx.NonCriticalExtensionOptions),
+			RawLog:                      tx.RawLog,
+			TxRaw:                       anyToPB(tx.TxRaw),
+			Parties:                     tx.Parties,
+		}
+
+		for _, l := range tx.Logs {
+			pl := &Log{MsgIndex: l.MsgIndex, Log: l.Log}
+			for _, e := range l.Events {
+				pl.Events = append(pl.Events, &Event{Type: e.Type, Attributes: e.Attributes})
+			}
+			pb.Logs = append(pb.Logs, pl)
+		}
+
+		if ai := tx.AuthInfo; ai != nil {
+			pb.AuthInfo = &AuthInfo{}
+			if f := ai.Fee; f != nil {
+				pb.AuthInfo.Fee = &Fee{GasLimit: f.GasLimit, Payer: f.Sender, Granter: f.Recipient}
+				for _, c := range f.Amount {
+					pc := &Coin{Denom: c.Denom}
+					if c.Amount != nil {
+						pc.Amount = c.Amount.String()
+					}
+					pb.AuthInfo.Fee.Coins = append(pb.AuthInfo.Fee.Coins, pc)
+				}
+				if len(pb.AuthInfo.Fee.Coins) > 0 {
+					// the first coin for the peers not knowing coins yet
+					pb.AuthInfo.Fee.Amount = pb.AuthInfo.Fee.Coins[0].Amount
+					pb.AuthInfo.Fee.Currency = pb.AuthInfo.Fee.Coins[0].Denom
+				}
+			}
+			for _, si := range ai.SignerInfos {
+				psi := &SignerInfo{ModeInfo: si.ModeInfo, Sequence: si.Sequence}
+				if si.PublicKey != nil {
+					psi.PublicKey = anyToPB(*si.PublicKey)
+				}
+				pb.AuthInfo.SignerInfos = append(pb.AuthInfo.SignerInfos, psi)
+			}
+		}
+		pbs[i] = pb
+	}
+	return pbs
+}
+
+func TransactionsFromPB(pbs []*Transaction) []structs.Transaction {
+	txs := make([]structs.Transaction, len(pbs))
+	for i, pb := range pbs {
+		tx := structs.Transaction{
+			ChainID:                     pb.GetChainId(),
+			Height:                      pb.GetHeight(),
+			Hash:                        pb.GetHash(),
+			BlockHash:                   pb.GetBlockHash(),
+			Time:                        fromTimestamp(pb.GetTime()),
+			CodeSpace:                   pb.GetCodeSpace(),
+			Code:                        pb.GetCode(),
+			GasWanted:                   pb.GetGasWanted(),
+			GasUsed:                     pb.GetGasUsed(),
+			Info:                        pb.GetInfo(),
+			Memo:                        pb.GetMemo(),
+			Result:                      pb.GetResult(),
+			Signatures:                  pb.GetSignatures(),
+			ExtensionOptions:            anysFromPB(pb.GetExtensionOptions()),
+			Messages:                    anysFromPB(pb.GetMessages()),
+			NonCriticalExtensionOptions: anysFromPB(pb.GetNonCriticalExtensionOptions()),
+			RawLog:                      pb.GetRawLog(),
+			TxRaw:                       anyFromPB(pb.GetTxRaw()),
+			Parties:                     pb.GetParties(),
+		}
+
+		for _, l := range pb.GetLogs() {
+			sl := structs.Log{MsgIndex: l.GetMsgIndex(), Log: l.GetLog()}
+			for _, e := range l.GetEvents() {
+				sl.Events = append(sl.Events, structs.Event{Type: e.GetType(), Attributes: e.GetAttributes()})
+			}
+			tx.Logs = append(tx.Logs, sl)
+		}
+
+		if ai := pb.GetAuthInfo(); ai != nil {
+			tx.AuthInfo = &structs.AuthInfo{}
+			if f := ai.GetFee(); f != nil {
+				tx.AuthInfo.Fee = &structs.Fee{GasLimit: f.GetGasLimit(), Sender: f.GetPayer(), Recipient: f.GetGranter()}
+				coins := f.GetCoins()
+				if len(coins) == 0 && f.GetAmount() != "" {
+					// sent by the peer not knowing coins yet
+					coins = []*Coin{{Denom: f.GetCurrency(), Amount: f.GetAmount()}}
+				}
+				for _, c := range coins {
+					sc := structs.Coin{Denom: c.GetDenom()}
+					if amount, ok := new(big.Int).SetString(c.GetAmount(), 10); ok {
+						sc.Amount = amount
+					}
+					tx.AuthInfo.Fee.Amount = append(tx.AuthInfo.Fee.Amount, sc)
+				}
+			}
+			for _, si := range ai.GetSignerInfos() {
+				ssi := structs.SignerInfo{ModeInfo: si.GetModeInfo(), Sequence: si.GetSequence()}
+				if si.GetPublicKey() != nil {
+					pk := anyFromPB(si.GetPublicKey())
+					ssi.PublicKey = &pk
+				}
+				tx.AuthInfo.SignerInfos = append(tx.AuthInfo.SignerInfos, ssi)
+			}
+		}
+		txs[i] = tx
+	}
+	return txs
+}
+
+func blockIDToPB(id structs.BlockID) *BlockID {
+	return &BlockID{Hash: id.Hash, PartSetTotal: id.PartSetHeader.Total, PartSetHash: id.PartSetHeader.Hash}
+}
+
+func blockIDFromPB(id *BlockID) structs.BlockID {
+	return structs.BlockID{Hash: id.GetHash(), PartSetHeader: structs.PartSetHeader{Total: id.GetPartSetTotal(), Hash: id.GetPartSetHash()}}
+}
+
+func anyToPB(a structs.Any) *Any {
+	return &Any{TypeUrl: a.TypeURL, Value: a.Value}
+}
+
+func anyFromPB(a *Any) structs.Any {
+	return structs.Any{TypeURL: a.GetTypeUrl(), Value: a.GetValue()}
+}
+
+func anysToPB(as []structs.Any) []*Any {
+	if as == nil {
+		return nil
+	}
+	pbs := make([]*Any, len(as))
+	for i, a := range as {
+		pbs[i] = anyToPB(a)
+	}
+	return pbs
+}
+
+func anysFromPB(pbs []*Any) []structs.Any {
+	if pbs == nil {
+		return nil
+	}
+	as := make([]structs.Any, len(pbs))
+	for i, a := range pbs {
+		as[i] = anyFromPB(a)
+	}
+	return as
+}
+
+// toTimestamp leaves zero time unset
+func toTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func fromTimestamp(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
@@ -0,0 +1,20 @@
+package workerpb
+
+import (
+	"github.com/figment-networks/graph-demo/manager/structs"
+)
+
+// Error makes the error of the result usable as Go error
+func (x *Error) Error() string {
+	return x.GetMessage()
+}
+
+// Err recreates the error of processing heights sent with the code
+func (x *Error) Err() error {
+	return structs.ErrorFromCode(x.GetCode(), x.GetMessage())
+}
+
+// ErrorResult returns the result of failed request
+func ErrorResult(err error) *Result {
+	return &Result{Error: &Error{Code: structs.ErrorCode(err), Message: err.Error()}}
+}
@@ -0,0 +1,70 @@
+package handshake
+
+import (
+	"context"
+	"sync"
+
+	"github.com/figment-networks/graph-demo/connectivity"
+	"github.com/figment-networks/graph-demo/connectivity/jsonrpc"
+)
+
+// ErrHandshakeRequired is returned for the calls made before the handshake is accepted
+var ErrHandshakeRequired = &jsonrpc.Error{Code: -32021, Message: "Handshake required"}
+
+// Gate refuses the calls of gated methods of FunctionCallHandler until the handshake of the connection is accepted
+type Gate struct {
+	connectivity.FunctionCallHandler
+	gated map[string]bool
+
+	accepted map[string]bool
+	l        sync.RWMutex
+}
+
+// NewGate creates Gate, nil methods leave every method open to the peers that skip the handshake
+func NewGate(fch connectivity.FunctionCallHandler, methods []string) *Gate {
+	g := &Gate{
+		FunctionCallHandler: fch,
+		gated:               make(map[string]bool, len(methods)),
+		accepted:            make(map[string]bool),
+	}
+	for _, m := range methods {
+		g.gated[m] = true
+	}
+	return g
+}
+
+// Accept opens gated methods for the connection
+func (g *Gate) Accept(connID string) {
+	g.l.Lock()
+	defer g.l.Unlock()
+	g.accepted[connID] = true
+}
+
+// Forget closes gated methods for the connection, once it's closed
+func (g *Gate) Forget(connID string) {
+	g.l.Lock()
+	defer g.l.Unlock()
+	delete(g.accepted, connID)
+}
+
+// Accepted checks if the handshake of the connection was accepted
+func (g *Gate) Accepted(connID string) bool {
+	g.l.RLock()
+	defer g.l.RUnlock()
+	return g.accepted[connID]
+}
+
+func (g *Gate) Get(name string) (connectivity.Handler, bool) {
+	h, ok := g.FunctionCallHandler.Get(name)
+	if !ok || !g.gated[name] {
+		return h, ok
+	}
+
+	return func(ctx context.Context, req connectivity.Request, resp connectivity.Response) {
+		if !g.Accepted(req.ConnID()) {
+			resp.Send(nil, ErrHandshakeRequired)
+			return
+		}
+		h(ctx, req, resp)
+	}, true
+}
@@ -0,0 +1,250 @@
+// Package handshake implements the exchange of component, protocol and schema versions
+// that every client does with the manager right after connecting.
+package handshake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/figment-networks/graph-demo/connectivity"
+	"github.com/figment-networks/graph-demo/connectivity/jsonrpc"
+)
+
+// Method is the name of the handshake call
+const Method = "handshake"
+
+// ProtocolVersion is the version of the protocol spoken between manager, workers and runners.
+// Peers are compatible as long as the major versions are equal.
+const ProtocolVersion = "1.0"
+
+// ErrIncompatible is returned for peers that can't work with each other
+var ErrIncompatible = &jsonrpc.Error{Code: -32020, Message: "Incompatible peer"}
+
+// Hello describes the peer
+type Hello struct {
+	Component string `json:"component"`
+	Version   string `json:"version"`
+	Protocol  string `json:"protocol"`
+	// Methods the peer serves
+	Methods []string `json:"methods"`
+	// Events are the payload schema versions of events the peer sends or understands, by event name
+	Events map[string][]string `json:"events,omitempty"`
+	// QueryVersions are the versions of query schema the peer serves
+	QueryVersions []string `json:"query_versions,omitempty"`
+}
+
+// Result is the outcome of accepted handshake
+type Result struct {
+	Remote Hello
+	// Events are the negotiated event schema versions, the highest ones both peers support
+	Events map[string]string
+}
+
+// Sender sends the handshake call
+type Sender interface {
+	SendSync(ctx context.Context, method string, params []json.RawMessage) (jsonrpc.Response, error)
+}
+
+// Check checks if the remote peer is compatible with the local one and serves all the required methods
+func Check(local, remote Hello, required []string) (res Result, err error) {
+	res.Remote = remote
+
+	if major(local.Protocol) != major(remote.Protocol) {
+		return res, incompatible("%s %s speaks protocol %q, %s %s speaks %q", remote.Component, remote.Version, remote.Protocol, local.Component, local.Version, local.Protocol)
+	}
+
+	served := make(map[string]bool, len(remote.Methods))
+	for _, m := range remote.Methods {
+		served[m] = true
+	}
+	for _, m := range required {
+		if !served[m] {
+			return res, incompatible("%s %s doesn't serve required method %q", remote.Component, remote.Version, m)
+		}
+	}
+
+	res.Events = make(map[string]string)
+	for name, versions := range local.Events {
+		remoteVersions, ok := remote.Events[name]
+		if !ok {
+			continue
+		}
+		v, ok := highestCommon(versions, remoteVersions)
+		if !ok {
+			return res, incompatible("%s %s supports %q event schema versions %v, %s %s supports %v", remote.Component, remote.Version, name, remoteVersions, local.Component, local.Version, versions)
+		}
+		res.Events[name] = v
+	}
+
+	return res, nil
+}
+
+// Do sends the local hello and checks the one the remote peer responded with.
+// Peers that don't know the handshake yet are accepted, legacy is true then.
+func Do(ctx context.Context, s Sender, local Hello, required []string) (res Result, legacy bool, err error) {
+	h, err := json.Marshal(local)
+	if err != nil {
+		return res, false, err
+	}
+
+	resp, err := s.SendSync(ctx, Method, []json.RawMessage{h})
+	if err != nil {
+		if errors.Is(err, jsonrpc.ErrMethodNotFound) {
+			return res, true, nil
+		}
+		return res, false, err
+	}
+
+	var remote Hello
+	if err := json.Unmarshal(resp.Result, &remote); err != nil {
+		return res, false, fmt.Errorf("error decoding handshake response: %w", err)
+	}
+
+	res, err = Check(local, remote, required)
+	return res, false, err
+}
+
+// NewHandler creates the handler of handshake call, responding with local hello.
+// Incompatible peers get ErrIncompatible, accepted ones are passed to onAccept.
+func NewHandler(local Hello, required []string, onAccept func(connID string, res Result)) connectivity.Handler {
+	return func(ctx context.Context, req connectivity.Request, resp connectivity.Response) {
+		args := req.Arguments()
+		if len(args) == 0 {
+			resp.Send(nil, jsonrpc.ErrInvalidParams)
+			return
+		}
+
+		var remote Hello
+		if err := json.Unmarshal(args[0], &remote); err != nil {
+			resp.Send(nil, &jsonrpc.Error{Code: jsonrpc.ErrInvalidParams.Code, Message: "error decoding hello: " + err.Error()})
+			return
+		}
+
+		res, err := Check(local, remote, required)
+		if err != nil {
+			resp.Send(nil, err)
+			return
+		}
+		if onAccept != nil {
+			onAccept(req.ConnID(), res)
+		}
+
+		h, err := json.Marshal(local)
+		if err != nil {
+			resp.Send(nil, err)
+			return
+		}
+		resp.Send(h, nil)
+	}
+}
+
+// Supports checks if version is one of the versions
+func Supports(versions []string, version string) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+func incompatible(format string, a ...interface{}) error {
+	return &jsonrpc.Error{Code: ErrIncompatible.Code, Message: ErrIncompatible.Message + ": " + fmt.Sprintf(format, a...)}
+}
+
+func major(version string) string {
+	return strings.SplitN(strings.TrimPrefix(version, "v"), ".", 2)[0]
+}
+
+// highestCommon returns the highest version present in both lists
+func highestCommon(a, b []string) (v string, ok bool) {
+	for _, va := range a {
+		if !Supports(b, va) {
+			continue
+		}
+		if !ok || less(v, va) {
+			v, ok = va, true
+		}
+	}
+	return v, ok
+}
+
+// less compares versions like "1.2" or "v1.2.3-rc.1" part by part, numeric parts numerically and the other ones
+// as strings. Pre-release version is lower than the release of the same version.
+func less(a, b string) bool {
+	ca, pa := splitPreRelease(a)
+	cb, pb := splitPreRelease(b)
+	if c := compareParts(ca, cb); c != 0 {
+		return c < 0
+	}
+
+	if pa == "" || pb == "" {
+		return pa != "" && pb == ""
+	}
+	return compareParts(pa, pb) < 0
+}
+
+func splitPreRelease(version string) (core, pre string) {
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexByte(version, '-'); i >= 0 {
+		return version[:i], version[i+1:]
+	}
+	return version, ""
+}
+
+// compareParts compares dot separated parts, version with more parts is greater if the common ones are equal
+func compareParts(a, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, errA := strconv.Atoi(pa[i])
+		nb, errB := strconv.Atoi(pb[i])
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				if na < nb {
+					return -1
+				}
+				return 1
+			}
+		case pa[i] != pb[i]:
+			return strings.Compare(pa[i], pb[i])
+		}
+	}
+	return len(pa) - len(pb)
+}
+
+// Headers carrying hello for the transports without handshake call, like gRPC streams
+const (
+	HeaderComponent = "X-Graph-Component"
+	HeaderVersion   = "X-Graph-Version"
+	HeaderProtocol  = "X-Graph-Protocol"
+	HeaderMethods   = "X-Graph-Methods"
+)
+
+// SetHeader sets hello in the header
+func (h Hello) SetHeader(header http.Header) {
+	header.Set(HeaderComponent, h.Component)
+	header.Set(HeaderVersion, h.Version)
+	header.Set(HeaderProtocol, h.Protocol)
+	header.Set(HeaderMethods, strings.Join(h.Methods, ","))
+}
+
+// FromHeader reads hello from the header, ok is false for peers that don't send it
+func FromHeader(header http.Header) (h Hello, ok bool) {
+	h.Protocol = header.Get(HeaderProtocol)
+	if h.Protocol == "" {
+		return h, false
+	}
+
+	h.Component = header.Get(HeaderComponent)
+	h.Version = header.Get(HeaderVersion)
+	if m := header.Get(HeaderMethods); m != "" {
+		h.Methods = strings.Split(m, ",")
+	}
+	return h, true
+}
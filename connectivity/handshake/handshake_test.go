@@ -0,0 +1,206 @@
+package handshake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/figment-networks/graph-demo/connectivity"
+	"github.com/figment-networks/graph-demo/connectivity/jsonrpc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		less bool
+	}{
+		{a: "1.0", b: "1.1", less: true},
+		{a: "1.1", b: "1.0"},
+		{a: "1.0", b: "1.0"},
+		{a: "1.9", b: "1.10", less: true},
+		{a: "v1.2", b: "1.3", less: true},
+		{a: "1.0", b: "1.0.1", less: true},
+		{a: "2.0", b: "10.0", less: true},
+		{a: "1.0.0-rc1", b: "1.0.0", less: true},
+		{a: "1.0.0", b: "1.0.0-rc1"},
+		{a: "1.0.0-rc.1", b: "1.0.0-rc.2", less: true},
+		{a: "1.0.0-rc.2", b: "1.0.0-rc.10", less: true},
+		{a: "1.0.0-alpha", b: "1.0.0-beta", less: true},
+		{a: "0.9.0", b: "1.0.0-rc1", less: true},
+		{a: "", b: "1", less: true},
+		{a: "1.x", b: "1.0"},
+		{a: "1.0", b: "1.x", less: true},
+		{a: "abc", b: "abd", less: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" < "+tt.b, func(t *testing.T) {
+			assert.Equal(t, tt.less, less(tt.a, tt.b))
+		})
+	}
+}
+
+func TestHighestCommon(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		v    string
+		ok   bool
+	}{
+		{name: "single common", a: []string{"1"}, b: []string{"1"}, v: "1", ok: true},
+		{name: "highest of common", a: []string{"1", "2", "3"}, b: []string{"2", "3", "4"}, v: "3", ok: true},
+		{name: "numeric order", a: []string{"1.9", "1.10"}, b: []string{"1.10", "1.9"}, v: "1.10", ok: true},
+		{name: "release over pre-release", a: []string{"2.0-rc1", "2.0"}, b: []string{"2.0", "2.0-rc1"}, v: "2.0", ok: true},
+		{name: "nothing common", a: []string{"1"}, b: []string{"2"}},
+		{name: "empty", a: nil, b: []string{"1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, ok := highestCommon(tt.a, tt.b)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.v, v)
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	local := Hello{
+		Component: "manager",
+		Protocol:  "1.0",
+		Events:    map[string][]string{"block": {"1", "2"}},
+	}
+
+	tests := []struct {
+		name     string
+		remote   Hello
+		required []string
+		events   map[string]string
+		err      bool
+	}{
+		{name: "compatible", remote: Hello{Protocol: "1.2", Methods: []string{"get_all"}}, required: []string{"get_all"}, events: map[string]string{}},
+		{name: "major with v", remote: Hello{Protocol: "v1.3"}, events: map[string]string{}},
+		{name: "other major", remote: Hello{Protocol: "2.0"}, err: true},
+		{name: "pre-release of other major", remote: Hello{Protocol: "2.0-rc1"}, err: true},
+		{name: "missing protocol", remote: Hello{}, err: true},
+		{name: "malformed protocol", remote: Hello{Protocol: "one"}, err: true},
+		{name: "missing method", remote: Hello{Protocol: "1.0", Methods: []string{"get_latest"}}, required: []string{"get_all"}, err: true},
+		{name: "negotiated events", remote: Hello{Protocol: "1.0", Events: map[string][]string{"block": {"2", "3"}}}, events: map[string]string{"block": "2"}},
+		{name: "incompatible events", remote: Hello{Protocol: "1.0", Events: map[string][]string{"block": {"3"}}}, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Check(local, tt.remote, tt.required)
+			if tt.err {
+				var rpcErr *jsonrpc.Error
+				require.True(t, errors.As(err, &rpcErr))
+				assert.Equal(t, ErrIncompatible.Code, rpcErr.Code)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.events, res.Events)
+		})
+	}
+}
+
+func TestHeader(t *testing.T) {
+	h := Hello{Component: "cosmos-worker", Version: "0.1.0", Protocol: "1.0", Methods: []string{"get_all", "get_latest"}}
+
+	header := http.Header{}
+	h.SetHeader(header)
+	got, ok := FromHeader(header)
+	require.True(t, ok)
+	assert.Equal(t, h, got)
+
+	_, ok = FromHeader(http.Header{})
+	assert.False(t, ok, "peer without hello headers")
+}
+
+// senderMock responds to the handshake with resp or err
+type senderMock struct {
+	resp json.RawMessage
+	err  error
+}
+
+func (sm senderMock) SendSync(ctx context.Context, method string, params []json.RawMessage) (jsonrpc.Response, error) {
+	return jsonrpc.Response{Result: sm.resp}, sm.err
+}
+
+func TestDo(t *testing.T) {
+	local := Hello{Component: "runner", Protocol: ProtocolVersion}
+
+	res, legacy, err := Do(context.Background(), senderMock{resp: []byte(`{"component":"manager","protocol":"1.1"}`)}, local, nil)
+	require.NoError(t, err)
+	assert.False(t, legacy)
+	assert.Equal(t, "manager", res.Remote.Component)
+
+	_, legacy, err = Do(context.Background(), senderMock{err: jsonrpc.ErrMethodNotFound}, local, nil)
+	require.NoError(t, err)
+	assert.True(t, legacy)
+
+	_, _, err = Do(context.Background(), senderMock{resp: []byte(`{"protocol":"2.0"}`)}, local, nil)
+	require.Error(t, err)
+
+	_, _, err = Do(context.Background(), senderMock{resp: []byte(`"ACK"`)}, local, nil)
+	require.Error(t, err)
+}
+
+// handlerMock serves every method with ACK
+type handlerMock struct{}
+
+func (hm handlerMock) Get(name string) (connectivity.Handler, bool) {
+	return func(ctx context.Context, req connectivity.Request, resp connectivity.Response) {
+		resp.Send([]byte(`"ACK"`), nil)
+	}, true
+}
+
+func (hm handlerMock) Add(name string, h connectivity.Handler) {}
+
+type requestMock struct {
+	connID string
+}
+
+func (rm requestMock) ConnID() string                    { return rm.connID }
+func (rm requestMock) Arguments() []json.RawMessage      { return nil }
+func (rm requestMock) Decode(i int, v interface{}) error { return nil }
+
+type responseMock struct {
+	result json.RawMessage
+	err    error
+}
+
+func (rm *responseMock) Send(result json.RawMessage, err error) error {
+	rm.result, rm.err = result, err
+	return nil
+}
+
+func TestGate(t *testing.T) {
+	call := func(fch connectivity.FunctionCallHandler, method, connID string) *responseMock {
+		h, ok := fch.Get(method)
+		require.True(t, ok)
+		resp := &responseMock{}
+		h(context.Background(), requestMock{connID: connID}, resp)
+		return resp
+	}
+
+	g := NewGate(handlerMock{}, []string{"register"})
+
+	assert.Equal(t, ErrHandshakeRequired, call(g, "register", "c1").err)
+	assert.NoError(t, call(g, "store_block", "c1").err, "other methods are not gated")
+
+	g.Accept("c1")
+	assert.NoError(t, call(g, "register", "c1").err)
+	assert.Equal(t, ErrHandshakeRequired, call(g, "register", "c2").err, "handshake is accepted per connection")
+
+	g.Forget("c1")
+	assert.Equal(t, ErrHandshakeRequired, call(g, "register", "c1").err)
+
+	open := NewGate(handlerMock{}, nil)
+	assert.NoError(t, call(open, "register", "c1").err, "legacy peers allowed")
+}
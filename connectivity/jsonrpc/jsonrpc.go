@@ -2,8 +2,9 @@ package jsonrpc
 
 import "encoding/json"
 
+// Request is a JSON-RPC request, the one without ID is a notification that gets no response
 type Request struct {
-	ID      uint64            `json:"id"`
+	ID      *uint64           `json:"id,omitempty"`
 	JSONRPC string            `json:"jsonrpc"`
 	Method  string            `json:"method"`
 	Params  []json.RawMessage `json:"params"`
@@ -15,6 +16,25 @@ type Error struct {
 	Data    []interface{} `json:"data"`
 }
 
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is makes errors with the same code equal, so errors.Is(err, jsonrpc.ErrMethodNotFound) works for received errors
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t != nil && e != nil && t.Code == e.Code
+}
+
+// Standard JSON-RPC 2.0 errors
+var (
+	ErrParse          = &Error{Code: -32700, Message: "Parse error"}
+	ErrInvalidRequest = &Error{Code: -32600, Message: "Invalid Request"}
+	ErrMethodNotFound = &Error{Code: -32601, Message: "Method not found"}
+	ErrInvalidParams  = &Error{Code: -32602, Message: "Invalid params"}
+	ErrInternal       = &Error{Code: -32603, Message: "Internal error"}
+)
+
 type Response struct {
 	ID      uint64          `json:"id"`
 	JSONRPC string          `json:"jsonrpc"`
@@ -22,8 +42,9 @@ type Response struct {
 	Result  json.RawMessage `json:"result"`
 }
 
+// Hybrid is either request or response, ID is nil for notifications
 type Hybrid struct {
-	ID      uint64            `json:"id"`
+	ID      *uint64           `json:"id"`
 	JSONRPC string            `json:"jsonrpc"`
 	Method  string            `json:"method"`
 	Params  []json.RawMessage `json:"params"`
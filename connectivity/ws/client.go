@@ -0,0 +1,247 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/figment-networks/graph-demo/connectivity"
+	"github.com/figment-networks/graph-demo/connectivity/jsonrpc"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+type ConnState string
+
+const (
+	StateConnecting   ConnState = "connecting"
+	StateConnected    ConnState = "connected"
+	StateDisconnected ConnState = "disconnected"
+	StateClosed       ConnState = "closed"
+)
+
+type ReconnectConfig struct {
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+var DefaultReconnectConfig = ReconnectConfig{
+	InitialBackoff: time.Second,
+	MaxBackoff:     time.Minute,
+}
+
+// Backoff returns exponential backoff with jitter in range [b/2, b) for given (1-indexed) attempt
+func (rc ReconnectConfig) Backoff(attempt int) time.Duration {
+	b := rc.InitialBackoff
+	for i := 1; i < attempt && b < rc.MaxBackoff; i++ {
+		b *= 2
+	}
+	if b > rc.MaxBackoff {
+		b = rc.MaxBackoff
+	}
+
+	half := int64(b / 2)
+	if half <= 0 {
+		return b
+	}
+	return time.Duration(half + rand.Int63n(half))
+}
+
+// Client is a websocket session that reconnects after the connection is lost.
+// OnConnect hooks are called after every (re)connect, before the client is reported as connected,
+// so the state of the connection (like registrations or subscriptions) can be restored.
+type Client struct {
+	address string
+	l       *zap.Logger
+	rh      connectivity.FunctionCallHandler
+	cfg     ReconnectConfig
+
+	// header returns the header of every dial, like credentials
+	header func() http.Header
+	dialer websocket.Dialer
+
+	sess *Session
+	sl   sync.RWMutex
+
+	onConnect []func(ctx context.Context, ss SyncSender) error
+	onState   []func(state ConnState, err error)
+	hl        sync.RWMutex
+}
+
+func NewClient(l *zap.Logger, address string, rh connectivity.FunctionCallHandler, cfg ReconnectConfig) *Client {
+	return &Client{
+		address: address,
+		l:       l,
+		rh:      rh,
+		cfg:     cfg,
+		dialer:  *websocket.DefaultDialer,
+	}
+}
+
+// DialOptions are the options of the connection set by the users of Client
+type DialOptions struct {
+	// Header returns the header of every dial, like credentials
+	Header func() http.Header
+	// Encoding proposed to the server, JSON if empty
+	Encoding string
+	// Compression enables per-message compression
+	Compression bool
+	// OnState is called on every change of the connection state
+	OnState func(state ConnState, err error)
+	// Version of the connecting component, announced to the server in handshake
+	Version string
+}
+
+// Apply sets the options of the connection, it has to be called before Connect
+func (c *Client) Apply(opts DialOptions) {
+	if opts.Header != nil {
+		c.SetHeader(opts.Header)
+	}
+	c.SetEncoding(opts.Encoding, opts.Compression)
+	if opts.OnState != nil {
+		c.OnStateChange(opts.OnState)
+	}
+}
+
+// SetEncoding sets the encoding proposed to the server and enables per-message compression.
+// Server that doesn't support the encoding falls back to JSON.
+func (c *Client) SetEncoding(encoding string, compression bool) {
+	c.dialer.Subprotocols = nil
+	if encoding != "" && encoding != EncodingJSON {
+		c.dialer.Subprotocols = []string{encoding}
+	}
+	c.dialer.EnableCompression = compression
+}
+
+// SetHeader sets the function returning header sent on every dial
+func (c *Client) SetHeader(f func() http.Header) {
+	c.header = f
+}
+
+// OnConnect adds hook called after every successful connection
+func (c *Client) OnConnect(f func(ctx context.Context, ss SyncSender) error) {
+	c.hl.Lock()
+	defer c.hl.Unlock()
+	c.onConnect = append(c.onConnect, f)
+}
+
+// OnStateChange adds hook called on every change of the connection state
+func (c *Client) OnStateChange(f func(state ConnState, err error)) {
+	c.hl.Lock()
+	defer c.hl.Unlock()
+	c.onState = append(c.onState, f)
+}
+
+// Connect makes the first connection and keeps reconnecting in background until ctx is done
+func (c *Client) Connect(ctx context.Context) error {
+	sess, err := c.connect(ctx)
+	if err != nil {
+		c.setState(StateDisconnected, err)
+		return err
+	}
+
+	go c.run(ctx, sess)
+	return nil
+}
+
+func (c *Client) run(ctx context.Context, sess *Session) {
+	for {
+		select {
+		case <-ctx.Done():
+			c.setState(StateClosed, ctx.Err())
+			return
+		case <-sess.Done():
+		}
+		c.setState(StateDisconnected, sess.Err())
+
+		var attempt int
+		for {
+			attempt++
+			select {
+			case <-ctx.Done():
+				c.setState(StateClosed, ctx.Err())
+				return
+			case <-time.After(c.cfg.Backoff(attempt)):
+			}
+
+			var err error
+			if sess, err = c.connect(ctx); err == nil {
+				break
+			}
+			c.l.Warn("error reconnecting", zap.String("address", c.address), zap.Int("attempt", attempt), zap.Error(err))
+			c.setState(StateDisconnected, err)
+		}
+	}
+}
+
+func (c *Client) connect(ctx context.Context) (*Session, error) {
+	c.setState(StateConnecting, nil)
+
+	var header http.Header
+	if c.header != nil {
+		header = c.header()
+	}
+
+	conn, _, err := c.dialer.DialContext(ctx, c.address, header)
+	if err != nil {
+		return nil, err
+	}
+
+	sess := NewSession(ctx, conn, c.l, c.rh)
+	go sess.Recv()
+	go sess.Req()
+
+	c.hl.RLock()
+	hooks := c.onConnect
+	c.hl.RUnlock()
+
+	for _, f := range hooks {
+		if err := f(ctx, sess); err != nil {
+			sess.Close(err)
+			return nil, err
+		}
+	}
+
+	c.sl.Lock()
+	c.sess = sess
+	c.sl.Unlock()
+
+	c.setState(StateConnected, nil)
+	return sess, nil
+}
+
+func (c *Client) setState(state ConnState, err error) {
+	c.hl.RLock()
+	defer c.hl.RUnlock()
+	for _, f := range c.onState {
+		f(state, err)
+	}
+}
+
+// SendSync sends request using current session
+func (c *Client) SendSync(ctx context.Context, method string, params []json.RawMessage) (resp jsonrpc.Response, e error) {
+	c.sl.RLock()
+	sess := c.sess
+	c.sl.RUnlock()
+
+	if sess == nil || sess.Err() != nil {
+		return resp, ErrConnectionClosed
+	}
+	return sess.SendSync(ctx, method, params)
+}
+
+// SendSyncValues sends request with params encoded by the codec of current session
+func (c *Client) SendSyncValues(ctx context.Context, method string, params ...interface{}) (resp jsonrpc.Response, e error) {
+	c.sl.RLock()
+	sess := c.sess
+	c.sl.RUnlock()
+
+	if sess == nil || sess.Err() != nil {
+		return resp, ErrConnectionClosed
+	}
+	return sess.SendSyncValues(ctx, method, params...)
+}
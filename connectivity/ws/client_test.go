@@ -0,0 +1,210 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/figment-networks/graph-demo/connectivity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// registerHandler acknowledges every call, reporting the connections that registered
+type registerHandler struct {
+	registered chan string
+}
+
+func (rh *registerHandler) Get(name string) (connectivity.Handler, bool) {
+	return func(ctx context.Context, req connectivity.Request, resp connectivity.Response) {
+		if name == "register" {
+			rh.registered <- req.ConnID()
+		}
+		resp.Send([]byte(`"ACK"`), nil)
+	}, true
+}
+
+func (rh *registerHandler) Add(name string, h connectivity.Handler) {}
+
+// testServer accepts client sessions, the sessions are available to close them on the server side
+type testServer struct {
+	*httptest.Server
+	rh       *registerHandler
+	sessions chan *Session
+}
+
+func newTestServer(t *testing.T, ctx context.Context) *testServer {
+	ts := &testServer{
+		rh:       &registerHandler{registered: make(chan string, 10)},
+		sessions: make(chan *Session, 10),
+	}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		sess := NewSession(ctx, conn, zap.NewNop(), ts.rh)
+		go sess.Recv()
+		go sess.Req()
+		ts.sessions <- sess
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func (ts *testServer) address() string {
+	return "ws" + strings.TrimPrefix(ts.URL, "http")
+}
+
+// stateRecorder records the state changes of the client
+type stateRecorder struct {
+	states chan ConnState
+	errs   []error
+	l      sync.Mutex
+}
+
+func newStateRecorder() *stateRecorder {
+	return &stateRecorder{states: make(chan ConnState, 20)}
+}
+
+func (sr *stateRecorder) record(state ConnState, err error) {
+	sr.l.Lock()
+	sr.errs = append(sr.errs, err)
+	sr.l.Unlock()
+	sr.states <- state
+}
+
+// expect waits for the given states in order
+func (sr *stateRecorder) expect(t *testing.T, states ...ConnState) {
+	t.Helper()
+	for _, want := range states {
+		select {
+		case got := <-sr.states:
+			require.Equal(t, want, got)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for state %s", want)
+		}
+	}
+}
+
+func expectRegistered(t *testing.T, ts *testServer) {
+	t.Helper()
+	select {
+	case <-ts.rh.registered:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for registration")
+	}
+}
+
+func expectSession(t *testing.T, ts *testServer) *Session {
+	t.Helper()
+	select {
+	case sess := <-ts.sessions:
+		return sess
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for session")
+		return nil
+	}
+}
+
+var testReconnectConfig = ReconnectConfig{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+
+func TestClientReconnects(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ts := newTestServer(t, ctx)
+
+	sr := newStateRecorder()
+	c := NewClient(zap.NewNop(), ts.address(), &registerHandler{registered: make(chan string, 10)}, testReconnectConfig)
+	c.OnStateChange(sr.record)
+	c.OnConnect(func(ctx context.Context, ss SyncSender) error {
+		_, err := ss.SendSync(ctx, "register", nil)
+		return err
+	})
+
+	require.NoError(t, c.Connect(ctx))
+	sr.expect(t, StateConnecting, StateConnected)
+	expectRegistered(t, ts)
+
+	// server drops the connection
+	expectSession(t, ts).Close(nil)
+
+	sr.expect(t, StateDisconnected, StateConnecting, StateConnected)
+	expectRegistered(t, ts)
+	expectSession(t, ts)
+
+	resp, err := c.SendSync(ctx, "query", nil)
+	require.NoError(t, err)
+	assert.Equal(t, `"ACK"`, string(resp.Result))
+
+	cancel()
+	sr.expect(t, StateClosed)
+}
+
+func TestClientRetriesFailedOnConnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ts := newTestServer(t, ctx)
+
+	errRegister := errors.New("register failed")
+	var connects int
+
+	sr := newStateRecorder()
+	c := NewClient(zap.NewNop(), ts.address(), &registerHandler{registered: make(chan string, 10)}, testReconnectConfig)
+	c.OnStateChange(sr.record)
+	c.OnConnect(func(ctx context.Context, ss SyncSender) error {
+		connects++
+		if connects == 2 {
+			return errRegister
+		}
+		_, err := ss.SendSync(ctx, "register", nil)
+		return err
+	})
+
+	require.NoError(t, c.Connect(ctx))
+	sr.expect(t, StateConnecting, StateConnected)
+	expectRegistered(t, ts)
+
+	expectSession(t, ts).Close(nil)
+
+	// hook fails on the first reconnect, client keeps reconnecting with backoff
+	sr.expect(t, StateDisconnected, StateConnecting, StateDisconnected, StateConnecting, StateConnected)
+	expectRegistered(t, ts)
+	assert.Equal(t, 3, connects)
+
+	sr.l.Lock()
+	assert.Contains(t, sr.errs, errRegister)
+	sr.l.Unlock()
+}
+
+func TestClientSendWithoutConnection(t *testing.T) {
+	c := NewClient(zap.NewNop(), "ws://127.0.0.1:1", nil, testReconnectConfig)
+
+	_, err := c.SendSync(context.Background(), "query", nil)
+	require.ErrorIs(t, err, ErrConnectionClosed)
+
+	sr := newStateRecorder()
+	c.OnStateChange(sr.record)
+	require.Error(t, c.Connect(context.Background()))
+	sr.expect(t, StateConnecting, StateDisconnected)
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	rc := ReconnectConfig{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	for attempt, b := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 10: 5 * time.Second} {
+		for i := 0; i < 10; i++ {
+			d := rc.Backoff(attempt)
+			assert.GreaterOrEqual(t, int64(d), int64(b/2), "attempt %d", attempt)
+			assert.Less(t, int64(d), int64(b), "attempt %d", attempt)
+		}
+	}
+}
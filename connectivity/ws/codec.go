@@ -0,0 +1,329 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+
+	"github.com/figment-networks/graph-demo/connectivity/jsonrpc"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/gorilla/websocket"
+)
+
+// Encodings of the session, negotiated as websocket subprotocols
+const (
+	EncodingJSON = "jsonrpc-json"
+	EncodingCBOR = "jsonrpc-cbor"
+)
+
+var ErrUnknownEncoding = errors.New("unknown encoding")
+
+// Subprotocols returns the encodings supported by server in order of preference
+func Subprotocols() []string {
+	return []string{EncodingCBOR, EncodingJSON}
+}
+
+// message is the wire representation of JSON-RPC request or response.
+// Params and Result are encoded with the codec of the session.
+// Notification is the request sent without ID.
+type message struct {
+	ID           uint64
+	Notification bool
+	JSONRPC      string
+	Method       string
+	Params       [][]byte
+	Error        *jsonrpc.Error
+	Result       []byte
+}
+
+// Codec encodes messages of the session
+type Codec interface {
+	Name() string
+	// MessageType - websocket message type of the frames
+	MessageType() int
+
+	// Encode encodes messages of the frame, batch is encoded as an array even if it has a single message
+	Encode(msgs []message, batch bool) ([]byte, error)
+	Decode(data []byte) (msgs []message, batch bool, err error)
+
+	// Marshal encodes single param or result
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal decodes single param or result
+	Unmarshal(data []byte, v interface{}) error
+
+	// ToJSON converts encoded param or result to JSON
+	ToJSON(raw []byte) (json.RawMessage, error)
+	// FromJSON converts JSON param or result to the encoding
+	FromJSON(raw json.RawMessage) ([]byte, error)
+}
+
+// CodecFor returns the codec of negotiated subprotocol, JSON if none was negotiated
+func CodecFor(subprotocol string) (Codec, error) {
+	switch subprotocol {
+	case "", EncodingJSON:
+		return jsonCodec{}, nil
+	case EncodingCBOR:
+		return newCBORCodec(), nil
+	default:
+		return nil, ErrUnknownEncoding
+	}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return EncodingJSON
+}
+
+func (jsonCodec) MessageType() int {
+	return websocket.TextMessage
+}
+
+func (c jsonCodec) Encode(msgs []message, batch bool) ([]byte, error) {
+	if !batch && len(msgs) == 1 {
+		return c.encode(msgs[0])
+	}
+
+	arr := make([]json.RawMessage, len(msgs))
+	for i, m := range msgs {
+		var err error
+		if arr[i], err = c.encode(m); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(arr)
+}
+
+func (jsonCodec) encode(m message) ([]byte, error) {
+	if m.Method != "" {
+		req := jsonrpc.Request{JSONRPC: m.JSONRPC, Method: m.Method, Params: make([]json.RawMessage, len(m.Params))}
+		if !m.Notification {
+			id := m.ID
+			req.ID = &id
+		}
+		for i, p := range m.Params {
+			req.Params[i] = p
+		}
+		return json.Marshal(req)
+	}
+	return json.Marshal(jsonrpc.Response{ID: m.ID, JSONRPC: m.JSONRPC, Error: m.Error, Result: m.Result})
+}
+
+func (c jsonCodec) Decode(data []byte) (msgs []message, batch bool, err error) {
+	if d := bytes.TrimLeft(data, " \t\r\n"); len(d) > 0 && d[0] == '[' {
+		var arr []json.RawMessage
+		if err := json.Unmarshal(d, &arr); err != nil {
+			return nil, true, err
+		}
+
+		msgs = make([]message, len(arr))
+		for i, a := range arr {
+			if msgs[i], err = c.decode(a); err != nil {
+				return nil, true, err
+			}
+		}
+		return msgs, true, nil
+	}
+
+	m, err := c.decode(data)
+	if err != nil {
+		return nil, false, err
+	}
+	return []message{m}, false, nil
+}
+
+func (jsonCodec) decode(data []byte) (m message, err error) {
+	h := &jsonrpc.Hybrid{}
+	if err := json.Unmarshal(data, h); err != nil {
+		return m, err
+	}
+
+	m = message{JSONRPC: h.JSONRPC, Method: h.Method, Error: h.Error, Result: h.Result}
+	if h.ID != nil {
+		m.ID = *h.ID
+	} else {
+		m.Notification = m.Method != ""
+	}
+	if h.Params != nil {
+		m.Params = make([][]byte, len(h.Params))
+		for i, p := range h.Params {
+			m.Params[i] = p
+		}
+	}
+	return m, nil
+}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) ToJSON(raw []byte) (json.RawMessage, error) {
+	return raw, nil
+}
+
+func (jsonCodec) FromJSON(raw json.RawMessage) ([]byte, error) {
+	return raw, nil
+}
+
+// cborMessage uses integer keys to keep the frames small, jsonrpc version is always 2.0.
+// ID is nil for notifications.
+type cborMessage struct {
+	ID     *uint64           `cbor:"1,keyasint,omitempty"`
+	Method string            `cbor:"2,keyasint,omitempty"`
+	Params []cbor.RawMessage `cbor:"3,keyasint,omitempty"`
+	Error  *jsonrpc.Error    `cbor:"4,keyasint,omitempty"`
+	Result cbor.RawMessage   `cbor:"5,keyasint,omitempty"`
+}
+
+type cborCodec struct {
+	enc cbor.EncMode
+	dec cbor.DecMode
+}
+
+func newCBORCodec() cborCodec {
+	enc, _ := cbor.EncOptions{Time: cbor.TimeRFC3339Nano}.EncMode()
+	// maps decoded into interface{} have to be JSON compatible
+	dec, _ := cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]interface{}{})}.DecMode()
+	return cborCodec{enc: enc, dec: dec}
+}
+
+func (cborCodec) Name() string {
+	return EncodingCBOR
+}
+
+func (cborCodec) MessageType() int {
+	return websocket.BinaryMessage
+}
+
+func (c cborCodec) Encode(msgs []message, batch bool) ([]byte, error) {
+	if !batch && len(msgs) == 1 {
+		return c.enc.Marshal(toCBORMessage(msgs[0]))
+	}
+
+	arr := make([]cborMessage, len(msgs))
+	for i, m := range msgs {
+		arr[i] = toCBORMessage(m)
+	}
+	return c.enc.Marshal(arr)
+}
+
+func toCBORMessage(m message) cborMessage {
+	cm := cborMessage{Method: m.Method, Error: m.Error, Result: m.Result}
+	if !m.Notification {
+		id := m.ID
+		cm.ID = &id
+	}
+	if m.Params != nil {
+		cm.Params = make([]cbor.RawMessage, len(m.Params))
+		for i, p := range m.Params {
+			cm.Params[i] = p
+		}
+	}
+	return cm
+}
+
+// cborArray is the major type of CBOR arrays, stored in the top 3 bits of the initial byte
+const cborArray = 4
+
+func (c cborCodec) Decode(data []byte) (msgs []message, batch bool, err error) {
+	if len(data) > 0 && data[0]>>5 == cborArray {
+		var arr []cborMessage
+		if err := c.dec.Unmarshal(data, &arr); err != nil {
+			return nil, true, err
+		}
+
+		msgs = make([]message, len(arr))
+		for i, cm := range arr {
+			msgs[i] = fromCBORMessage(cm)
+		}
+		return msgs, true, nil
+	}
+
+	cm := cborMessage{}
+	if err := c.dec.Unmarshal(data, &cm); err != nil {
+		return nil, false, err
+	}
+	return []message{fromCBORMessage(cm)}, false, nil
+}
+
+func fromCBORMessage(cm cborMessage) message {
+	m := message{JSONRPC: "2.0", Method: cm.Method, Error: cm.Error, Result: cm.Result}
+	if cm.ID != nil {
+		m.ID = *cm.ID
+	} else {
+		m.Notification = m.Method != ""
+	}
+	if cm.Params != nil {
+		m.Params = make([][]byte, len(cm.Params))
+		for i, p := range cm.Params {
+			m.Params[i] = p
+		}
+	}
+	return m
+}
+
+func (c cborCodec) Marshal(v interface{}) ([]byte, error) {
+	return c.enc.Marshal(v)
+}
+
+func (c cborCodec) Unmarshal(data []byte, v interface{}) error {
+	return c.dec.Unmarshal(data, v)
+}
+
+func (c cborCodec) ToJSON(raw []byte) (json.RawMessage, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	var v interface{}
+	if err := c.dec.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+func (c cborCodec) FromJSON(raw json.RawMessage) ([]byte, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return c.enc.Marshal(numbers(v))
+}
+
+// numbers converts json.Number values to integers where possible, so they're not encoded as floats
+func numbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(string(t), 10, 64); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(string(t), 10, 64); err == nil {
+			return u
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = numbers(e)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = numbers(e)
+		}
+	}
+	return v
+}
@@ -0,0 +1,251 @@
+package ws
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/figment-networks/graph-demo/connectivity"
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// benchBlock returns block with txs of the size typical for cosmoshub-4
+func benchBlock(txs int) (structs.Block, []structs.Transaction) {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	block := structs.Block{
+		Hash:    strings.Repeat("AB", 32),
+		Height:  5200791,
+		Time:    now,
+		ChainID: "cosmoshub-4",
+		Header: structs.BlockHeader{
+			ChainID:         "cosmoshub-4",
+			Height:          5200791,
+			Time:            now,
+			AppHash:         strings.Repeat("CD", 32),
+			ProposerAddress: strings.Repeat("EF", 20),
+		},
+	}
+
+	transactions := make([]structs.Transaction, txs)
+	for i := range transactions {
+		raw := make([]byte, 400)
+		rand.Read(raw)
+		block.Data.Txs = append(block.Data.Txs, raw)
+
+		msg := make([]byte, 150)
+		rand.Read(msg)
+		transactions[i] = structs.Transaction{
+			ChainID:   "cosmoshub-4",
+			Height:    5200791,
+			Hash:      fmt.Sprintf("%064X", i),
+			BlockHash: block.Hash,
+			Time:      now,
+			GasWanted: 200000,
+			GasUsed:   85000,
+			Memo:      "benchmark",
+			Messages:  []structs.Any{{TypeURL: "/cosmos.bank.v1beta1.MsgSend", Value: msg}},
+			Logs: []structs.Log{{Events: []structs.Event{{
+				Type:       "transfer",
+				Attributes: map[string]string{"recipient": "cosmos1recipient", "sender": "cosmos1sender", "amount": "1000uatom"},
+			}}}},
+			RawLog: []byte(`[{"events":[{"type":"transfer","attributes":[{"key":"recipient","value":"cosmos1recipient"}]}]}]`),
+			TxRaw:  structs.Any{Value: raw},
+		}
+	}
+	return block, transactions
+}
+
+type benchHandler struct {
+	received chan structs.Block
+}
+
+func (bh *benchHandler) Get(name string) (connectivity.Handler, bool) {
+	return func(ctx context.Context, req connectivity.Request, resp connectivity.Response) {
+		var block structs.Block
+		if err := req.Decode(0, &block); err != nil {
+			resp.Send(nil, err)
+			return
+		}
+		select {
+		case bh.received <- block:
+		default:
+		}
+		resp.Send([]byte(`"ACK"`), nil)
+	}, true
+}
+
+func (bh *benchHandler) Add(name string, h connectivity.Handler) {}
+
+// benchSession connects client session to the test server using given encoding
+func benchSession(b *testing.B, encoding string, compression bool) (*Session, *benchHandler, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	bh := &benchHandler{received: make(chan structs.Block, 1)}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			b.Error(err)
+			return
+		}
+		sess := NewSession(ctx, conn, zap.NewNop(), bh)
+		go sess.Recv()
+		go sess.Req()
+	}))
+
+	dialer := websocket.Dialer{EnableCompression: compression}
+	if encoding != EncodingJSON {
+		dialer.Subprotocols = []string{encoding}
+	}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	sess := NewSession(ctx, conn, zap.NewNop(), bh)
+	go sess.Recv()
+	go sess.Req()
+
+	return sess, bh, func() {
+		cancel()
+		srv.Close()
+	}
+}
+
+// frameSizes returns the size of the store_block frame and its size after deflate
+func frameSizes(b *testing.B, codec Codec, block structs.Block) (plain, compressed int) {
+	p, err := codec.Marshal(block)
+	if err != nil {
+		b.Fatal(err)
+	}
+	frame, err := codec.Encode([]message{{ID: 1, JSONRPC: "2.0", Method: "store_block", Params: [][]byte{p}}}, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	buff := new(bytes.Buffer)
+	fw, _ := flate.NewWriter(buff, flate.BestSpeed)
+	fw.Write(frame)
+	fw.Close()
+	return len(frame), buff.Len()
+}
+
+var benchCases = []struct {
+	encoding    string
+	compression bool
+}{
+	{EncodingJSON, false},
+	{EncodingJSON, true},
+	{EncodingCBOR, false},
+	{EncodingCBOR, true},
+}
+
+func benchName(encoding string, compression bool) string {
+	if compression {
+		return encoding + "/deflate"
+	}
+	return encoding
+}
+
+func BenchmarkSessionStoreBlock(b *testing.B) {
+	block, _ := benchBlock(100)
+
+	for _, bc := range benchCases {
+		b.Run(benchName(bc.encoding, bc.compression), func(b *testing.B) {
+			sess, bh, closeFn := benchSession(b, bc.encoding, bc.compression)
+			defer closeFn()
+
+			if sess.Encoding() != bc.encoding {
+				b.Fatalf("negotiated %s, expected %s", sess.Encoding(), bc.encoding)
+			}
+
+			// sanity check, block has to arrive unchanged
+			if _, err := sess.SendSyncValues(context.Background(), "store_block", block); err != nil {
+				b.Fatal(err)
+			}
+			if got := <-bh.received; !reflect.DeepEqual(got, block) {
+				b.Fatal("block changed in transport")
+			}
+
+			codec, _ := CodecFor(bc.encoding)
+			plain, compressed := frameSizes(b, codec, block)
+			size := plain
+			if bc.compression {
+				size = compressed
+			}
+			b.SetBytes(int64(plain))
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := sess.SendSyncValues(context.Background(), "store_block", block); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(size), "wire-B/op")
+		})
+	}
+}
+
+func BenchmarkSessionStoreBlockParallel(b *testing.B) {
+	block, _ := benchBlock(100)
+
+	for _, bc := range benchCases {
+		b.Run(benchName(bc.encoding, bc.compression), func(b *testing.B) {
+			sess, _, closeFn := benchSession(b, bc.encoding, bc.compression)
+			defer closeFn()
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := sess.SendSyncValues(context.Background(), "store_block", block); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkCodecTransactions(b *testing.B) {
+	_, txs := benchBlock(100)
+
+	for _, encoding := range []string{EncodingJSON, EncodingCBOR} {
+		codec, _ := CodecFor(encoding)
+
+		b.Run(encoding+"/marshal", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := codec.Marshal(txs); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		data, err := codec.Marshal(txs)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(encoding+"/unmarshal", func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				var out []structs.Transaction
+				if err := codec.Unmarshal(data, &out); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
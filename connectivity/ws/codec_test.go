@@ -0,0 +1,53 @@
+package ws
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeNotification(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		id           uint64
+		notification bool
+	}{
+		{name: "request", data: `{"jsonrpc":"2.0","id":7,"method":"event","params":[]}`, id: 7},
+		{name: "request with id 0", data: `{"jsonrpc":"2.0","id":0,"method":"event","params":[]}`},
+		{name: "notification", data: `{"jsonrpc":"2.0","method":"event","params":[]}`, notification: true},
+		{name: "response with id 0", data: `{"jsonrpc":"2.0","id":0,"result":"ACK"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs, _, err := jsonCodec{}.Decode([]byte(tt.data))
+			require.NoError(t, err)
+			require.Len(t, msgs, 1)
+			assert.Equal(t, tt.id, msgs[0].ID)
+			assert.Equal(t, tt.notification, msgs[0].Notification)
+		})
+	}
+}
+
+func TestEncodeNotification(t *testing.T) {
+	for _, c := range []Codec{jsonCodec{}, newCBORCodec()} {
+		t.Run(c.Name(), func(t *testing.T) {
+			msgs := []message{
+				{ID: 0, JSONRPC: "2.0", Method: "event"},
+				{JSONRPC: "2.0", Method: "event", Notification: true},
+			}
+
+			data, err := c.Encode(msgs, true)
+			require.NoError(t, err)
+
+			decoded, batch, err := c.Decode(data)
+			require.NoError(t, err)
+			assert.True(t, batch)
+			require.Len(t, decoded, 2)
+			assert.False(t, decoded[0].Notification, "request with id 0 is not a notification")
+			assert.True(t, decoded[1].Notification)
+		})
+	}
+}
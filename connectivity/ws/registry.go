@@ -0,0 +1,179 @@
+package ws
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Roles of the sessions connected to manager
+const (
+	RoleWorker = "worker"
+	RoleRunner = "runner"
+	RoleAdmin  = "admin"
+)
+
+// SessionInfo describes live session
+type SessionInfo struct {
+	ID   string `json:"id"`
+	Role string `json:"role"`
+	// Name of the authenticated client
+	Name string `json:"name,omitempty"`
+	// ChainIDs the session is allowed to work with, all chains if empty
+	ChainIDs  []string          `json:"chain_ids,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+type registered struct {
+	ss   SyncSender
+	info SessionInfo
+}
+
+// Registry keeps live sessions. Sessions added with AddSession are removed as soon as they're closed.
+type Registry struct {
+	sessions map[string]*registered
+	sl       sync.RWMutex
+
+	onRemove []func(info SessionInfo)
+	hl       sync.RWMutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{sessions: make(map[string]*registered)}
+}
+
+// Add adds sender under given id, it has to be removed manually
+func (r *Registry) Add(connID string, ss SyncSender) {
+	r.sl.Lock()
+	defer r.sl.Unlock()
+	r.sessions[connID] = &registered{ss: ss, info: SessionInfo{ID: connID, CreatedAt: time.Now()}}
+}
+
+// AddSession adds session described by info, removing it from registry after it's closed
+func (r *Registry) AddSession(sess *Session, info SessionInfo) {
+	info.ID = sess.ID
+	info.CreatedAt = sess.CreatedAt
+
+	r.sl.Lock()
+	r.sessions[sess.ID] = &registered{ss: sess, info: info}
+	r.sl.Unlock()
+
+	sess.OnClose(func(reason error) {
+		r.Remove(sess.ID)
+	})
+}
+
+// AddInfo adds session that isn't called through registry, like the gRPC stream of a worker.
+// It has to be removed with Remove.
+func (r *Registry) AddInfo(info SessionInfo) {
+	if info.CreatedAt.IsZero() {
+		info.CreatedAt = time.Now()
+	}
+
+	r.sl.Lock()
+	defer r.sl.Unlock()
+	r.sessions[info.ID] = &registered{info: info}
+}
+
+func (r *Registry) Get(connID string) (ss SyncSender, ok bool) {
+	r.sl.RLock()
+	defer r.sl.RUnlock()
+	rs, ok := r.sessions[connID]
+	if !ok || rs.ss == nil {
+		return nil, false
+	}
+
+	return rs.ss, true
+}
+
+// Info returns the description of the session
+func (r *Registry) Info(connID string) (info SessionInfo, ok bool) {
+	r.sl.RLock()
+	defer r.sl.RUnlock()
+	rs, ok := r.sessions[connID]
+	if !ok {
+		return info, false
+	}
+	return rs.info, true
+}
+
+// AllowsChain checks if session is allowed to work with given chain
+func (r *Registry) AllowsChain(connID, chainID string) bool {
+	info, ok := r.Info(connID)
+	if !ok {
+		return false
+	}
+	if len(info.ChainIDs) == 0 {
+		return true
+	}
+	for _, c := range info.ChainIDs {
+		if c == chainID {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove removes session from registry and calls OnRemove hooks
+func (r *Registry) Remove(connID string) {
+	r.sl.Lock()
+	rs, ok := r.sessions[connID]
+	delete(r.sessions, connID)
+	r.sl.Unlock()
+
+	if !ok {
+		return
+	}
+
+	r.hl.RLock()
+	defer r.hl.RUnlock()
+	for _, f := range r.onRemove {
+		f(rs.info)
+	}
+}
+
+// OnRemove adds hook called after the session is removed from registry
+func (r *Registry) OnRemove(f func(info SessionInfo)) {
+	r.hl.Lock()
+	defer r.hl.Unlock()
+	r.onRemove = append(r.onRemove, f)
+}
+
+// SetMetadata sets metadata value of the session
+func (r *Registry) SetMetadata(connID, key, value string) {
+	r.sl.Lock()
+	defer r.sl.Unlock()
+
+	rs, ok := r.sessions[connID]
+	if !ok {
+		return
+	}
+	if rs.info.Metadata == nil {
+		rs.info.Metadata = make(map[string]string)
+	}
+	rs.info.Metadata[key] = value
+}
+
+// List returns live sessions, with given role if it's not empty, ordered by creation time
+func (r *Registry) List(role string) []SessionInfo {
+	r.sl.RLock()
+	defer r.sl.RUnlock()
+
+	infos := make([]SessionInfo, 0, len(r.sessions))
+	for _, rs := range r.sessions {
+		if role != "" && rs.info.Role != role {
+			continue
+		}
+
+		info := rs.info
+		info.Metadata = make(map[string]string, len(rs.info.Metadata))
+		for k, v := range rs.info.Metadata {
+			info.Metadata[k] = v
+		}
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].CreatedAt.Before(infos[j].CreatedAt) })
+	return infos
+}
@@ -1,9 +1,10 @@
 package ws
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -22,48 +23,54 @@ const (
 	pingTime = 50 * time.Second
 )
 
-type SyncSender interface {
-	SendSync(method string, params []json.RawMessage) (resp jsonrpc.Response, e error)
-}
+var ErrMissingParam = errors.New("missing param")
 
-type Registry struct {
-	sessions map[string]SyncSender
-	sl       sync.RWMutex
-}
+// DefaultSendTimeout is the time SendSync waits for the response if the context has no deadline
+var DefaultSendTimeout = 2 * time.Minute
 
-func NewRegistry() *Registry {
-	return &Registry{sessions: make(map[string]SyncSender)}
+type SyncSender interface {
+	SendSync(ctx context.Context, method string, params []json.RawMessage) (resp jsonrpc.Response, e error)
 }
 
-func (r *Registry) Add(connID string, ss SyncSender) {
-	r.sl.Lock()
-	defer r.sl.Unlock()
-	r.sessions[connID] = ss
+// Notifier sends fire-and-forget notifications and batches of calls
+type Notifier interface {
+	Notify(ctx context.Context, method string, params []json.RawMessage) error
+	SendBatch(ctx context.Context, calls []Call) ([]jsonrpc.Response, error)
 }
 
-func (r *Registry) Get(connID string) (ss SyncSender, ok bool) {
-	r.sl.RLock()
-	defer r.sl.RUnlock()
-	ss, ok = r.sessions[connID]
-	if !ok || ss == nil {
-		return nil, false
-	}
-
-	return ss, true
+// Call is a single call of the batch. Notification calls get no response.
+type Call struct {
+	Method       string
+	Params       []json.RawMessage
+	Notification bool
+}
 
+// frame is a single websocket message, batch frames are encoded as arrays
+type frame struct {
+	msgs  []message
+	batch bool
 }
 
 // Session represents websocket connection during it's livetime
 type Session struct {
 	ID        string
+	CreatedAt time.Time
 	c         *websocket.Conn
 	reg       connectivity.FunctionCallHandler
 	ctx       context.Context
 	ctxCancel context.CancelFunc
 	l         *zap.Logger
 
+	closeOnce  sync.Once
+	closeErr   error
+	closeHooks []func(reason error)
+	closeLock  sync.Mutex
+
+	// codec negotiated during websocket handshake
+	codec Codec
+
 	// Buffered channel of outbound messages.
-	send     chan jsonrpc.Request
+	send     chan frame
 	response chan jsonrpc.Response
 
 	routing     map[uint64]*Waiting
@@ -82,50 +89,260 @@ func NewWaiting() *Waiting {
 func NewSession(ctx context.Context, c *websocket.Conn, l *zap.Logger, callH connectivity.FunctionCallHandler) *Session {
 	nCtx, cancel := context.WithCancel(ctx)
 
+	codec, err := CodecFor(c.Subprotocol())
+	if err != nil {
+		l.Warn("unknown subprotocol, falling back to json", zap.String("subprotocol", c.Subprotocol()))
+		codec = jsonCodec{}
+	}
+	// has effect only if compression was negotiated
+	c.EnableWriteCompression(true)
+
 	firstCall := uint64(0)
 	return &Session{
 		ID:        uuid.NewString(),
+		CreatedAt: time.Now(),
 		reg:       callH,
 		c:         c,
 		ctx:       nCtx,
 		ctxCancel: cancel,
 		l:         l,
-		send:      make(chan jsonrpc.Request, 10),
+		codec:     codec,
+		send:      make(chan frame, 10),
 		response:  make(chan jsonrpc.Response, 10),
 		newID:     &firstCall,
 		routing:   make(map[uint64]*Waiting),
 	}
 }
 
+// OnClose adds hook called once the session is closed. Hook added to already closed session is called immediately.
+func (s *Session) OnClose(f func(reason error)) {
+	s.closeLock.Lock()
+	select {
+	case <-s.ctx.Done():
+		reason := s.closeErr
+		s.closeLock.Unlock()
+		f(reason)
+		return
+	default:
+	}
+	s.closeHooks = append(s.closeHooks, f)
+	s.closeLock.Unlock()
+}
+
+// Close closes the session with given reason, cancelling its context and running close hooks.
+// It's called on read error (including ping timeout), write error and when the session context is done.
+func (s *Session) Close(reason error) {
+	s.closeOnce.Do(func() {
+		s.closeLock.Lock()
+		if reason == nil {
+			reason = ErrConnectionClosed
+		}
+		s.closeErr = reason
+		s.ctxCancel()
+		hooks := s.closeHooks
+		s.closeHooks = nil
+		s.closeLock.Unlock()
+
+		s.l.Debug("session closed", zap.String("id", s.ID), zap.Error(reason))
+		for _, f := range hooks {
+			f(reason)
+		}
+	})
+}
+
+// Done is closed when the session is closed
+func (s *Session) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Err returns the reason the session was closed with, nil if it's still open
+func (s *Session) Err() error {
+	s.closeLock.Lock()
+	defer s.closeLock.Unlock()
+	return s.closeErr
+}
+
+// Encoding returns the name of the encoding negotiated for the session
+func (s *Session) Encoding() string {
+	return s.codec.Name()
+}
+
 func (s *Session) Send(req jsonrpc.Request) {
-	s.send <- req
+	m := message{JSONRPC: req.JSONRPC, Method: req.Method, Params: make([][]byte, len(req.Params))}
+	if req.ID != nil {
+		m.ID = *req.ID
+	} else {
+		m.Notification = true
+	}
+	for i, p := range req.Params {
+		var err error
+		if m.Params[i], err = s.codec.FromJSON(p); err != nil {
+			s.l.Error("error converting param", zap.String("method", req.Method), zap.Error(err))
+			return
+		}
+	}
+	s.send <- frame{msgs: []message{m}}
 }
 
-func (s *Session) SendSync(method string, params []json.RawMessage) (resp jsonrpc.Response, e error) {
+// SendSync sends request and waits for the response until ctx is done or the session is closed.
+// Requests with ctx without deadline are given DefaultSendTimeout.
+// JSON-RPC error of the response is returned as *jsonrpc.Error.
+func (s *Session) SendSync(ctx context.Context, method string, params []json.RawMessage) (resp jsonrpc.Response, e error) {
+	raw, err := s.fromJSON(params)
+	if err != nil {
+		return resp, err
+	}
+	return s.sendSync(ctx, method, raw)
+}
+
+// SendSyncValues works like SendSync, but the params are encoded directly with the codec of the session,
+// so the binary encodings don't have to go through JSON.
+func (s *Session) SendSyncValues(ctx context.Context, method string, params ...interface{}) (resp jsonrpc.Response, e error) {
+	raw := make([][]byte, len(params))
+	for i, p := range params {
+		var err error
+		if raw[i], err = s.codec.Marshal(p); err != nil {
+			return resp, err
+		}
+	}
+	return s.sendSync(ctx, method, raw)
+}
+
+func (s *Session) sendSync(ctx context.Context, method string, params [][]byte) (resp jsonrpc.Response, e error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultSendTimeout)
+		defer cancel()
+	}
+
+	id, w := s.wait()
+	// response that comes after that is dropped
+	defer s.unwait(id)
+
+	if err := s.push(ctx, method, frame{msgs: []message{{ID: id, JSONRPC: "2.0", Method: method, Params: params}}}); err != nil {
+		return resp, err
+	}
+
+	select {
+	case <-s.ctx.Done():
+		return resp, ErrConnectionClosed
+	case <-ctx.Done():
+		return resp, fmt.Errorf("%w: %s", ctx.Err(), method)
+	case resp = <-w.returnCh:
+	}
+
+	if resp.Error != nil {
+		return resp, resp.Error
+	}
+	return resp, nil
+}
+
+// Notify sends notification, the request without ID that gets no response
+func (s *Session) Notify(ctx context.Context, method string, params []json.RawMessage) error {
+	raw, err := s.fromJSON(params)
+	if err != nil {
+		return err
+	}
+	return s.push(ctx, method, frame{msgs: []message{{Notification: true, JSONRPC: "2.0", Method: method, Params: raw}}})
+}
+
+// SendBatch sends calls as a single JSON-RPC batch and waits for the responses of all the calls
+// that are not notifications. Responses are returned in the order of the calls, notifications
+// leave empty responses in place. Errors of particular calls are set in their responses.
+func (s *Session) SendBatch(ctx context.Context, calls []Call) ([]jsonrpc.Response, error) {
+	if len(calls) == 0 {
+		return nil, nil
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultSendTimeout)
+		defer cancel()
+	}
+
+	f := frame{msgs: make([]message, len(calls)), batch: true}
+	waiting := make([]*Waiting, len(calls))
+	for i, c := range calls {
+		raw, err := s.fromJSON(c.Params)
+		if err != nil {
+			return nil, err
+		}
+		f.msgs[i] = message{JSONRPC: "2.0", Method: c.Method, Params: raw}
+		if c.Notification {
+			f.msgs[i].Notification = true
+			continue
+		}
+
+		id, w := s.wait()
+		defer s.unwait(id)
+		f.msgs[i].ID = id
+		waiting[i] = w
+	}
+
+	if err := s.push(ctx, "batch", f); err != nil {
+		return nil, err
+	}
+
+	responses := make([]jsonrpc.Response, len(calls))
+	for i, w := range waiting {
+		if w == nil {
+			continue
+		}
+		select {
+		case <-s.ctx.Done():
+			return responses, ErrConnectionClosed
+		case <-ctx.Done():
+			return responses, fmt.Errorf("%w: %s", ctx.Err(), calls[i].Method)
+		case responses[i] = <-w.returnCh:
+		}
+	}
+	return responses, nil
+}
+
+// wait registers the routing of the response to the new request id
+func (s *Session) wait() (uint64, *Waiting) {
 	w := NewWaiting()
-	defer close(w.returnCh)
 	id := atomic.AddUint64(s.newID, 1)
 
 	s.routingLock.Lock()
 	s.routing[id] = w
 	s.routingLock.Unlock()
+	return id, w
+}
 
-	select {
-	case <-s.ctx.Done():
-		return resp, nil
-	case s.send <- jsonrpc.Request{ID: id, JSONRPC: "2.0", Method: method, Params: params}:
-	}
+func (s *Session) unwait(id uint64) {
+	s.routingLock.Lock()
+	delete(s.routing, id)
+	s.routingLock.Unlock()
+}
 
+// push queues the frame to be written
+func (s *Session) push(ctx context.Context, method string, f frame) error {
 	select {
 	case <-s.ctx.Done():
-	case resp = <-w.returnCh:
+		return ErrConnectionClosed
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %s", ctx.Err(), method)
+	case s.send <- f:
+		return nil
 	}
+}
 
-	return resp, nil
+func (s *Session) fromJSON(params []json.RawMessage) ([][]byte, error) {
+	raw := make([][]byte, len(params))
+	for i, p := range params {
+		var err error
+		if raw[i], err = s.codec.FromJSON(p); err != nil {
+			return nil, err
+		}
+	}
+	return raw, nil
 }
 
 func (s *Session) Recv() {
+	var closeReason error
 	defer func() {
+		s.Close(closeReason)
 		if s.c != nil {
 			s.c.Close()
 		}
@@ -134,6 +351,7 @@ func (s *Session) Recv() {
 	err := s.c.SetReadDeadline(time.Now().Add(pongWait))
 	if err != nil {
 		s.l.Error("error setting read deadline", zap.Error(err))
+		closeReason = err
 		return
 	}
 
@@ -141,73 +359,151 @@ func (s *Session) Recv() {
 		return s.c.SetReadDeadline(time.Now().Add(pongWait))
 	})
 
-	readr := bytes.NewReader(nil)
-	dec := json.NewDecoder(readr)
 	for {
-		_, message, err := s.c.ReadMessage()
+		_, data, err := s.c.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				s.l.Error(" websocket unexpected close error", zap.Error(err))
 			}
+			closeReason = err
 			break
 		}
 
-		readr.Reset(message)
-		req := &jsonrpc.Hybrid{}
-		if err = dec.Decode(req); err != nil {
-			s.l.Error("error unmarshaling jsonrpc response", zap.Error(err))
+		msgs, batch, err := s.codec.Decode(data)
+		if err != nil {
+			s.l.Error("error unmarshaling jsonrpc message", zap.Error(err))
+			s.response <- jsonrpc.Response{JSONRPC: "2.0", Error: jsonrpc.ErrParse}
 			continue
 		}
 
-		if req.JSONRPC != "2.0" {
-			s.response <- jsonrpc.Response{JSONRPC: "2.0", Error: &jsonrpc.Error{Code: -32700, Message: "Parse error"}}
+		if batch && len(msgs) == 0 {
+			s.response <- jsonrpc.Response{JSONRPC: "2.0", Error: jsonrpc.ErrInvalidRequest}
+			continue
 		}
 
-		if req.Result != nil || req.Error != nil {
-			s.routingLock.RLock()
-			waitO, ok := s.routing[req.ID]
-			s.routingLock.RUnlock()
+		calls := msgs[:0]
+		for _, m := range msgs {
+			if m.JSONRPC != "2.0" {
+				s.response <- jsonrpc.Response{ID: m.ID, JSONRPC: "2.0", Error: jsonrpc.ErrInvalidRequest}
+				continue
+			}
 
-			s.l.Debug("msg", zap.Any("message", req))
-			if !ok {
-				s.l.Error("unexpected message", zap.Any("message", req))
+			if m.Method == "" {
+				s.route(m)
 				continue
 			}
-			waitO.returnCh <- jsonrpc.Response{ID: req.ID, JSONRPC: "2.0", Result: req.Result, Error: req.Error}
+			calls = append(calls, m)
+		}
 
-			s.routingLock.RLock()
-			delete(s.routing, req.ID)
-			s.routingLock.RUnlock()
-			continue
+		switch {
+		case len(calls) == 0:
+		case batch:
+			go s.handleBatch(calls)
+		default:
+			go s.handle(calls[0], s.response)
 		}
+	}
+}
 
-		h, ok := s.reg.Get(req.Method)
-		if !ok {
-			s.l.Warn("method not found", zap.String("method", req.Method))
-			s.response <- jsonrpc.Response{ID: req.ID, JSONRPC: "2.0", Error: &jsonrpc.Error{Code: -32601, Message: "Method not found"}}
-			continue
+// route passes the response to the request waiting for it
+func (s *Session) route(m message) {
+	s.routingLock.Lock()
+	waitO, ok := s.routing[m.ID]
+	delete(s.routing, m.ID)
+	s.routingLock.Unlock()
+
+	s.l.Debug("msg", zap.Any("message", m))
+	if !ok {
+		s.l.Error("unexpected message", zap.Any("message", m))
+		return
+	}
+	result, err := s.codec.ToJSON(m.Result)
+	if err != nil {
+		s.l.Error("error converting result", zap.Error(err))
+	}
+	waitO.returnCh <- jsonrpc.Response{ID: m.ID, JSONRPC: "2.0", Result: result, Error: m.Error}
+}
+
+// handle calls the handler of the request, response is sent to respCh unless it's a notification
+func (s *Session) handle(m message, respCh chan jsonrpc.Response) {
+	var resp connectivity.Response = &SessionResponse{ID: m.ID, SessionContext: s.ctx, RespCh: respCh}
+	if m.Notification {
+		resp = discardResponse{}
+	}
+
+	h, ok := s.reg.Get(m.Method)
+	if !ok {
+		s.l.Warn("method not found", zap.String("method", m.Method))
+		resp.Send(nil, jsonrpc.ErrMethodNotFound)
+		return
+	}
+
+	h(s.ctx, &SessionRequest{params: m.Params, codec: s.codec, connID: s.ID}, resp)
+}
+
+// handleBatch calls handlers of the batch one after another, keeping the order of the calls,
+// and sends all the responses back as a single batch
+func (s *Session) handleBatch(calls []message) {
+	respCh := make(chan jsonrpc.Response, len(calls))
+	expected := 0
+	for _, m := range calls {
+		if !m.Notification {
+			expected++
 		}
+		s.handle(m, respCh)
+	}
+
+	if expected == 0 {
+		return
+	}
 
-		go h(s.ctx, &SessionRequest{args: req.Params, connID: s.ID},
-			&SessionResponse{
-				ID:             req.ID,
-				SessionContext: s.ctx,
-				RespCh:         s.response,
-			})
+	f := frame{msgs: make([]message, 0, expected), batch: true}
+	for len(f.msgs) < expected {
+		select {
+		case <-s.ctx.Done():
+			return
+		case resp := <-respCh:
+			m, err := s.responseMessage(resp)
+			if err != nil {
+				s.l.Info("error in encode response", zap.Error(err), zap.Any("message", resp))
+				m = message{ID: resp.ID, JSONRPC: "2.0", Error: jsonrpc.ErrInternal}
+			}
+			f.msgs = append(f.msgs, m)
+		}
+	}
+
+	select {
+	case <-s.ctx.Done():
+	case s.send <- f:
 	}
 }
 
+func (s *Session) responseMessage(resp jsonrpc.Response) (message, error) {
+	result, err := s.codec.FromJSON(resp.Result)
+	if err != nil {
+		return message{}, err
+	}
+	return message{ID: resp.ID, JSONRPC: resp.JSONRPC, Error: resp.Error, Result: result}, nil
+}
+
 func (s *Session) Req() {
+	var closeReason error
+	defer func() {
+		s.Close(closeReason)
+		// unblocks Recv
+		if s.c != nil {
+			s.c.Close()
+		}
+	}()
 
 	tckr := time.NewTicker(pingTime)
 	defer tckr.Stop()
 
-	buff := new(bytes.Buffer)
-	enc := json.NewEncoder(buff)
 WSLOOP:
 	for {
 		select {
 		case <-s.ctx.Done():
+			closeReason = s.ctx.Err()
 			s.l.Info("closing connection on context done")
 			if err := s.c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")); err != nil {
 				s.l.Error("error closing websocket ", zap.Error(err))
@@ -218,7 +514,7 @@ WSLOOP:
 
 			break WSLOOP
 
-		case message, ok := <-s.send:
+		case f, ok := <-s.send:
 			if !ok {
 				s.l.Info("send is closed")
 				if s.c != nil {
@@ -227,18 +523,19 @@ WSLOOP:
 				return
 			}
 
-			buff.Reset()
-			if err := enc.Encode(message); err != nil {
-				s.l.Info("error in encode send", zap.Error(err), zap.Any("message", message))
+			data, err := s.codec.Encode(f.msgs, f.batch)
+			if err != nil {
+				s.l.Info("error in encode send", zap.Error(err), zap.Int("messages", len(f.msgs)))
 				continue WSLOOP
 			}
 
-			if err := s.c.WriteMessage(websocket.TextMessage, buff.Bytes()); err != nil {
+			if err := s.c.WriteMessage(s.codec.MessageType(), data); err != nil {
 				s.l.Error("error sending data websocket ", zap.Error(err))
+				closeReason = err
 				break WSLOOP
 			}
 
-		case message, ok := <-s.response:
+		case resp, ok := <-s.response:
 			if !ok {
 				s.l.Info("send is closed")
 				if s.c != nil {
@@ -247,19 +544,27 @@ WSLOOP:
 				return
 			}
 
-			buff.Reset()
-			if err := enc.Encode(message); err != nil {
-				s.l.Info("error in encode response", zap.Error(err), zap.Any("message", message))
+			m, err := s.responseMessage(resp)
+			if err != nil {
+				s.l.Info("error in encode response", zap.Error(err), zap.Any("message", resp))
+				continue WSLOOP
+			}
+
+			data, err := s.codec.Encode([]message{m}, false)
+			if err != nil {
+				s.l.Info("error in encode response", zap.Error(err), zap.Any("message", resp))
 				continue WSLOOP
 			}
 
-			if err := s.c.WriteMessage(websocket.TextMessage, buff.Bytes()); err != nil {
+			if err := s.c.WriteMessage(s.codec.MessageType(), data); err != nil {
 				s.l.Error("error sending data websocket ", zap.Error(err))
+				closeReason = err
 				break WSLOOP
 			}
 
 		case <-tckr.C:
 			if err := s.c.WriteMessage(websocket.PingMessage, nil); err != nil {
+				closeReason = err
 				return
 			}
 
@@ -282,22 +587,57 @@ func (s *SessionResponse) Send(result json.RawMessage, er error) error {
 	}
 
 	if er != nil {
-		resp.Error = &jsonrpc.Error{Message: er.Error()}
+		rpcErr := &jsonrpc.Error{}
+		if errors.As(er, &rpcErr) {
+			resp.Error = rpcErr
+		} else {
+			resp.Error = &jsonrpc.Error{Code: jsonrpc.ErrInternal.Code, Message: er.Error()}
+		}
 	}
 
 	s.RespCh <- resp
 	return nil
 }
 
+// discardResponse is the response of notification, nothing is sent back
+type discardResponse struct{}
+
+func (discardResponse) Send(result json.RawMessage, er error) error {
+	return nil
+}
+
 type SessionRequest struct {
 	connID string
-	args   []json.RawMessage
+	codec  Codec
+	params [][]byte
+
+	args     []json.RawMessage
+	argsOnce sync.Once
 }
 
+// Arguments returns params of the request as JSON, converting them from the session encoding if needed
 func (sR *SessionRequest) Arguments() []json.RawMessage {
+	sR.argsOnce.Do(func() {
+		sR.args = make([]json.RawMessage, 0, len(sR.params))
+		for _, p := range sR.params {
+			a, err := sR.codec.ToJSON(p)
+			if err != nil {
+				a = nil
+			}
+			sR.args = append(sR.args, a)
+		}
+	})
 	return sR.args
 }
 
+// Decode decodes i-th param of the request into v
+func (sR *SessionRequest) Decode(i int, v interface{}) error {
+	if i >= len(sR.params) {
+		return ErrMissingParam
+	}
+	return sR.codec.Unmarshal(sR.params[i], v)
+}
+
 func (sR *SessionRequest) ConnID() string {
 	return sR.connID
 }
@@ -12,8 +12,10 @@ import (
 )
 
 var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
+	ReadBufferSize:    1024,
+	WriteBufferSize:   1024,
+	Subprotocols:      Subprotocols(),
+	EnableCompression: true,
 }
 
 var ErrConnectionClosed = errors.New("connection closed")
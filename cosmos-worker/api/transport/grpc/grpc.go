@@ -0,0 +1,281 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	grpcConn "github.com/figment-networks/graph-demo/connectivity/grpc"
+	"github.com/figment-networks/graph-demo/connectivity/grpc/workerpb"
+	"github.com/figment-networks/graph-demo/connectivity/handshake"
+	wsapi "github.com/figment-networks/graph-demo/connectivity/ws"
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type CosmosClient interface {
+	GetAll(ctx context.Context, height uint64) error
+	GetLatest(ctx context.Context) (uint64, error)
+}
+
+// ProcessHandler connects worker to the manager over gRPC stream, it's the gRPC counterpart
+// of the websocket ProcessHandler
+type ProcessHandler struct {
+	// clients of every served chain by chain id
+	clients map[string]CosmosClient
+	log     *zap.Logger
+	cfg     wsapi.ReconnectConfig
+
+	// dialOpts are added to the options of the dial
+	dialOpts []grpc.DialOption
+
+	conn *connection
+	cl   sync.RWMutex
+}
+
+func NewProcessHandler(log *zap.Logger, clients map[string]CosmosClient) *ProcessHandler {
+	return &ProcessHandler{
+		log:     log,
+		clients: clients,
+		cfg:     wsapi.DefaultReconnectConfig,
+	}
+}
+
+// SetDialOptions sets the options added to the dial, like custom dialer. It has to be called before Connect
+func (ph *ProcessHandler) SetDialOptions(opts ...grpc.DialOption) {
+	ph.dialOpts = opts
+}
+
+// connection is a single stream to the manager
+type connection struct {
+	stream  workerpb.Manager_ConnectClient
+	sl      sync.Mutex
+	pending *grpcConn.Pending
+}
+
+func (c *connection) send(msg *workerpb.WorkerMessage) error {
+	c.sl.Lock()
+	defer c.sl.Unlock()
+	return c.stream.Send(msg)
+}
+
+func (c *connection) call(ctx context.Context, method string, msg *workerpb.WorkerMessage) error {
+	_, err := c.pending.Call(ctx, method, func(id uint64) error {
+		msg.Id = id
+		return c.send(msg)
+	})
+	return err
+}
+
+// Connect connects to the manager registering worker under given name.
+// Connection is restored after it's lost, registering the worker again.
+// Only Header, OnState and Version of the options are used, the version is sent in hello headers.
+func (ph *ProcessHandler) Connect(ctx context.Context, address, name string, opts wsapi.DialOptions) error {
+	cc, err := grpc.DialContext(ctx, address, append([]grpc.DialOption{grpc.WithInsecure()}, ph.dialOpts...)...)
+	if err != nil {
+		return err
+	}
+
+	setState := func(state wsapi.ConnState, err error) {
+		if opts.OnState != nil {
+			opts.OnState(state, err)
+		}
+	}
+
+	conn, err := ph.connect(ctx, cc, name, opts, setState)
+	if err != nil {
+		setState(wsapi.StateDisconnected, err)
+		cc.Close()
+		return err
+	}
+
+	go ph.run(ctx, cc, conn, name, opts, setState)
+	return nil
+}
+
+func (ph *ProcessHandler) run(ctx context.Context, cc *grpc.ClientConn, conn *connection, name string, opts wsapi.DialOptions, setState func(wsapi.ConnState, error)) {
+	defer cc.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			setState(wsapi.StateClosed, ctx.Err())
+			return
+		case <-conn.pending.Done():
+		}
+		setState(wsapi.StateDisconnected, conn.pending.Err())
+
+		var attempt int
+		for {
+			attempt++
+			select {
+			case <-ctx.Done():
+				setState(wsapi.StateClosed, ctx.Err())
+				return
+			case <-time.After(ph.cfg.Backoff(attempt)):
+			}
+
+			var err error
+			if conn, err = ph.connect(ctx, cc, name, opts, setState); err == nil {
+				break
+			}
+			ph.log.Warn("error reconnecting", zap.Int("attempt", attempt), zap.Error(err))
+			setState(wsapi.StateDisconnected, err)
+		}
+	}
+}
+
+// connect opens the stream and registers worker for all the chains it serves
+func (ph *ProcessHandler) connect(ctx context.Context, cc *grpc.ClientConn, name string, opts wsapi.DialOptions, setState func(wsapi.ConnState, error)) (*connection, error) {
+	setState(wsapi.StateConnecting, nil)
+
+	header := http.Header{}
+	if opts.Header != nil {
+		for k, v := range opts.Header() {
+			header[k] = v
+		}
+	}
+	handshake.Hello{
+		Component: "cosmos-worker",
+		Version:   opts.Version,
+		Protocol:  handshake.ProtocolVersion,
+		Methods:   []string{"get_all", "get_latest"},
+	}.SetHeader(header)
+	sCtx := metadata.NewOutgoingContext(ctx, grpcConn.HeaderToMetadata(header))
+
+	stream, err := workerpb.NewManagerClient(cc).Connect(sCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &connection{stream: stream, pending: grpcConn.NewPending()}
+	go ph.recv(ctx, conn)
+
+	reg := structs.Register{Name: name}
+	for chainID := range ph.clients {
+		reg.Chains = append(reg.Chains, structs.RegisterChain{
+			ChainID:      chainID,
+			Capabilities: []string{structs.CapabilityGetAll, structs.CapabilityGetLatest},
+		})
+	}
+
+	if err := conn.call(ctx, "register", &workerpb.WorkerMessage{Body: &workerpb.WorkerMessage_Register{Register: workerpb.RegisterToPB(reg)}}); err != nil {
+		stream.CloseSend()
+		return nil, err
+	}
+
+	ph.cl.Lock()
+	ph.conn = conn
+	ph.cl.Unlock()
+
+	setState(wsapi.StateConnected, nil)
+	return conn, nil
+}
+
+// recv reads the stream until it's closed
+func (ph *ProcessHandler) recv(ctx context.Context, conn *connection) {
+	for {
+		msg, err := conn.stream.Recv()
+		if err != nil {
+			conn.pending.Close(err)
+			return
+		}
+
+		switch b := msg.GetBody().(type) {
+		case *workerpb.ManagerMessage_Result:
+			if !conn.pending.Resolve(msg.GetId(), b.Result) {
+				ph.log.Error("unexpected result", zap.Uint64("request_id", msg.GetId()))
+			}
+		// handlers run outside of the loop, getting the height stores its data with calls whose results it reads
+		case *workerpb.ManagerMessage_GetAll:
+			id, req := msg.GetId(), b.GetAll
+			go func() { ph.reply(conn, id, ph.getAll(ctx, req)) }()
+		case *workerpb.ManagerMessage_GetLatest:
+			id, req := msg.GetId(), b.GetLatest
+			go func() { ph.reply(conn, id, ph.getLatest(ctx, req)) }()
+		default:
+			ph.reply(conn, msg.GetId(), workerpb.ErrorResult(errors.New("unexpected message")))
+		}
+	}
+}
+
+func (ph *ProcessHandler) reply(conn *connection, id uint64, res *workerpb.Result) {
+	if err := conn.send(&workerpb.WorkerMessage{Id: id, Body: &workerpb.WorkerMessage_Result{Result: res}}); err != nil {
+		ph.log.Error("error sending result", zap.Error(err))
+	}
+}
+
+// client returns the client of the chain. Requests without chain id are served by the only client if there is just one.
+func (ph *ProcessHandler) client(chainID string) (CosmosClient, error) {
+	if chainID == "" && len(ph.clients) == 1 {
+		for _, c := range ph.clients {
+			return c, nil
+		}
+	}
+
+	c, ok := ph.clients[chainID]
+	if !ok {
+		return nil, errors.New("chain is not served by this worker: " + chainID)
+	}
+	return c, nil
+}
+
+func (ph *ProcessHandler) getAll(ctx context.Context, req *workerpb.GetAll) *workerpb.Result {
+	svc, err := ph.client(req.GetChainId())
+	if err != nil {
+		return workerpb.ErrorResult(err)
+	}
+
+	if err := svc.GetAll(ctx, req.GetHeight()); err != nil {
+		return workerpb.ErrorResult(err)
+	}
+	return &workerpb.Result{}
+}
+
+func (ph *ProcessHandler) getLatest(ctx context.Context, req *workerpb.GetLatest) *workerpb.Result {
+	svc, err := ph.client(req.GetChainId())
+	if err != nil {
+		return workerpb.ErrorResult(err)
+	}
+
+	height, err := svc.GetLatest(ctx)
+	if err != nil {
+		return workerpb.ErrorResult(err)
+	}
+	return &workerpb.Result{Height: height}
+}
+
+// current returns the live connection
+func (ph *ProcessHandler) current() (*connection, error) {
+	ph.cl.RLock()
+	defer ph.cl.RUnlock()
+
+	if ph.conn == nil || ph.conn.pending.Err() != nil {
+		return nil, grpcConn.ErrConnectionClosed
+	}
+	return ph.conn, nil
+}
+
+func (ph *ProcessHandler) StoreTransactions(ctx context.Context, txs []structs.Transaction) error {
+	conn, err := ph.current()
+	if err != nil {
+		return err
+	}
+	return conn.call(ctx, "store_transactions", &workerpb.WorkerMessage{
+		Body: &workerpb.WorkerMessage_StoreTransactions{StoreTransactions: &workerpb.StoreTransactions{Transactions: workerpb.TransactionsToPB(txs)}},
+	})
+}
+
+func (ph *ProcessHandler) StoreBlock(ctx context.Context, block structs.Block) error {
+	conn, err := ph.current()
+	if err != nil {
+		return err
+	}
+	return conn.call(ctx, "store_block", &workerpb.WorkerMessage{
+		Body: &workerpb.WorkerMessage_StoreBlock{StoreBlock: workerpb.BlockToPB(block)},
+	})
+}
@@ -3,6 +3,7 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -11,60 +12,100 @@ import (
 	"github.com/figment-networks/graph-demo/manager/structs"
 )
 
+// CosmosClient fetches the data of the chain without storing it, manager pulls it and stores it itself
 type CosmosClient interface {
-	GetAll(ctx context.Context, height uint64) error
-	GetLatest(ctx context.Context) (structs.Block, error)
+	FetchAll(ctx context.Context, height uint64) (structs.BlockAndTx, error)
+	GetLatest(ctx context.Context) (uint64, error)
 }
 
 type Handler struct {
-	client CosmosClient
+	// clients of every served chain by chain id
+	clients map[string]CosmosClient
 }
 
-func NewHandler(c CosmosClient) *Handler {
+func NewHandler(clients map[string]CosmosClient) *Handler {
 	return &Handler{
-		client: c,
+		clients: clients,
 	}
 }
 
 func (h *Handler) AttachToMux(mux *http.ServeMux) {
 	mux.HandleFunc("/getAll/", h.HandleGetAll)
-	mux.HandleFunc("/getLast/", h.HandleGetLast)
+	mux.HandleFunc("/getLatest", h.HandleGetLatest)
 }
 
+// HandleGetAll returns block and transactions of the height - /getAll/{height}?chain_id={chainID}
 func (h *Handler) HandleGetAll(w http.ResponseWriter, r *http.Request) {
-	heightStr := strings.Replace(r.URL.Path, "/getAll/", "", -1)
-	heightInt, err := strconv.Atoi(heightStr)
+	heightStr := strings.TrimPrefix(r.URL.Path, "/getAll/")
+	height, err := strconv.ParseUint(heightStr, 10, 64)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(fmt.Sprintf("Error while parsing block height: %s", err.Error())))
+		writeError(w, http.StatusBadRequest, fmt.Errorf("Error while parsing block height: %w", err))
 		return
 	}
 
-	if err := h.client.GetAll(r.Context(), uint64(heightInt)); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(fmt.Sprintf("Error while getting a block: %s", err.Error())))
+	c, err := h.client(r.URL.Query().Get("chain_id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("ACK"))
+	bTx, err := c.FetchAll(r.Context(), height)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("Error while getting a block: %w", err))
+		return
+	}
+
+	writeJSON(w, bTx)
 }
 
-func (h *Handler) HandleGetLast(w http.ResponseWriter, r *http.Request) {
-	block, err := h.client.GetLatest(r.Context())
+// HandleGetLatest returns the latest height of the chain - /getLatest?chain_id={chainID}
+func (h *Handler) HandleGetLatest(w http.ResponseWriter, r *http.Request) {
+	c, err := h.client(r.URL.Query().Get("chain_id"))
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(fmt.Sprintf("Error while getting a block: %s", err.Error())))
+		writeError(w, http.StatusNotFound, err)
 		return
 	}
 
-	resp, err := json.Marshal(block)
+	height, err := c.GetLatest(r.Context())
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(fmt.Sprintf("Error while marshalling response: %s", err.Error())))
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("Error while getting latest height: %w", err))
 		return
 	}
 
+	writeJSON(w, height)
+}
+
+// client returns the client of the chain. Requests without chain id are served by the only client if there is just one.
+func (h *Handler) client(chainID string) (CosmosClient, error) {
+	if chainID == "" && len(h.clients) == 1 {
+		for _, c := range h.clients {
+			return c, nil
+		}
+	}
+
+	c, ok := h.clients[chainID]
+	if !ok {
+		return nil, errors.New("chain is not served by this worker: " + chainID)
+	}
+	return c, nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	resp, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("Error while marshalling response: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	w.Write(resp)
 }
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	resp, _ := json.Marshal(structs.ErrorResponse{Code: structs.ErrorCode(err), Message: err.Error()})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(resp)
+}
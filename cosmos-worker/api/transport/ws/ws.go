@@ -1,49 +1,41 @@
 package ws
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"sort"
 	"sync"
 
 	"github.com/figment-networks/graph-demo/connectivity"
+	"github.com/figment-networks/graph-demo/connectivity/handshake"
+	"github.com/figment-networks/graph-demo/connectivity/jsonrpc"
 	wsapi "github.com/figment-networks/graph-demo/connectivity/ws"
 	"github.com/figment-networks/graph-demo/manager/structs"
 
-	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 )
 
-type JSONGraphQLResponse struct {
-	Data   json.RawMessage `json:"data"`
-	Errors []ErrorMessage  `json:"errors,omitempty"`
-}
-
-type ErrorMessage struct {
-	Message string `json:"message,omitempty"`
-}
-
 type CosmosClient interface {
 	GetAll(ctx context.Context, height uint64) error
 	GetLatest(ctx context.Context) (uint64, error)
 }
 
 type ProcessHandler struct {
-	service CosmosClient
+	// clients of every served chain by chain id
+	clients map[string]CosmosClient
 	log     *zap.Logger
 
-	c    *websocket.Conn
-	sess *wsapi.Session
+	cli *wsapi.Client
 
 	registry     map[string]connectivity.Handler
 	registrySync sync.RWMutex
 }
 
-func NewProcessHandler(log *zap.Logger, svc CosmosClient) *ProcessHandler {
+func NewProcessHandler(log *zap.Logger, clients map[string]CosmosClient) *ProcessHandler {
 	ph := &ProcessHandler{
 		log:      log,
-		service:  svc,
+		clients:  clients,
 		registry: make(map[string]connectivity.Handler),
 	}
 
@@ -53,23 +45,99 @@ func NewProcessHandler(log *zap.Logger, svc CosmosClient) *ProcessHandler {
 	return ph
 }
 
-func (ng *ProcessHandler) Connect(ctx context.Context, address string) (err error) {
-	if ng.c, _, err = websocket.DefaultDialer.DialContext(ctx, address, nil); err != nil {
-		return err
+// Connect connects to the manager registering worker under given name.
+// Connection is restored after it's lost, registering the worker again.
+func (ng *ProcessHandler) Connect(ctx context.Context, address, name string, opts wsapi.DialOptions) error {
+	ng.cli = wsapi.NewClient(ng.log, address, ng, wsapi.DefaultReconnectConfig)
+	ng.cli.Apply(opts)
+	ng.cli.OnConnect(func(ctx context.Context, ss wsapi.SyncSender) error {
+		return ng.handshake(ctx, ss, opts.Version)
+	})
+	ng.cli.OnConnect(func(ctx context.Context, ss wsapi.SyncSender) error {
+		return ng.register(ctx, ss, name)
+	})
+
+	return ng.cli.Connect(ctx)
+}
+
+// ManagerMethods are the methods worker calls in manager
+var ManagerMethods = []string{"register", "store_block", "store_transactions"}
+
+// Hello describes the worker in handshake
+func (ng *ProcessHandler) Hello(version string) handshake.Hello {
+	ng.registrySync.RLock()
+	methods := make([]string, 0, len(ng.registry))
+	for m := range ng.registry {
+		methods = append(methods, m)
 	}
+	ng.registrySync.RUnlock()
+	sort.Strings(methods)
+
+	return handshake.Hello{
+		Component: "cosmos-worker",
+		Version:   version,
+		Protocol:  handshake.ProtocolVersion,
+		Methods:   methods,
+	}
+}
 
-	ng.sess = wsapi.NewSession(ctx, ng.c, ng.log, ng)
-	go ng.sess.Recv()
-	go ng.sess.Req()
+// handshake checks if manager speaks compatible protocol
+func (ng *ProcessHandler) handshake(ctx context.Context, ss wsapi.SyncSender, version string) error {
+	res, legacy, err := handshake.Do(ctx, ss, ng.Hello(version), ManagerMethods)
+	if err != nil {
+		return err
+	}
 
+	if legacy {
+		ng.log.Warn("manager doesn't support handshake, protocol compatibility is not checked")
+		return nil
+	}
+	ng.log.Info("handshake with manager", zap.String("version", res.Remote.Version), zap.String("protocol", res.Remote.Protocol))
 	return nil
 }
 
-func (ng *ProcessHandler) Register(ctx context.Context, chainID string) (err error) {
-	_, err = ng.sess.SendSync("register", []json.RawMessage{[]byte(`"` + chainID + `"`)})
+// register registers worker in manager for all the chains it serves
+func (ng *ProcessHandler) register(ctx context.Context, ss wsapi.SyncSender, name string) (err error) {
+	reg := structs.Register{Name: name}
+	for chainID := range ng.clients {
+		reg.Chains = append(reg.Chains, structs.RegisterChain{
+			ChainID:      chainID,
+			Capabilities: []string{structs.CapabilityGetAll, structs.CapabilityGetLatest},
+		})
+	}
+
+	regM, err := json.Marshal(reg)
+	if err != nil {
+		return err
+	}
+
+	_, err = ss.SendSync(ctx, "register", []json.RawMessage{regM})
 	return err
 }
 
+// client returns the client of the chain from given argument.
+// Requests without chain id are served by the only client if there is just one.
+func (ph *ProcessHandler) client(args []json.RawMessage, pos int) (CosmosClient, error) {
+	var chainID string
+	if len(args) > pos {
+		if err := json.Unmarshal(args[pos], &chainID); err != nil {
+			return nil, err
+		}
+	}
+
+	if chainID == "" && len(ph.clients) == 1 {
+		for _, c := range ph.clients {
+			return c, nil
+		}
+	}
+
+	c, ok := ph.clients[chainID]
+	if !ok {
+		return nil, errors.New("chain is not served by this worker: " + chainID)
+	}
+	return c, nil
+}
+
 func (ph *ProcessHandler) Add(name string, handler connectivity.Handler) {
 	ph.registrySync.Lock()
 	defer ph.registrySync.Unlock()
@@ -84,99 +152,61 @@ func (ph *ProcessHandler) Get(name string) (h connectivity.Handler, ok bool) {
 	return h, ok
 }
 
+// GetAll fetches the height and stores its data through the manager.
+// Failures are sent as JSON-RPC errors with the codes of structs errors, so manager can classify them.
 func (ph *ProcessHandler) GetAll(ctx context.Context, req connectivity.Request, resp connectivity.Response) {
-	b := new(bytes.Buffer)
-	enc := json.NewEncoder(b)
-	r := JSONGraphQLResponse{}
-
 	args := req.Arguments()
 	if len(args) == 0 {
-		r.Errors = append(r.Errors, ErrorMessage{
-			Message: "Missing query (getAll)",
-		})
-		enc.Encode(r)
-		resp.Send(json.RawMessage(b.Bytes()), nil)
+		resp.Send(nil, &jsonrpc.Error{Code: jsonrpc.ErrInvalidParams.Code, Message: "missing height"})
 		return
 	}
 
 	var height uint64
 	if err := json.Unmarshal(args[0], &height); err != nil {
-		r.Errors = append(r.Errors, ErrorMessage{
-			Message: "Error unmarshaling query " + err.Error(),
-		})
-		enc.Encode(r)
-		resp.Send(json.RawMessage(b.Bytes()), nil)
+		resp.Send(nil, &jsonrpc.Error{Code: jsonrpc.ErrInvalidParams.Code, Message: "error decoding height: " + err.Error()})
 		return
 	}
 
-	if err := ph.service.GetAll(ctx, height); err != nil {
-		r.Errors = append(r.Errors, ErrorMessage{
-			Message: "Error unmarshaling query " + err.Error(),
-		})
-		enc.Encode(r)
-		resp.Send(json.RawMessage(b.Bytes()), nil)
+	svc, err := ph.client(args, 1)
+	if err != nil {
+		resp.Send(nil, &jsonrpc.Error{Code: structs.ErrorCode(err), Message: "Error getting chain " + err.Error()})
+		return
+	}
+
+	if err := svc.GetAll(ctx, height); err != nil {
+		resp.Send(nil, &jsonrpc.Error{Code: structs.ErrorCode(err), Message: "Error getting height " + err.Error()})
 		return
 	}
 	resp.Send(json.RawMessage([]byte(`"ACK"`)), nil)
 }
 
 func (ph *ProcessHandler) GetLatest(ctx context.Context, req connectivity.Request, resp connectivity.Response) {
-	b := new(bytes.Buffer)
-	enc := json.NewEncoder(b)
-	r := JSONGraphQLResponse{}
+	svc, err := ph.client(req.Arguments(), 0)
+	if err != nil {
+		resp.Send(nil, &jsonrpc.Error{Code: structs.ErrorCode(err), Message: "Error getting chain " + err.Error()})
+		return
+	}
 
-	block, err := ph.service.GetLatest(ctx)
+	block, err := svc.GetLatest(ctx)
 	if err != nil {
-		r.Errors = append(r.Errors, ErrorMessage{
-			Message: "Error getting latest",
-		})
-		enc.Encode(r)
-		resp.Send(json.RawMessage(b.Bytes()), nil)
+		resp.Send(nil, &jsonrpc.Error{Code: structs.ErrorCode(err), Message: "Error getting latest " + err.Error()})
 		return
 	}
 
 	encoded, err := json.Marshal(block)
 	if err != nil {
-		r.Errors = append(r.Errors, ErrorMessage{
-			Message: "Error encoding respons",
-		})
-		enc.Encode(r)
-		resp.Send(json.RawMessage(b.Bytes()), nil)
+		resp.Send(nil, &jsonrpc.Error{Code: structs.ErrorCode(err), Message: "Error encoding response " + err.Error()})
 		return
 	}
 	resp.Send(encoded, nil)
 }
 
 func (ph *ProcessHandler) StoreTransactions(ctx context.Context, txs []structs.Transaction) error {
-	txsM, err := json.Marshal(txs)
-	if err != nil {
-		return err
-	}
-
-	resp, err := ph.sess.SendSync("store_transactions", []json.RawMessage{txsM})
-	if err != nil {
-		return err
-	}
-	if resp.Error != nil && resp.Error.Message != "" {
-		return errors.New(resp.Error.Message)
-	}
-
-	return nil
+	_, err := ph.cli.SendSyncValues(ctx, "store_transactions", txs)
+	return err
 }
 
 func (ph *ProcessHandler) StoreBlock(ctx context.Context, block structs.Block) error {
-	blockM, err := json.Marshal(block)
-	if err != nil {
-		return err
-	}
-
-	resp, err := ph.sess.SendSync("store_block", []json.RawMessage{blockM})
-	if err != nil {
-		return err
-	}
-	if resp.Error != nil && resp.Error.Message != "" {
-		return errors.New(resp.Error.Message)
-	}
-
-	return nil
+	_, err := ph.cli.SendSyncValues(ctx, "store_block", block)
+	return err
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/figment-networks/graph-demo/cosmos-worker/client/mapper"
+	"github.com/figment-networks/graph-demo/manager/structs"
 
 	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
 	"go.uber.org/zap"
@@ -12,23 +13,16 @@ import (
 
 const perPage = 100
 
-// GetAll fetches all data for given height
+// GetAll fetches all data for given height and stores it through the linked persistor
 func (c *Client) GetAll(ctx context.Context, height uint64) (err error) {
 	ctx, cancel := context.WithTimeout(ctx, c.cfg.TimeoutBlockCall)
 	defer cancel()
 
-	c.log.Debug("[COSMOS-WORKER] Getting block", zap.Uint64("height", height))
-
-	b, err := c.tmServiceClient.GetBlockByHeight(ctx, &tmservice.GetBlockByHeightRequest{Height: int64(height)}, grpc.WaitForReady(true))
+	block, err := c.GetBlock(ctx, height)
 	if err != nil {
-		c.log.Debug("[COSMOS-CLIENT] Error getting block by height", zap.Uint64("height", height), zap.Error(err))
 		return err
 	}
 
-	c.log.Debug("[COSMOS-WORKER] Got block", zap.Uint64("height", height))
-
-	block := mapper.BlockMapper(b)
-
 	if c.persistor != nil {
 		if err = c.persistor.StoreBlock(ctx, block); err != nil {
 			c.log.Debug("[COSMOS-CLIENT] Error storing block at height", zap.Uint64("height", height), zap.Error(err))
@@ -52,7 +46,39 @@ func (c *Client) GetAll(ctx context.Context, height uint64) (err error) {
 	return nil
 }
 
-// GetBlock fetches most recent block from chain
+// FetchAll fetches block and transactions of given height without storing them, it's used in pull mode
+// where manager stores the data itself
+func (c *Client) FetchAll(ctx context.Context, height uint64) (bTx structs.BlockAndTx, err error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.TimeoutBlockCall)
+	defer cancel()
+
+	if bTx.Block, err = c.GetBlock(ctx, height); err != nil {
+		return bTx, err
+	}
+
+	if bTx.Transactions, err = c.SearchTx(ctx, bTx.Block); err != nil {
+		c.log.Debug("[COSMOS-CLIENT] Error getting transactions by height", zap.Uint64("height", height), zap.Error(err))
+		return bTx, err
+	}
+	return bTx, nil
+}
+
+// GetBlock fetches block of given height
+func (c *Client) GetBlock(ctx context.Context, height uint64) (structs.Block, error) {
+	c.log.Debug("[COSMOS-WORKER] Getting block", zap.Uint64("height", height))
+
+	b, err := c.tmServiceClient.GetBlockByHeight(ctx, &tmservice.GetBlockByHeightRequest{Height: int64(height)}, grpc.WaitForReady(true))
+	if err != nil {
+		c.log.Debug("[COSMOS-CLIENT] Error getting block by height", zap.Uint64("height", height), zap.Error(err))
+		return structs.Block{}, nodeError(err)
+	}
+
+	c.log.Debug("[COSMOS-WORKER] Got block", zap.Uint64("height", height))
+
+	return mapper.BlockMapper(b), nil
+}
+
+// GetLatest fetches the height of the most recent block of the chain
 func (c *Client) GetLatest(ctx context.Context) (uint64, error) {
 	ctx, cancel := context.WithTimeout(ctx, c.cfg.TimeoutBlockCall)
 	defer cancel()
@@ -62,7 +88,7 @@ func (c *Client) GetLatest(ctx context.Context) (uint64, error) {
 	b, err := c.tmServiceClient.GetLatestBlock(ctx, &tmservice.GetLatestBlockRequest{}, grpc.WaitForReady(true))
 	if err != nil {
 		c.log.Debug("[COSMOS-CLIENT] Error getting latest block", zap.Error(err))
-		return 0, err
+		return 0, nodeError(err)
 	}
 
 	c.log.Debug("[COSMOS-CLIENT] Got latest block", zap.Uint64("height", uint64(b.Block.Header.Height)))
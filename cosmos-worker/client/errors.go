@@ -0,0 +1,29 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// nodeError classifies error returned by the cosmos node, so manager knows how to retry
+func nodeError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	st := status.Convert(err)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), st.Code() == codes.DeadlineExceeded, st.Code() == codes.Unavailable:
+		return fmt.Errorf("%w: %s", structs.ErrNodeTimeout, err.Error())
+	case st.Code() == codes.InvalidArgument && strings.Contains(st.Message(), "height"):
+		return fmt.Errorf("%w: %s", structs.ErrHeightNotAvailable, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,93 @@
+package mapper
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	"github.com/gogo/protobuf/proto"
+)
+
+// parties returns the addresses involved in transaction: the addresses found in its messages (signers, senders,
+// recipients, validators...), the payer and granter of the fee and the addresses in the attributes of its events
+func parties(msgs []*codectypes.Any, authInfo *structs.AuthInfo, logs []structs.Log) []string {
+	found := make(map[string]struct{})
+
+	for _, msg := range msgs {
+		if msg == nil {
+			continue
+		}
+		t := proto.MessageType(strings.TrimPrefix(msg.TypeUrl, "/"))
+		if t == nil || t.Kind() != reflect.Ptr {
+			continue
+		}
+		pb, ok := reflect.New(t.Elem()).Interface().(proto.Message)
+		if !ok || proto.Unmarshal(msg.Value, pb) != nil {
+			continue
+		}
+		collectAddresses(reflect.ValueOf(pb), found)
+	}
+
+	if authInfo != nil && authInfo.Fee != nil {
+		addAddress(authInfo.Fee.Sender, found)
+		addAddress(authInfo.Fee.Recipient, found)
+	}
+
+	for _, l := range logs {
+		for _, ev := range l.Events {
+			for _, v := range ev.Attributes {
+				addAddress(v, found)
+			}
+		}
+	}
+
+	if len(found) == 0 {
+		return nil
+	}
+
+	addresses := make([]string, 0, len(found))
+	for a := range found {
+		addresses = append(addresses, a)
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+// collectAddresses walks the decoded message collecting all the strings that are bech32 addresses
+func collectAddresses(v reflect.Value, found map[string]struct{}) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			collectAddresses(v.Elem(), found)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath != "" {
+				continue // unexported
+			}
+			collectAddresses(v.Field(i), found)
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return // bytes
+		}
+		for i := 0; i < v.Len(); i++ {
+			collectAddresses(v.Index(i), found)
+		}
+	case reflect.String:
+		addAddress(v.String(), found)
+	}
+}
+
+func addAddress(s string, found map[string]struct{}) {
+	if s == "" || !strings.Contains(s, "1") {
+		return
+	}
+	if _, _, err := bech32.DecodeAndConvert(s); err == nil {
+		found[s] = struct{}{}
+	}
+}
@@ -63,6 +63,8 @@ func TransactionMapper(ctx context.Context, in *tx.Tx, resp *types.TxResponse, b
 		}
 	}
 
+	tx.Parties = parties(in.GetBody().GetMessages(), tx.AuthInfo, tx.Logs)
+
 	if tx.ExtensionOptions != nil {
 		fmt.Println(tx.ExtensionOptions)
 	}
@@ -103,9 +105,8 @@ func fee(fee *tx.Fee) *structs.Fee {
 		Recipient: fee.Granter,
 	}
 
-	if fee.Amount != nil {
-		f.Amount = fee.Amount[0].Amount.BigInt()
-		f.Currency = fee.Amount[0].Denom
+	for _, c := range fee.Amount {
+		f.Amount = append(f.Amount, structs.Coin{Denom: c.Denom, Amount: c.Amount.BigInt()})
 	}
 
 	return f
@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"fmt"
 	"regexp"
 	"strconv"
 	"time"
@@ -41,13 +42,13 @@ func (c *Client) SearchTx(ctx context.Context, block structs.Block) (txs []struc
 
 		c.log.Debug("[COSMOS-API] Request Time (/tx_search)", zap.Duration("duration", time.Since(now)))
 		if err != nil {
-			return nil, err
+			return nil, nodeError(err)
 		}
 
 		pageTxs := make([]structs.Transaction, len(grpcRes.Txs))
 		for i, trans := range grpcRes.Txs {
 			if pageTxs[i], err = mapper.TransactionMapper(ctx, trans, grpcRes.TxResponses[i], block.Hash, block.Header.ChainID); err != nil {
-				return nil, err
+				return nil, fmt.Errorf("%w: %s", structs.ErrMapping, err.Error())
 			}
 		}
 		txs = append(txs, pageTxs...)
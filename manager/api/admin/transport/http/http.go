@@ -0,0 +1,194 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/figment-networks/graph-demo/connectivity/auth"
+	wsConn "github.com/figment-networks/graph-demo/connectivity/ws"
+	"github.com/figment-networks/graph-demo/manager/backfill"
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"github.com/google/uuid"
+)
+
+type JobsService interface {
+	Create(ctx context.Context, chainID string, from, to uint64) (structs.BackfillJob, error)
+	List(ctx context.Context, chainID string) ([]structs.BackfillJob, error)
+	Get(ctx context.Context, id uuid.UUID) (structs.BackfillJob, error)
+	Pause(ctx context.Context, id uuid.UUID) (structs.BackfillJob, error)
+	Resume(ctx context.Context, id uuid.UUID) (structs.BackfillJob, error)
+	Cancel(ctx context.Context, id uuid.UUID) (structs.BackfillJob, error)
+}
+
+type ConsistencyService interface {
+	Reports(chainID string) []structs.ConsistencyReport
+}
+
+type SessionsService interface {
+	List(role string) []wsConn.SessionInfo
+}
+
+type CreateJobRequest struct {
+	ChainID string `json:"chain_id"`
+	From    uint64 `json:"from"`
+	To      uint64 `json:"to"`
+}
+
+type JSONResponse struct {
+	Data   interface{}    `json:"data,omitempty"`
+	Errors []ErrorMessage `json:"errors,omitempty"`
+}
+
+type ErrorMessage struct {
+	Message string `json:"message,omitempty"`
+}
+
+type Handler struct {
+	jobs     JobsService
+	checker  ConsistencyService
+	sessions SessionsService
+	authn    auth.Authenticator
+}
+
+// NewHandler creates Handler, its endpoints are limited to the requests authenticated with admin role
+func NewHandler(jobs JobsService, checker ConsistencyService, sessions SessionsService, authn auth.Authenticator) *Handler {
+	return &Handler{
+		jobs:     jobs,
+		checker:  checker,
+		sessions: sessions,
+		authn:    authn,
+	}
+}
+
+// AttachMux attaches backfill jobs endpoints:
+//
+//	GET  /admin/jobs?chain_id=        - list jobs
+//	POST /admin/jobs                  - create job {"chain_id", "from", "to"}
+//	GET  /admin/jobs/{id}             - get job
+//	POST /admin/jobs/{id}/pause       - pause job
+//	POST /admin/jobs/{id}/resume      - resume job
+//	POST /admin/jobs/{id}/cancel      - cancel job
+//	GET  /admin/consistency?chain_id= - last consistency check reports
+//	GET  /admin/sessions?role=        - live websocket sessions
+func (h *Handler) AttachMux(mux *http.ServeMux) {
+	mux.HandleFunc("/admin/jobs", auth.RequireRole(h.authn, wsConn.RoleAdmin, h.HandleJobs))
+	mux.HandleFunc("/admin/jobs/", auth.RequireRole(h.authn, wsConn.RoleAdmin, h.HandleJob))
+	mux.HandleFunc("/admin/consistency", auth.RequireRole(h.authn, wsConn.RoleAdmin, h.HandleConsistency))
+	mux.HandleFunc("/admin/sessions", auth.RequireRole(h.authn, wsConn.RoleAdmin, h.HandleSessions))
+}
+
+func (h *Handler) HandleSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	writeResponse(w, http.StatusOK, h.sessions.List(r.URL.Query().Get("role")))
+}
+
+func (h *Handler) HandleConsistency(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	writeResponse(w, http.StatusOK, h.checker.Reports(r.URL.Query().Get("chain_id")))
+}
+
+func (h *Handler) HandleJobs(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		jobs, err := h.jobs.List(ctx, r.URL.Query().Get("chain_id"))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if jobs == nil {
+			jobs = []structs.BackfillJob{}
+		}
+		writeResponse(w, http.StatusOK, jobs)
+
+	case http.MethodPost:
+		req := &CreateJobRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		job, err := h.jobs.Create(ctx, req.ChainID, req.From, req.To)
+		if err != nil {
+			writeError(w, statusCode(err), err)
+			return
+		}
+		writeResponse(w, http.StatusCreated, job)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+func (h *Handler) HandleJob(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/jobs/"), "/"), "/")
+	id, err := uuid.Parse(parts[0])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var job structs.BackfillJob
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		job, err = h.jobs.Get(ctx, id)
+	case len(parts) == 2 && r.Method == http.MethodPost && parts[1] == "pause":
+		job, err = h.jobs.Pause(ctx, id)
+	case len(parts) == 2 && r.Method == http.MethodPost && parts[1] == "resume":
+		job, err = h.jobs.Resume(ctx, id)
+	case len(parts) == 2 && r.Method == http.MethodPost && parts[1] == "cancel":
+		job, err = h.jobs.Cancel(ctx, id)
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	if err != nil {
+		writeError(w, statusCode(err), err)
+		return
+	}
+	writeResponse(w, http.StatusOK, job)
+}
+
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, backfill.ErrJobDoesntExist):
+		return http.StatusNotFound
+	case errors.Is(err, backfill.ErrInvalidStatus):
+		return http.StatusConflict
+	case errors.Is(err, backfill.ErrInvalidRange), errors.Is(err, backfill.ErrEmptyChainID):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeResponse(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(JSONResponse{Data: data})
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(JSONResponse{Errors: []ErrorMessage{{Message: err.Error()}}})
+}
@@ -2,22 +2,45 @@ package api
 
 import (
 	"context"
-	"errors"
+	_ "embed"
 	"fmt"
 
 	"github.com/figment-networks/graph-demo/graphcall"
+	qStructs "github.com/figment-networks/graph-demo/graphcall/response"
 	"github.com/figment-networks/graph-demo/manager/store"
 	"github.com/figment-networks/graph-demo/manager/structs"
 )
 
+// FamilyCosmos is the chain family served by default
+const FamilyCosmos = "cosmos"
+
+//go:embed schema/cosmos.graphql
+var cosmosSchema []byte
+
 type Service struct {
 	store store.Storager
+	graph *Graph
 }
 
 func NewService(store store.Storager) *Service {
-	return &Service{
+	s := &Service{
 		store: store,
+		graph: NewGraph(FamilyCosmos),
+	}
+
+	cosmos, err := ParseSchema(FamilyCosmos, cosmosSchema)
+	if err != nil {
+		panic(fmt.Errorf("error parsing embedded cosmos schema: %w", err))
 	}
+	s.addCosmosResolvers(cosmos)
+	s.graph.AddSchema(cosmos)
+
+	return s
+}
+
+// Graph returns the network graph schemas served by the service, new chain families and root queries are added there
+func (s *Service) Graph() *Graph {
+	return s.graph
 }
 
 func (s *Service) StoreBlock(ctx context.Context, block structs.Block) error {
@@ -37,12 +60,20 @@ func (s *Service) ProcessGraphqlQuery(ctx context.Context, q []byte, v map[strin
 		return nil, fmt.Errorf("error while parsing graphql query: %w", err)
 	}
 
-	d, err := s.getData(ctx, &queries)
-	if err != nil {
-		return nil, fmt.Errorf("error while fetching data: %w", err)
+	resp := make(qStructs.MapSlice, len(queries.Queries))
+	for _, query := range queries.Queries {
+		data, err := s.resolve(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("error while fetching data: %w", err)
+		}
+
+		resp[query.Order] = qStructs.MapItem{
+			Key:   query.Name,
+			Value: mapValue(query.Fields, data),
+		}
 	}
 
-	rawResp, err := mapBlocksToResponse(queries.Queries, d)
+	rawResp, err := resp.MarshalJSON()
 	if err != nil {
 		return nil, fmt.Errorf("error while mapping response: %w", err)
 	}
@@ -50,65 +81,23 @@ func (s *Service) ProcessGraphqlQuery(ctx context.Context, q []byte, v map[strin
 	return rawResp, nil
 }
 
-func (s *Service) getData(ctx context.Context, query *graphcall.GraphQuery) (qresp structs.QueriesResp, err error) {
-	qResp := make(map[string]map[uint64]structs.BlockAndTx)
-
-	for _, query := range query.Queries {
-
-		cID := query.Params["chain_id"]
-		cIDv := cID.Params["chain_id"].Value
-		if cIDv == nil {
-			return nil, errors.New("empty parameter chain_id")
-		}
-		chainID, ok := cIDv.(string)
-		if !ok {
-			return nil, errors.New("chain_id is not a string")
-		}
+// resolve resolves root query with the resolver of the schema of its chain family
+func (s *Service) resolve(ctx context.Context, query graphcall.Query) (interface{}, error) {
+	chainID, _ := argValue(query, "chain_id").(string)
+	schema, err := s.graph.Schema(chainID)
+	if err != nil {
+		return nil, err
+	}
 
-		resp := make(map[uint64]structs.BlockAndTx)
-		if query.Name == "block" {
-
-			cID := query.Params["height"]
-			cIDv := cID.Params["height"].Value
-			if cIDv == nil {
-				return nil, errors.New("empty parameter height")
-			}
-			height, ok := cIDv.(uint64)
-			if !ok {
-				return nil, errors.New("height is not a string")
-			}
-
-			btx := structs.BlockAndTx{}
-			btx.Block, err = s.store.GetBlockByHeight(ctx, height, chainID)
-			if err != nil {
-				return nil, err
-			}
-
-			if len(btx.Block.Data.Txs) > 0 {
-				if btx.Transactions, err = s.store.GetTransactionsByParam(ctx, chainID, "height", height); err != nil {
-					return nil, err
-				}
-			}
-
-			resp[height] = btx
-		} else if query.Name == "transaction" {
-
-			for k, h := range query.Params {
-				if k == "chain_id" {
-					continue
-				}
-
-				btx := structs.BlockAndTx{}
-				if btx.Transactions, err = s.store.GetTransactionsByParam(ctx, chainID, k, h.Params[k].Value); err != nil {
-					return nil, err
-				}
-
-				resp[0] = btx
-				break
-			}
-		}
+	args, err := schema.Args(query)
+	if err != nil {
+		return nil, err
+	}
 
-		qResp[query.Name] = resp
+	r, ok := schema.Get(query.Name)
+	if !ok {
+		return nil, fmt.Errorf("query %q of %s schema has no resolver", query.Name, schema.Family)
 	}
-	return qResp, nil
+
+	return r(ctx, args)
 }
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+)
+
+// Page sizes of connections
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PageInfo is the Relay page info of connection
+type PageInfo struct {
+	HasNextPage bool
+	EndCursor   string
+}
+
+type BlockEdge struct {
+	Cursor string
+	Node   structs.Block
+}
+
+// BlockConnection is the Relay connection of blocks
+type BlockConnection struct {
+	Edges    []BlockEdge
+	PageInfo PageInfo
+}
+
+type TransactionEdge struct {
+	Cursor string
+	Node   structs.Transaction
+}
+
+// TransactionConnection is the Relay connection of transactions
+type TransactionConnection struct {
+	Edges    []TransactionEdge
+	PageInfo PageInfo
+}
+
+// encodeCursor returns opaque cursor of the position, the kind prevents using cursor of one list with another
+func encodeCursor(kind string, c structs.Cursor) string {
+	s := kind + ":" + strconv.FormatUint(c.Height, 10)
+	if c.Hash != "" {
+		s += ":" + c.Hash
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func decodeCursor(kind, cursor string) (*structs.Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCursor, err.Error())
+	}
+
+	parts := strings.SplitN(string(b), ":", 3)
+	if len(parts) < 2 || parts[0] != kind {
+		return nil, fmt.Errorf("%w: not a %s cursor", ErrInvalidCursor, kind)
+	}
+
+	c := &structs.Cursor{}
+	if c.Height, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCursor, err.Error())
+	}
+	if len(parts) == 3 {
+		c.Hash = parts[2]
+	}
+	return c, nil
+}
+
+// rangeQuery builds the query of connection from the arguments, asking for one record more than requested
+// to know if there is a next page
+func rangeQuery(kind string, args Args, fromArg, toArg string) (q structs.RangeQuery, first uint64, err error) {
+	q.ChainID = args.String("chain_id")
+	q.From, _ = args.Uint64(fromArg)
+	q.To, _ = args.Uint64(toArg)
+	if q.To != 0 && q.To < q.From {
+		return q, 0, fmt.Errorf("%s has to be greater or equal to %s", toArg, fromArg)
+	}
+
+	if t, ok := args.Uint64("timeFrom"); ok {
+		q.TimeFrom = unixTime(t)
+	}
+	if t, ok := args.Uint64("timeTo"); ok {
+		q.TimeTo = unixTime(t)
+		if q.TimeTo.Before(q.TimeFrom) {
+			return q, 0, errors.New("timeTo has to be greater or equal to timeFrom")
+		}
+	}
+
+	first = DefaultPageSize
+	if f, ok := args.Uint64("first"); ok {
+		first = f
+	}
+	if first == 0 || first > MaxPageSize {
+		return q, 0, fmt.Errorf("first has to be between 1 and %d", MaxPageSize)
+	}
+	q.Limit = first + 1
+
+	if after := args.String("after"); after != "" {
+		if q.After, err = decodeCursor(kind, after); err != nil {
+			return q, 0, err
+		}
+	}
+
+	return q, first, nil
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+)
+
+// transactionConditions map the arguments of transaction query to filter conditions
+var transactionConditions = map[string]struct {
+	field structs.TxField
+	op    structs.Operator
+}{
+	"hash":         {structs.TxFieldHash, structs.OpEq},
+	"height":       {structs.TxFieldHeight, structs.OpEq},
+	"block_hash":   {structs.TxFieldBlockHash, structs.OpEq},
+	"memo":         {structs.TxFieldMemo, structs.OpEq},
+	"memoContains": {structs.TxFieldMemo, structs.OpContains},
+	"code":         {structs.TxFieldCode, structs.OpEq},
+	"signer":       {structs.TxFieldSigner, structs.OpEq},
+	"address":      {structs.TxFieldAddress, structs.OpEq},
+	"timeFrom":     {structs.TxFieldTime, structs.OpGte},
+	"timeTo":       {structs.TxFieldTime, structs.OpLte},
+}
+
+// addCosmosResolvers adds the resolvers of root queries of cosmos schema
+func (s *Service) addCosmosResolvers(schema *Schema) {
+	mustAdd(schema, "block", s.resolveBlock)
+	mustAdd(schema, "blockAtTime", s.resolveBlockAtTime)
+	mustAdd(schema, "transaction", s.resolveTransaction)
+	mustAdd(schema, "blocks", s.resolveBlocks)
+	mustAdd(schema, "transactions", s.resolveTransactions)
+	mustAdd(schema, "messages", s.resolveMessages)
+	mustAdd(schema, "transactionStats", s.resolveTransactionStats)
+}
+
+func mustAdd(schema *Schema, name string, r Resolver) {
+	if err := schema.Add(name, r); err != nil {
+		panic(err)
+	}
+}
+
+func (s *Service) resolveBlock(ctx context.Context, args Args) (interface{}, error) {
+	height, _ := args.Uint64("height")
+	return s.store.GetBlockByHeight(ctx, height, args.String("chain_id"))
+}
+
+func (s *Service) resolveBlockAtTime(ctx context.Context, args Args) (interface{}, error) {
+	t, _ := args.Uint64("time")
+	return s.store.GetBlockAtTime(ctx, args.String("chain_id"), unixTime(t))
+}
+
+// unixTime converts the time argument given in unix seconds
+func unixTime(sec uint64) time.Time {
+	return time.Unix(int64(sec), 0).UTC()
+}
+
+func (s *Service) resolveTransaction(ctx context.Context, args Args) (interface{}, error) {
+	f := structs.TxFilter{Limit: MaxPageSize}
+	for name, v := range args {
+		c, ok := transactionConditions[name]
+		if !ok {
+			continue
+		}
+		if c.field == structs.TxFieldTime {
+			v = unixTime(v.(uint64))
+		}
+		f.Conditions = append(f.Conditions, structs.TxCondition{Field: c.field, Op: c.op, Value: v})
+	}
+
+	if len(f.Conditions) == 0 {
+		names := make([]string, 0, len(transactionConditions))
+		for name := range transactionConditions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("at least one of %s has to be set", strings.Join(names, ", "))
+	}
+
+	return s.store.FindTransactions(ctx, args.String("chain_id"), f)
+}
+
+func (s *Service) resolveBlocks(ctx context.Context, args Args) (interface{}, error) {
+	q, first, err := rangeQuery("block", args, "from", "to")
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, err := s.store.GetBlocks(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := BlockConnection{Edges: []BlockEdge{}}
+	if uint64(len(blocks)) > first {
+		blocks = blocks[:first]
+		conn.PageInfo.HasNextPage = true
+	}
+	for _, b := range blocks {
+		conn.Edges = append(conn.Edges, BlockEdge{Cursor: encodeCursor("block", structs.Cursor{Height: b.Height}), Node: b})
+	}
+	if len(conn.Edges) > 0 {
+		conn.PageInfo.EndCursor = conn.Edges[len(conn.Edges)-1].Cursor
+	}
+	return conn, nil
+}
+
+func (s *Service) resolveTransactions(ctx context.Context, args Args) (interface{}, error) {
+	q, first, err := rangeQuery("transaction", args, "heightFrom", "heightTo")
+	if err != nil {
+		return nil, err
+	}
+	q.Address = args.String("address")
+
+	txs, err := s.store.GetTransactions(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := TransactionConnection{Edges: []TransactionEdge{}}
+	if uint64(len(txs)) > first {
+		txs = txs[:first]
+		conn.PageInfo.HasNextPage = true
+	}
+	for _, tx := range txs {
+		conn.Edges = append(conn.Edges, TransactionEdge{Cursor: encodeCursor("transaction", structs.Cursor{Height: tx.Height, Hash: tx.Hash}), Node: tx})
+	}
+	if len(conn.Edges) > 0 {
+		conn.PageInfo.EndCursor = conn.Edges[len(conn.Edges)-1].Cursor
+	}
+	return conn, nil
+}
+
+func (s *Service) resolveMessages(ctx context.Context, args Args) (interface{}, error) {
+	q := structs.MessageQuery{
+		ChainID: args.String("chain_id"),
+		Type:    args.String("type"),
+		Module:  args.String("module"),
+		Limit:   MaxPageSize,
+	}
+	q.Height, _ = args.Uint64("height")
+	return s.store.GetMessages(ctx, q)
+}
+
+func (s *Service) resolveTransactionStats(ctx context.Context, args Args) (interface{}, error) {
+	q := structs.StatsQuery{
+		ChainID: args.String("chain_id"),
+		GroupBy: structs.StatsGroup(args.String("groupBy")),
+		Limit:   MaxPageSize,
+	}
+	q.From, _ = args.Uint64("from")
+	q.To, _ = args.Uint64("to")
+	if q.To != 0 && q.To < q.From {
+		return nil, errors.New("to has to be greater or equal to from")
+	}
+	return s.store.GetTransactionStats(ctx, q)
+}
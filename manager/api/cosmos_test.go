@@ -0,0 +1,106 @@
+package api_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/figment-networks/graph-demo/manager/api"
+	"github.com/figment-networks/graph-demo/manager/store"
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type storeMock struct {
+	store.Storager
+
+	chainID  string
+	filters  []structs.TxFilter
+	msgQuery structs.MessageQuery
+}
+
+func (sm *storeMock) FindTransactions(ctx context.Context, chainID string, f structs.TxFilter) ([]structs.Transaction, error) {
+	sm.chainID = chainID
+	sm.filters = append(sm.filters, f)
+	return []structs.Transaction{{ChainID: chainID, Hash: "TX", Height: 10}}, nil
+}
+
+func (sm *storeMock) GetMessages(ctx context.Context, q structs.MessageQuery) ([]structs.Message, error) {
+	sm.msgQuery = q
+	return []structs.Message{{
+		ChainID: q.ChainID,
+		Height:  10,
+		TxHash:  "TX",
+		Type:    "/cosmos.bank.v1beta1.MsgSend",
+		Module:  "bank",
+		Value:   []byte(`{"from_address":"cosmos1a","amount":[{"denom":"uatom","amount":"1"}]}`),
+	}}, nil
+}
+
+func TestTransactionQueryRejectsUnknownArguments(t *testing.T) {
+	queries := []string{
+		`query Q { transaction(chain_id: "a", raw_log: "x") { hash } }`,
+		`query Q { transaction(chain_id: "a", hash: "x", height_or_1: 1) { hash } }`,
+		`query Q($v: String) { transaction(chain_id: "a", signatures: $v) { hash } }`,
+	}
+
+	for _, q := range queries {
+		sm := &storeMock{}
+		_, err := api.NewService(sm).ProcessGraphqlQuery(context.Background(), []byte(q), map[string]interface{}{"v": "x"})
+		require.Error(t, err, q)
+		assert.Contains(t, err.Error(), "unknown argument")
+		assert.Empty(t, sm.filters, "store must not be called for %s", q)
+	}
+}
+
+func TestTransactionQueryFilters(t *testing.T) {
+	sm := &storeMock{}
+	svc := api.NewService(sm)
+
+	t.Run("conditions", func(t *testing.T) {
+		resp, err := svc.ProcessGraphqlQuery(context.Background(), []byte(`query Q($signer: String) {
+			transaction(chain_id: "a", signer: $signer, memoContains: "hi", code: 0, timeFrom: 1627776000) { hash height }
+		}`), map[string]interface{}{"signer": "cosmos1abc"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"transaction":[{"hash":"TX","height":10}]}`, string(resp))
+
+		require.Len(t, sm.filters, 1)
+		assert.Equal(t, "a", sm.chainID)
+		assert.Equal(t, uint64(api.MaxPageSize), sm.filters[0].Limit)
+		assert.ElementsMatch(t, []structs.TxCondition{
+			{Field: structs.TxFieldSigner, Op: structs.OpEq, Value: "cosmos1abc"},
+			{Field: structs.TxFieldMemo, Op: structs.OpContains, Value: "hi"},
+			{Field: structs.TxFieldCode, Op: structs.OpEq, Value: uint64(0)},
+			{Field: structs.TxFieldTime, Op: structs.OpGte, Value: time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)},
+		}, sm.filters[0].Conditions)
+		for _, c := range sm.filters[0].Conditions {
+			assert.NoError(t, c.Validate())
+		}
+	})
+
+	t.Run("no filter", func(t *testing.T) {
+		_, err := svc.ProcessGraphqlQuery(context.Background(), []byte(`query Q { transaction(chain_id: "a") { hash } }`), nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "at least one of")
+	})
+
+	t.Run("wrong argument type", func(t *testing.T) {
+		_, err := svc.ProcessGraphqlQuery(context.Background(), []byte(`query Q { transaction(chain_id: "a", height: "1 OR 1=1") { hash } }`), nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `argument "height"`)
+	})
+}
+
+func TestMessagesQuery(t *testing.T) {
+	sm := &storeMock{}
+	resp, err := api.NewService(sm).ProcessGraphqlQuery(context.Background(), []byte(`query Q {
+		messages(chain_id: "a", type: "/cosmos.bank.v1beta1.MsgSend", height: 10) { tx_hash index module value }
+	}`), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, structs.MessageQuery{ChainID: "a", Type: "/cosmos.bank.v1beta1.MsgSend", Height: 10, Limit: api.MaxPageSize}, sm.msgQuery)
+	assert.JSONEq(t, `{"messages":[{"tx_hash":"TX","index":0,"module":"bank",
+		"value":{"from_address":"cosmos1a","amount":[{"denom":"uatom","amount":"1"}]}}]}`, string(resp))
+}
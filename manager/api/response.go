@@ -1,7 +1,6 @@
 package api
 
 import (
-	"errors"
 	"fmt"
 	"math/big"
 	"reflect"
@@ -10,64 +9,30 @@ import (
 
 	"github.com/figment-networks/graph-demo/graphcall"
 	qStructs "github.com/figment-networks/graph-demo/graphcall/response"
-	"github.com/figment-networks/graph-demo/manager/structs"
 
 	"github.com/google/uuid"
 )
 
-func mapBlocksToResponse(queries []graphcall.Query, qResp structs.QueriesResp) ([]byte, error) {
-	var resp qStructs.MapSlice
-	var err error
-
-	resp = make([]qStructs.MapItem, len(queries))
-	for _, query := range queries {
-
-		blocks, ok := qResp[query.Name]
-		if !ok {
-			return nil, errors.New("response is empty")
-		}
-
-		bLen := len(blocks)
-		var response interface{}
-		responses := make([]interface{}, bLen)
-		i := 0
-		for _, bAndTxs := range blocks {
-			if response, err = fieldsResp(&query, bAndTxs); err != nil {
-				return nil, err
-			}
-
-			responses[i] = response
-			i++
-		}
-
-		if bLen > 1 {
-			response = responses
-		}
-
-		resp[query.Order] = qStructs.MapItem{
-			Key:   query.Name,
-			Value: response,
-		}
+// mapValue maps the result of resolver to the fields selected in query
+func mapValue(fields map[string]graphcall.Field, v interface{}) interface{} {
+	if v == nil {
+		return nil
 	}
 
-	return resp.MarshalJSON()
-}
-
-func fieldsResp(q *graphcall.Query, blockAndTx structs.BlockAndTx) (resp interface{}, err error) {
-	qStr := strings.ToLower(q.Name)
-	parseBlock := strings.Contains(qStr, "block")
-	parseTransaction := strings.Contains(qStr, "transaction")
-
-	if parseBlock && !parseTransaction {
-		resp = mapStructToFields(q.Fields, blockAndTx.Block)
-	} else if !parseBlock && parseTransaction {
-		resp = MapSliceToFields(q.Fields, blockAndTx.Transactions)
-	} else {
-		resp = mapStructToFields(q.Fields, blockAndTx)
-
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice:
+		return MapSliceToFields(fields, v)
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return nil
+		}
+		return mapStructToFields(fields, v)
+	case reflect.Struct:
+		return mapStructToFields(fields, v)
+	default:
+		return formatValue("", v)
 	}
-
-	return resp, err
 }
 
 func mapStructToFields(fields map[string]graphcall.Field, s interface{}) qStructs.MapSlice {
@@ -83,7 +48,7 @@ func mapStructToFields(fields map[string]graphcall.Field, s interface{}) qStruct
 			continue
 		}
 
-		field, ok := fields[fieldName]
+		field, ok := queryField(fields, fieldName)
 		if !ok {
 			// omit fields that are not defined in the graph query
 			continue
@@ -94,13 +59,14 @@ func mapStructToFields(fields map[string]graphcall.Field, s interface{}) qStruct
 		fieldKind := fieldType.Kind()
 
 		switch fieldType {
-		case reflect.TypeOf(time.Time{}):
+		case reflect.TypeOf(time.Time{}), reflect.TypeOf(&big.Int{}):
 			value = formatValue(fieldName, filedValue)
 		default:
 			switch fieldKind {
 			case reflect.Ptr:
-				if filedValue = v.Field(i).Elem().Interface(); filedValue != nil {
-					value = mapStructToFields(field.Fields, filedValue)
+				value = nil
+				if !v.Field(i).IsNil() {
+					value = mapStructToFields(field.Fields, v.Field(i).Elem().Interface())
 				}
 			case reflect.Slice:
 				if reflect.TypeOf(filedValue).Elem().Kind() == reflect.Struct {
@@ -148,6 +114,20 @@ func mapStructToFields(fields map[string]graphcall.Field, s interface{}) qStruct
 	return ms
 }
 
+// queryField returns the field of query selecting struct field, snake_case names of query (like chain_id)
+// select the fields of the same name without underscores
+func queryField(fields map[string]graphcall.Field, fieldName string) (graphcall.Field, bool) {
+	if f, ok := fields[fieldName]; ok {
+		return f, true
+	}
+	for name, f := range fields {
+		if strings.ReplaceAll(name, "_", "") == fieldName {
+			return f, true
+		}
+	}
+	return graphcall.Field{}, false
+}
+
 func nameIsStrict(name string) bool {
 	return name == "id"
 }
@@ -10,12 +10,14 @@ import (
 )
 
 type ManagerService interface {
-	ProcessGraphqlQuery(ctx context.Context, q []byte, v map[string]interface{}) ([]byte, error)
+	ProcessVersionedQuery(ctx context.Context, version string, q []byte, v map[string]interface{}) ([]byte, error)
 }
 
 type JSONGraphQLRequest struct {
 	Query     string                 `json:"query"`
 	Variables map[string]interface{} `json:"variables"`
+	// Version of the query schema, the default one if it's empty
+	Version string `json:"version,omitempty"`
 }
 
 type JSONGraphQLResponse struct {
@@ -60,7 +62,7 @@ func (h *Handler) HandleRequest(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	response, err := h.service.ProcessGraphqlQuery(ctx, []byte(req.Query), req.Variables)
+	response, err := h.service.ProcessVersionedQuery(ctx, req.Version, []byte(req.Query), req.Variables)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		resp.Errors = []ErrorMessage{{Message: err.Error()}}
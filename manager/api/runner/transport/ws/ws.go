@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	"github.com/figment-networks/graph-demo/connectivity"
+	"github.com/figment-networks/graph-demo/connectivity/jsonrpc"
 	wsConn "github.com/figment-networks/graph-demo/connectivity/ws"
 	"github.com/figment-networks/graph-demo/manager/structs"
 	"github.com/figment-networks/graph-demo/manager/subscription"
@@ -22,11 +23,19 @@ var upgrader = websocket.Upgrader{
 
 var ErrConnectionClosed = errors.New("connection closed")
 
+// ErrPrunedHeight is returned for subscriptions starting at height removed by retention policy
+var ErrPrunedHeight = &jsonrpc.Error{Code: -32030, Message: "Height is pruned"}
+
 type Subscriber interface {
 	Add(ctx context.Context, ev string, sub subscription.Sub) error
 	Remove(id string) error
 }
 
+// PruneGuard checks if the data of given height is still stored
+type PruneGuard interface {
+	CheckHeight(chainID string, height uint64) error
+}
+
 type ErrorMessage struct {
 	Message string `json:"message,omitempty"`
 }
@@ -42,7 +51,7 @@ type JSONGraphQLResponse struct {
 }
 
 type ManagerService interface {
-	ProcessGraphqlQuery(ctx context.Context, q []byte, v map[string]interface{}) ([]byte, error)
+	ProcessVersionedQuery(ctx context.Context, version string, q []byte, v map[string]interface{}) ([]byte, error)
 }
 
 type ProcessHandler struct {
@@ -55,6 +64,7 @@ type ProcessHandler struct {
 	registrySync sync.RWMutex
 
 	subscriptions Subscriber
+	guard         PruneGuard
 }
 
 func NewProcessHandler(log *zap.Logger, svc ManagerService, reg *wsConn.Registry, subscriptions Subscriber) *ProcessHandler {
@@ -71,6 +81,11 @@ func NewProcessHandler(log *zap.Logger, svc ManagerService, reg *wsConn.Registry
 	return ph
 }
 
+// LinkPruneGuard makes subscriptions starting at pruned heights rejected
+func (ph *ProcessHandler) LinkPruneGuard(g PruneGuard) {
+	ph.guard = g
+}
+
 func (ph *ProcessHandler) Add(name string, handler connectivity.Handler) {
 	ph.registrySync.Lock()
 	defer ph.registrySync.Unlock()
@@ -142,7 +157,26 @@ func (ph *ProcessHandler) GraphQLRequest(ctx context.Context, req connectivity.R
 		}
 	}
 
-	r.Data, err = ph.service.ProcessGraphqlQuery(ctx, []byte(gQLReq.Query), gQLReq.Variables)
+	// version of the query schema, the default one if it's not set
+	var version string
+	if len(args) > 2 {
+		if err = json.Unmarshal(args[2], &version); err != nil {
+			r.Errors = append(r.Errors, ErrorMessage{
+				Message: "Error unmarshaling query version " + err.Error(),
+			})
+			if err := enc.Encode(r); err != nil {
+				ph.log.Error("error encoding data", zap.Error(err))
+				return
+			}
+
+			if err := resp.Send(b.Bytes(), nil); err != nil {
+				ph.log.Error("error sending data in GraphQLRequest", zap.Error(err))
+			}
+			return
+		}
+	}
+
+	r.Data, err = ph.service.ProcessVersionedQuery(ctx, version, []byte(gQLReq.Query), gQLReq.Variables)
 	if err != nil {
 		r.Errors = append(r.Errors, ErrorMessage{
 			Message: "Error while processing graphql query " + err.Error(),
@@ -206,9 +240,20 @@ func (ph *ProcessHandler) Subscribe(ctx context.Context, req connectivity.Reques
 		return
 	}
 
+	if ph.guard != nil {
+		for _, ev := range events {
+			if err := ph.guard.CheckHeight(ev.ChainID, ev.StartingHeight); err != nil {
+				if err := resp.Send(nil, &jsonrpc.Error{Code: ErrPrunedHeight.Code, Message: err.Error()}); err != nil {
+					ph.log.Error("error sending data in Subscribe", zap.Error(err))
+				}
+				return
+			}
+		}
+	}
+
 	for _, ev := range events {
-		ph.subscriptions.Add(ctx, ev.Name, NewSubscriptionInstance(req.ConnID(), ph.reg, ev.StartingHeight))
-		ph.log.Debug("added subscription for event", zap.String("id", req.ConnID()), zap.String("event", ev.Name), zap.Uint64("from", ev.StartingHeight))
+		ph.subscriptions.Add(ctx, ev.Name, NewSubscriptionInstance(req.ConnID(), ph.reg, ev.ChainID, ev.StartingHeight))
+		ph.log.Debug("added subscription for event", zap.String("id", req.ConnID()), zap.String("event", ev.Name), zap.String("chain_id", ev.ChainID), zap.Uint64("from", ev.StartingHeight))
 	}
 
 	if err := resp.Send(json.RawMessage([]byte(`"ACK"`)), nil); err != nil {
@@ -267,30 +312,65 @@ func (ph *ProcessHandler) Unsubscribe(ctx context.Context, req connectivity.Requ
 	resp.Send(json.RawMessage([]byte(`"ACK"`)), nil)
 }
 
-func NewSubscriptionInstance(connID string, reg *wsConn.Registry, from uint64) subscription.Sub {
+func NewSubscriptionInstance(connID string, reg *wsConn.Registry, chainID string, from uint64) subscription.Sub {
 	return &SubscriptionInstance{
-		connID: connID,
-		reg:    reg,
-		from:   from,
+		connID:  connID,
+		chainID: chainID,
+		reg:     reg,
+		from:    from,
 	}
 }
 
 type SubscriptionInstance struct {
-	connID string
+	connID  string
+	chainID string
 
 	reg     *wsConn.Registry
 	from    uint64
 	current uint64
 }
 
+// Send sends the event as JSON-RPC notification, it's not acknowledged by the runner.
+// Sessions not supporting notifications get the event as a call.
 func (si *SubscriptionInstance) Send(ctx context.Context, height uint64, name string, resp json.RawMessage) error {
 	ss, ok := si.reg.Get(si.connID)
 	if !ok || ss == nil {
 		return errors.New("connection does not exists")
 	}
 
-	_, err := ss.SendSync("event", []json.RawMessage{[]byte(`"` + name + `"`), resp})
+	params := []json.RawMessage{[]byte(`"` + name + `"`), resp}
+	if n, ok := ss.(wsConn.Notifier); ok {
+		return n.Notify(ctx, "event", params)
+	}
+
+	_, err := ss.SendSync(ctx, "event", params)
+	return err
+}
+
+// SendBatch sends events as a single JSON-RPC batch of notifications, falling back to separate calls
+// if the session doesn't support batches
+func (si *SubscriptionInstance) SendBatch(ctx context.Context, height uint64, events []subscription.Payload) error {
+	ss, ok := si.reg.Get(si.connID)
+	if !ok || ss == nil {
+		return errors.New("connection does not exists")
+	}
+
+	n, ok := ss.(wsConn.Notifier)
+	if !ok {
+		for _, e := range events {
+			if _, err := ss.SendSync(ctx, "event", []json.RawMessage{[]byte(`"` + e.Name + `"`), e.Data}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	calls := make([]wsConn.Call, len(events))
+	for i, e := range events {
+		calls[i] = wsConn.Call{Method: "event", Params: []json.RawMessage{[]byte(`"` + e.Name + `"`), e.Data}, Notification: true}
+	}
 
+	_, err := n.SendBatch(ctx, calls)
 	return err
 }
 
@@ -298,6 +378,10 @@ func (si *SubscriptionInstance) ID() string {
 	return si.connID
 }
 
+func (si *SubscriptionInstance) ChainID() string {
+	return si.chainID
+}
+
 func (si *SubscriptionInstance) FromHeight() uint64 {
 	return si.from
 }
@@ -0,0 +1,314 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/figment-networks/graph-demo/graphcall"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+var ErrUnknownQuery = errors.New("unknown query")
+
+// Args are the validated arguments of root query
+type Args map[string]interface{}
+
+// String returns string argument, empty if it's not set
+func (a Args) String(name string) string {
+	s, _ := a[name].(string)
+	return s
+}
+
+// Uint64 returns integer argument, ok is false if it's not set
+func (a Args) Uint64(name string) (v uint64, ok bool) {
+	v, ok = a[name].(uint64)
+	return v, ok
+}
+
+// Has checks if argument is set
+func (a Args) Has(name string) bool {
+	_, ok := a[name]
+	return ok
+}
+
+// Resolver resolves root query. The result is a struct, a slice of structs or nil,
+// mapped to the fields selected in the query.
+type Resolver func(ctx context.Context, args Args) (interface{}, error)
+
+// ArgumentDef is the definition of the argument of root query
+type ArgumentDef struct {
+	Name    string
+	Type    string
+	NotNull bool
+	IsArray bool
+	// Values are the values allowed for the argument of enum type
+	Values []string
+}
+
+// QueryDef is the definition of root query
+type QueryDef struct {
+	Name    string
+	Type    string
+	IsArray bool
+	Args    map[string]ArgumentDef
+}
+
+// Schema is the network graph schema of a chain family. It declares root queries with their arguments
+// and keeps resolvers serving them.
+type Schema struct {
+	Family string
+
+	queries map[string]QueryDef
+
+	resolvers map[string]Resolver
+	rl        sync.RWMutex
+}
+
+// ParseSchema parses GraphQL schema definition, root queries are the fields of `Query` type
+func ParseSchema(family string, sdl []byte) (*Schema, error) {
+	doc, err := parser.Parse(parser.ParseParams{
+		Options: parser.ParseOptions{
+			NoSource: true,
+		},
+		Source: &source.Source{
+			Body: sdl,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Schema{
+		Family:    family,
+		queries:   make(map[string]QueryDef),
+		resolvers: make(map[string]Resolver),
+	}
+
+	enums := make(map[string][]string)
+	for _, def := range doc.Definitions {
+		if ed, ok := def.(*ast.EnumDefinition); ok {
+			for _, v := range ed.Values {
+				enums[ed.Name.Value] = append(enums[ed.Name.Value], v.Name.Value)
+			}
+		}
+	}
+
+	for _, def := range doc.Definitions {
+		od, ok := def.(*ast.ObjectDefinition)
+		if !ok || od.Name.Value != "Query" {
+			continue
+		}
+
+		for _, f := range od.Fields {
+			qd := QueryDef{Name: f.Name.Value, Args: make(map[string]ArgumentDef)}
+			qd.Type, _, qd.IsArray = typeOf(f.Type)
+			for _, a := range f.Arguments {
+				ad := ArgumentDef{Name: a.Name.Value}
+				ad.Type, ad.NotNull, ad.IsArray = typeOf(a.Type)
+				ad.Values = enums[ad.Type]
+				qd.Args[ad.Name] = ad
+			}
+			s.queries[qd.Name] = qd
+		}
+	}
+
+	if len(s.queries) == 0 {
+		return nil, errors.New("schema doesn't declare any query")
+	}
+
+	return s, nil
+}
+
+// typeOf unwraps the named type of field or argument
+func typeOf(t ast.Type) (name string, notNull, isArray bool) {
+	switch ty := t.(type) {
+	case *ast.NonNull:
+		name, _, isArray = typeOf(ty.Type)
+		return name, true, isArray
+	case *ast.List:
+		name, _, _ = typeOf(ty.Type)
+		return name, false, true
+	case *ast.Named:
+		return ty.Name.Value, false, false
+	}
+	return "", false, false
+}
+
+// Add adds the resolver of root query, the query has to be declared in schema
+func (s *Schema) Add(name string, r Resolver) error {
+	if _, ok := s.queries[name]; !ok {
+		return fmt.Errorf("%w %q in %s schema", ErrUnknownQuery, name, s.Family)
+	}
+
+	s.rl.Lock()
+	defer s.rl.Unlock()
+	s.resolvers[name] = r
+	return nil
+}
+
+// Get returns the resolver of root query
+func (s *Schema) Get(name string) (r Resolver, ok bool) {
+	s.rl.RLock()
+	defer s.rl.RUnlock()
+	r, ok = s.resolvers[name]
+	return r, ok
+}
+
+// Queries returns the names of declared root queries
+func (s *Schema) Queries() []string {
+	names := make([]string, 0, len(s.queries))
+	for n := range s.queries {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Args validates the arguments of parsed query against its definition
+func (s *Schema) Args(q graphcall.Query) (Args, error) {
+	qd, ok := s.queries[q.Name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q in %s schema", ErrUnknownQuery, q.Name, s.Family)
+	}
+
+	args := make(Args, len(q.Params))
+	for name := range q.Params {
+		ad, ok := qd.Args[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown argument %q of query %q", name, q.Name)
+		}
+
+		v := argValue(q, name)
+		if v == nil {
+			continue
+		}
+
+		var err error
+		if args[name], err = checkArg(ad, v); err != nil {
+			return nil, fmt.Errorf("argument %q of query %q: %w", name, q.Name, err)
+		}
+	}
+
+	for name, ad := range qd.Args {
+		if ad.NotNull && !args.Has(name) {
+			return nil, fmt.Errorf("missing required argument %q of query %q", name, q.Name)
+		}
+	}
+
+	return args, nil
+}
+
+// argValue returns the value of argument, either literal or taken from variable
+func argValue(q graphcall.Query, name string) interface{} {
+	for _, p := range q.Params[name].Params {
+		return p.Value
+	}
+	return nil
+}
+
+func checkArg(ad ArgumentDef, v interface{}) (interface{}, error) {
+	if len(ad.Values) > 0 && !ad.IsArray {
+		if str, ok := v.(string); ok {
+			for _, allowed := range ad.Values {
+				if str == allowed {
+					return str, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("expected one of %s, got %v", strings.Join(ad.Values, ", "), v)
+	}
+
+	if ad.IsArray {
+		switch ad.Type {
+		case "Int":
+			if vs, ok := v.([]uint64); ok {
+				return vs, nil
+			}
+		case "String", "ID":
+			if vs, ok := v.([]string); ok {
+				return vs, nil
+			}
+		}
+		return nil, fmt.Errorf("expected [%s], got %T", ad.Type, v)
+	}
+
+	switch ad.Type {
+	case "Int":
+		switch n := v.(type) {
+		case uint64:
+			return n, nil
+		case float64:
+			if n < 0 || n != float64(uint64(n)) {
+				return nil, fmt.Errorf("expected non negative integer, got %v", n)
+			}
+			return uint64(n), nil
+		}
+	case "String", "ID":
+		if str, ok := v.(string); ok {
+			return str, nil
+		}
+	case "Boolean":
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	default:
+		return v, nil
+	}
+	return nil, fmt.Errorf("expected %s, got %T", ad.Type, v)
+}
+
+// Graph keeps the network graph schemas of chain families and knows the family of every chain
+type Graph struct {
+	defaultFamily string
+
+	schemas map[string]*Schema
+	chains  map[string]string
+	l       sync.RWMutex
+}
+
+// NewGraph creates Graph, the chains of unknown family use defaultFamily
+func NewGraph(defaultFamily string) *Graph {
+	return &Graph{
+		defaultFamily: defaultFamily,
+		schemas:       make(map[string]*Schema),
+		chains:        make(map[string]string),
+	}
+}
+
+// AddSchema adds the schema of its chain family
+func (g *Graph) AddSchema(s *Schema) {
+	g.l.Lock()
+	defer g.l.Unlock()
+	g.schemas[s.Family] = s
+}
+
+// SetChainFamily sets the family of chain
+func (g *Graph) SetChainFamily(chainID, family string) {
+	g.l.Lock()
+	defer g.l.Unlock()
+	g.chains[chainID] = family
+}
+
+// Schema returns the schema of the family of given chain
+func (g *Graph) Schema(chainID string) (*Schema, error) {
+	g.l.RLock()
+	defer g.l.RUnlock()
+
+	family, ok := g.chains[chainID]
+	if !ok {
+		family = g.defaultFamily
+	}
+
+	s, ok := g.schemas[family]
+	if !ok {
+		return nil, fmt.Errorf("no schema of %q chain family", family)
+	}
+	return s, nil
+}
@@ -0,0 +1,227 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/figment-networks/graph-demo/manager/api"
+	"github.com/figment-networks/graph-demo/manager/store/memory"
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	alice = "cosmos1alice"
+	bob   = "cosmos1bob"
+)
+
+// newIndexedService returns service backed by in-memory store with heights 1-5 of the chain,
+// every height has a transaction from alice to bob and the even ones also a failed transaction of bob paying fee in two denominations
+func newIndexedService(t *testing.T) *api.Service {
+	d := memory.NewDriver()
+	start := time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	var heights []structs.BlockAndTx
+	for h := uint64(1); h <= 5; h++ {
+		tm := start.Add(time.Duration(h) * time.Minute)
+		bTx := structs.BlockAndTx{Block: structs.Block{ChainID: "chain", Height: h, Hash: fmt.Sprintf("BLOCK%d", h), Time: tm}}
+
+		send := json.RawMessage(fmt.Sprintf(`{"from_address":%q,"to_address":%q,"amount":[{"denom":"uatom","amount":"%d"}]}`, alice, bob, h))
+		bTx.Transactions = append(bTx.Transactions, structs.Transaction{
+			ChainID: "chain", Height: h, Hash: fmt.Sprintf("A%d", h), BlockHash: bTx.Block.Hash, Time: tm,
+			Memo:      fmt.Sprintf("payment %d", h),
+			GasWanted: 100, GasUsed: 80,
+			AuthInfo: &structs.AuthInfo{Fee: &structs.Fee{Amount: []structs.Coin{{Denom: "uatom", Amount: big.NewInt(int64(h) * 100)}}}},
+			Logs:     []structs.Log{{Events: []structs.Event{{Type: "message", Attributes: map[string]string{"sender": alice}}}}},
+			Messages: []structs.Any{{TypeURL: "/cosmos.bank.v1beta1.MsgSend", Value: send}},
+			Parties:  []string{alice, bob},
+		})
+		if h%2 == 0 {
+			bTx.Transactions = append(bTx.Transactions, structs.Transaction{
+				ChainID: "chain", Height: h, Hash: fmt.Sprintf("B%d", h), BlockHash: bTx.Block.Hash, Time: tm, Code: 5,
+				GasWanted: 50, GasUsed: 50,
+				AuthInfo: &structs.AuthInfo{Fee: &structs.Fee{Amount: []structs.Coin{{Denom: "ustake", Amount: big.NewInt(10)}, {Denom: "uatom", Amount: big.NewInt(1)}}}},
+				Logs:     []structs.Log{{Events: []structs.Event{{Type: "message", Attributes: map[string]string{"sender": bob}}}}},
+				Messages: []structs.Any{{TypeURL: "/cosmos.staking.v1beta1.MsgDelegate", Value: json.RawMessage(`{"delegator_address":"cosmos1bob"}`)}},
+				Parties:  []string{bob},
+			})
+		}
+		heights = append(heights, bTx)
+	}
+	require.NoError(t, d.StoreHeights(context.Background(), heights))
+
+	return api.NewService(d)
+}
+
+func query(t *testing.T, svc *api.Service, q string, v map[string]interface{}) string {
+	resp, err := svc.ProcessGraphqlQuery(context.Background(), []byte(q), v)
+	require.NoError(t, err, q)
+	return string(resp)
+}
+
+func TestServiceQueries(t *testing.T) {
+	svc := newIndexedService(t)
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{
+			name:     "block",
+			query:    `query Q { block(chain_id: "chain", height: 2) { hash height } }`,
+			expected: `{"block":{"hash":"BLOCK2","height":2}}`,
+		},
+		{
+			name:     "block at time",
+			query:    `query Q { blockAtTime(chain_id: "chain", time: 1627819350) { hash height } }`,
+			expected: `{"blockAtTime":{"hash":"BLOCK2","height":2}}`,
+		},
+		{
+			name:     "blocks of time range",
+			query:    `query Q { blocks(chain_id: "chain", timeFrom: 1627819380, timeTo: 1627819440) { edges { node { height } } } }`,
+			expected: `{"blocks":{"edges":[{"node":{"height":3}},{"node":{"height":4}}]}}`,
+		},
+		{
+			name:     "transactions of time range",
+			query:    `query Q { transactions(chain_id: "chain", timeFrom: 1627819440, address: "cosmos1alice") { edges { node { hash } } } }`,
+			expected: `{"transactions":{"edges":[{"node":{"hash":"A4"}},{"node":{"hash":"A5"}}]}}`,
+		},
+		{
+			name:     "transaction by hash",
+			query:    `query Q { transaction(chain_id: "chain", hash: "B4") { hash height code } }`,
+			expected: `{"transaction":[{"hash":"B4","height":4,"code":5}]}`,
+		},
+		{
+			name:     "transaction by signer and memo",
+			query:    `query Q { transaction(chain_id: "chain", signer: "cosmos1alice", memoContains: "payment 3") { hash } }`,
+			expected: `{"transaction":[{"hash":"A3"}]}`,
+		},
+		{
+			name:     "transaction by address",
+			query:    `query Q { transaction(chain_id: "chain", address: "cosmos1alice") { hash } }`,
+			expected: `{"transaction":[{"hash":"A1"},{"hash":"A2"},{"hash":"A3"},{"hash":"A4"},{"hash":"A5"}]}`,
+		},
+		{
+			name:     "transaction by address and time",
+			query:    `query Q { transaction(chain_id: "chain", address: "cosmos1bob", timeFrom: 1627819440) { hash } }`,
+			expected: `{"transaction":[{"hash":"A4"},{"hash":"B4"},{"hash":"A5"}]}`,
+		},
+		{
+			name:     "transactions of address",
+			query:    `query Q { transactions(chain_id: "chain", address: "cosmos1bob", heightFrom: 2, heightTo: 3) { edges { node { hash } } } }`,
+			expected: `{"transactions":{"edges":[{"node":{"hash":"A2"}},{"node":{"hash":"B2"}},{"node":{"hash":"A3"}}]}}`,
+		},
+		{
+			name:  "messages of module",
+			query: `query Q { messages(chain_id: "chain", module: "staking") { tx_hash type value } }`,
+			expected: `{"messages":[
+				{"tx_hash":"B2","type":"/cosmos.staking.v1beta1.MsgDelegate","value":{"delegator_address":"cosmos1bob"}},
+				{"tx_hash":"B4","type":"/cosmos.staking.v1beta1.MsgDelegate","value":{"delegator_address":"cosmos1bob"}}]}`,
+		},
+		{
+			name:     "fee of transaction",
+			query:    `query Q { transaction(chain_id: "chain", hash: "B2") { auth_info { fee { amount { denom amount } } } } }`,
+			expected: `{"transaction":[{"auth_info":{"fee":{"amount":[{"denom":"ustake","amount":"10"},{"denom":"uatom","amount":"1"}]}}}]}`,
+		},
+		{
+			name:     "unknown chain",
+			query:    `query Q { transaction(chain_id: "other", hash: "A1") { hash } }`,
+			expected: `{"transaction":[]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.JSONEq(t, tt.expected, query(t, svc, tt.query, nil))
+		})
+	}
+}
+
+func TestServicePagination(t *testing.T) {
+	svc := newIndexedService(t)
+
+	var page struct {
+		Transactions struct {
+			Edges []struct {
+				Node struct {
+					Hash string `json:"hash"`
+				} `json:"node"`
+			} `json:"edges"`
+			PageInfo struct {
+				HasNextPage bool   `json:"hasNextPage"`
+				EndCursor   string `json:"endCursor"`
+			} `json:"pageInfo"`
+		} `json:"transactions"`
+	}
+
+	var hashes []string
+	after := ""
+	for i := 0; i < 10; i++ {
+		resp := query(t, svc, `query Q($after: String) {
+			transactions(chain_id: "chain", first: 3, after: $after) { edges { node { hash } } pageInfo { hasNextPage endCursor } }
+		}`, map[string]interface{}{"after": after})
+		require.NoError(t, json.Unmarshal([]byte(resp), &page))
+
+		for _, e := range page.Transactions.Edges {
+			hashes = append(hashes, e.Node.Hash)
+		}
+		if !page.Transactions.PageInfo.HasNextPage {
+			break
+		}
+		after = page.Transactions.PageInfo.EndCursor
+	}
+
+	assert.Equal(t, []string{"A1", "A2", "B2", "A3", "A4", "B4", "A5"}, hashes)
+}
+
+func TestServiceStats(t *testing.T) {
+	svc := newIndexedService(t)
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{
+			name:  "by day",
+			query: `query Q { transactionStats(chain_id: "chain", groupBy: DAY) { time tx_count failed_tx_count message_count gas_wanted gas_used fees { denom amount } } }`,
+			expected: `{"transactionStats":[{"time":1627776000,"tx_count":7,"failed_tx_count":2,"message_count":7,"gas_wanted":600,"gas_used":500,
+				"fees":[{"denom":"uatom","amount":"1502"},{"denom":"ustake","amount":"20"}]}]}`,
+		},
+		{
+			name:     "by hour",
+			query:    `query Q { transactionStats(chain_id: "chain", groupBy: HOUR) { time tx_count } }`,
+			expected: `{"transactionStats":[{"time":1627819200,"tx_count":7}]}`,
+		},
+		{
+			name:  "by message type",
+			query: `query Q { transactionStats(chain_id: "chain", groupBy: MESSAGE_TYPE) { message_type tx_count failed_tx_count gas_used fees { denom amount } } }`,
+			expected: `{"transactionStats":[
+				{"message_type":"/cosmos.bank.v1beta1.MsgSend","tx_count":5,"failed_tx_count":0,"gas_used":400,"fees":[{"denom":"uatom","amount":"1500"}]},
+				{"message_type":"/cosmos.staking.v1beta1.MsgDelegate","tx_count":2,"failed_tx_count":2,"gas_used":100,"fees":[{"denom":"uatom","amount":"2"},{"denom":"ustake","amount":"20"}]}]}`,
+		},
+		{
+			name:  "by message type of height range",
+			query: `query Q { transactionStats(chain_id: "chain", from: 2, to: 3, groupBy: MESSAGE_TYPE) { message_type tx_count } }`,
+			expected: `{"transactionStats":[
+				{"message_type":"/cosmos.bank.v1beta1.MsgSend","tx_count":2},
+				{"message_type":"/cosmos.staking.v1beta1.MsgDelegate","tx_count":1}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.JSONEq(t, tt.expected, query(t, svc, tt.query, nil))
+		})
+	}
+
+	_, err := svc.ProcessGraphqlQuery(context.Background(), []byte(`query Q { transactionStats(chain_id: "chain", groupBy: WEEK) { tx_count } }`), nil)
+	assert.Error(t, err)
+}
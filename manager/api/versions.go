@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultQueryVersion is the version of query schema used by requests that don't specify any
+const DefaultQueryVersion = "0.0.1"
+
+var ErrUnsupportedQueryVersion = errors.New("unsupported query schema version")
+
+// QueryProcessor processes queries of a single query schema version
+type QueryProcessor interface {
+	ProcessGraphqlQuery(ctx context.Context, q []byte, v map[string]interface{}) ([]byte, error)
+}
+
+// QueryVersions routes queries to the implementation of the query schema version they're written against
+type QueryVersions struct {
+	processors map[string]QueryProcessor
+	l          sync.RWMutex
+}
+
+func NewQueryVersions() *QueryVersions {
+	return &QueryVersions{processors: make(map[string]QueryProcessor)}
+}
+
+// Add adds the implementation of given query schema version
+func (qv *QueryVersions) Add(version string, qp QueryProcessor) {
+	qv.l.Lock()
+	defer qv.l.Unlock()
+	qv.processors[version] = qp
+}
+
+// Versions returns supported query schema versions
+func (qv *QueryVersions) Versions() []string {
+	qv.l.RLock()
+	defer qv.l.RUnlock()
+
+	versions := make([]string, 0, len(qv.processors))
+	for v := range qv.processors {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// ProcessVersionedQuery processes query with the implementation of given version, DefaultQueryVersion if it's empty
+func (qv *QueryVersions) ProcessVersionedQuery(ctx context.Context, version string, q []byte, v map[string]interface{}) ([]byte, error) {
+	if version == "" {
+		version = DefaultQueryVersion
+	}
+
+	qv.l.RLock()
+	qp, ok := qv.processors[version]
+	qv.l.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w %q, supported versions: %s", ErrUnsupportedQueryVersion, version, strings.Join(qv.Versions(), ", "))
+	}
+	return qp.ProcessGraphqlQuery(ctx, q, v)
+}
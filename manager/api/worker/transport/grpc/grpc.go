@@ -0,0 +1,235 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/figment-networks/graph-demo/connectivity/auth"
+	grpcConn "github.com/figment-networks/graph-demo/connectivity/grpc"
+	"github.com/figment-networks/graph-demo/connectivity/grpc/workerpb"
+	"github.com/figment-networks/graph-demo/connectivity/handshake"
+	wsConn "github.com/figment-networks/graph-demo/connectivity/ws"
+	"github.com/figment-networks/graph-demo/manager/client"
+	"github.com/figment-networks/graph-demo/manager/scheduler"
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	cliTr "github.com/figment-networks/graph-demo/manager/client/transport/grpc"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type ManagerService interface {
+	StoreBlock(ctx context.Context, block structs.Block) error
+	StoreTransactions(ctx context.Context, txs []structs.Transaction) error
+}
+
+// Server serves worker streams, it's the gRPC counterpart of the websocket ProcessHandler
+type Server struct {
+	workerpb.UnimplementedManagerServer
+
+	service ManagerService
+	log     *zap.Logger
+	sched   *scheduler.Scheduler
+	authn   auth.Authenticator
+
+	// hello of manager and the methods required from workers, checked against the hello headers of the stream
+	hello    handshake.Hello
+	required []string
+	// allowLegacy accepts streams of workers that don't send hello headers
+	allowLegacy bool
+
+	// session storage, shared with websocket sessions
+	reg *wsConn.Registry
+}
+
+func NewServer(log *zap.Logger, svc ManagerService, sched *scheduler.Scheduler, reg *wsConn.Registry, authn auth.Authenticator, hello handshake.Hello, required []string) *Server {
+	return &Server{
+		log:      log,
+		service:  svc,
+		sched:    sched,
+		reg:      reg,
+		authn:    authn,
+		hello:    hello,
+		required: required,
+	}
+}
+
+// SetAllowLegacy sets if the streams of workers not sending hello headers are accepted, they're refused by default
+func (s *Server) SetAllowLegacy(allow bool) {
+	s.allowLegacy = allow
+}
+
+// Connect serves the stream of a single worker until it's closed
+func (s *Server) Connect(stream workerpb.Manager_ConnectServer) error {
+	r := grpcConn.RequestFromMetadata(stream.Context())
+	id, err := s.authn.Authenticate(r)
+	if err != nil {
+		s.log.Warn("Error authenticating stream", zap.Error(err))
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	if id.Role != "" && id.Role != wsConn.RoleWorker && id.Role != wsConn.RoleAdmin {
+		s.log.Warn("Stream role not allowed", zap.String("name", id.Name), zap.String("role", id.Role))
+		return status.Error(codes.PermissionDenied, auth.ErrForbidden.Error())
+	}
+
+	// streams have no handshake call, worker describes itself in the headers.
+	// Workers not sending them are accepted only if legacy workers are allowed.
+	meta := map[string]string{"transport": "grpc"}
+	remote, ok := handshake.FromHeader(r.Header)
+	if !ok && !s.allowLegacy {
+		s.log.Warn("Worker skipped the handshake", zap.String("name", id.Name))
+		return status.Error(codes.FailedPrecondition, handshake.ErrHandshakeRequired.Error())
+	}
+	if ok {
+		if _, err := handshake.Check(s.hello, remote, s.required); err != nil {
+			s.log.Warn("Incompatible worker", zap.String("name", id.Name), zap.Error(err))
+			return status.Error(codes.FailedPrecondition, err.Error())
+		}
+		meta["component"] = remote.Component
+		meta["version"] = remote.Version
+		meta["protocol"] = remote.Protocol
+	}
+
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	reg := first.GetRegister()
+	if reg == nil {
+		return status.Error(codes.InvalidArgument, "first message has to be register")
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	ws := &workerStream{ID: uuid.NewString(), stream: stream, pending: grpcConn.NewPending()}
+	defer ws.pending.Close(nil)
+
+	s.reg.AddInfo(wsConn.SessionInfo{
+		ID:       ws.ID,
+		Role:     wsConn.RoleWorker,
+		Name:     id.Name,
+		ChainIDs: id.ChainIDs,
+		Metadata: meta,
+	})
+	// removal of the session removes the worker from scheduler
+	defer s.reg.Remove(ws.ID)
+
+	s.register(ctx, ws, workerpb.RegisterFromPB(reg))
+	if err := ws.send(&workerpb.ManagerMessage{Id: first.GetId(), Body: &workerpb.ManagerMessage_Result{Result: &workerpb.Result{}}}); err != nil {
+		return err
+	}
+
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			s.log.Debug("worker stream closed", zap.String("id", ws.ID), zap.Error(err))
+			ws.pending.Close(err)
+			return nil
+		}
+
+		switch b := msg.GetBody().(type) {
+		case *workerpb.WorkerMessage_Result:
+			if !ws.pending.Resolve(msg.GetId(), b.Result) {
+				s.log.Error("unexpected result", zap.String("id", ws.ID), zap.Uint64("request_id", msg.GetId()))
+			}
+		// stores run outside of the loop, so the slow ones don't hold the results of the other calls
+		case *workerpb.WorkerMessage_StoreBlock:
+			id, block := msg.GetId(), workerpb.BlockFromPB(b.StoreBlock)
+			go func() { s.reply(ws, id, s.storeBlock(ctx, ws.ID, block)) }()
+		case *workerpb.WorkerMessage_StoreTransactions:
+			id, txs := msg.GetId(), workerpb.TransactionsFromPB(b.StoreTransactions.GetTransactions())
+			go func() { s.reply(ws, id, s.storeTransactions(ctx, ws.ID, txs)) }()
+		default:
+			s.reply(ws, msg.GetId(), status.Error(codes.InvalidArgument, "unexpected message"))
+		}
+	}
+}
+
+// register adds worker to the scheduler for all the allowed chains it supports
+func (s *Server) register(ctx context.Context, ws *workerStream, reg structs.Register) {
+	if reg.Name != "" {
+		s.reg.SetMetadata(ws.ID, "name", reg.Name)
+	}
+
+	chains := make([]string, 0, len(reg.Chains))
+	for _, ch := range reg.Chains {
+		if !s.reg.AllowsChain(ws.ID, ch.ChainID) {
+			s.log.Warn("worker is not allowed to register chain", zap.String("conn_id", ws.ID), zap.String("chain_id", ch.ChainID))
+			continue
+		}
+		if !ch.Supports(structs.CapabilityGetAll, structs.CapabilityGetLatest) {
+			s.log.Warn("worker chain lacks required capabilities", zap.String("conn_id", ws.ID), zap.String("chain_id", ch.ChainID), zap.Strings("capabilities", ch.Capabilities))
+			continue
+		}
+		nc := client.NewBreakerClient(cliTr.NewCosmosGRPCTransport(ws, ch.ChainID), client.DefaultBreakerConfig)
+		s.sched.AddWorker(ctx, nc, ws.ID, ch.ChainID)
+		chains = append(chains, ch.ChainID)
+	}
+	s.reg.SetMetadata(ws.ID, "chains", strings.Join(chains, ","))
+}
+
+func (s *Server) storeBlock(ctx context.Context, connID string, block structs.Block) error {
+	if !s.reg.AllowsChain(connID, block.ChainID) {
+		return auth.ErrUnauthorized
+	}
+	return s.service.StoreBlock(ctx, block)
+}
+
+func (s *Server) storeTransactions(ctx context.Context, connID string, txs []structs.Transaction) error {
+	for _, tx := range txs {
+		if !s.reg.AllowsChain(connID, tx.ChainID) {
+			return auth.ErrUnauthorized
+		}
+	}
+	return s.service.StoreTransactions(ctx, txs)
+}
+
+func (s *Server) reply(ws *workerStream, id uint64, err error) {
+	res := &workerpb.Result{}
+	if err != nil {
+		res = workerpb.ErrorResult(err)
+	}
+
+	if err := ws.send(&workerpb.ManagerMessage{Id: id, Body: &workerpb.ManagerMessage_Result{Result: res}}); err != nil {
+		s.log.Error("error sending result", zap.String("id", ws.ID), zap.Error(err))
+	}
+}
+
+// workerStream sends requests to the worker, it implements Caller of the gRPC NetworkClient transport
+type workerStream struct {
+	ID string
+
+	stream  workerpb.Manager_ConnectServer
+	sl      sync.Mutex
+	pending *grpcConn.Pending
+}
+
+func (ws *workerStream) Call(ctx context.Context, msg *workerpb.ManagerMessage) (*workerpb.Result, error) {
+	method := "get_all"
+	if msg.GetGetLatest() != nil {
+		method = "get_latest"
+	}
+
+	return ws.pending.Call(ctx, method, func(id uint64) error {
+		msg.Id = id
+		return ws.send(msg)
+	})
+}
+
+// Err returns the reason the stream was closed with, nil if it's still open
+func (ws *workerStream) Err() error {
+	return ws.pending.Err()
+}
+
+// send sends the message, stream doesn't allow concurrent sends
+func (ws *workerStream) send(msg *workerpb.ManagerMessage) error {
+	ws.sl.Lock()
+	defer ws.sl.Unlock()
+	return ws.stream.Send(msg)
+}
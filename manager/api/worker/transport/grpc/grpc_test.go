@@ -0,0 +1,172 @@
+package grpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/figment-networks/graph-demo/connectivity/auth"
+	"github.com/figment-networks/graph-demo/connectivity/grpc/workerpb"
+	"github.com/figment-networks/graph-demo/connectivity/handshake"
+	wsConn "github.com/figment-networks/graph-demo/connectivity/ws"
+	workerGRPC "github.com/figment-networks/graph-demo/cosmos-worker/api/transport/grpc"
+	managerGRPC "github.com/figment-networks/graph-demo/manager/api/worker/transport/grpc"
+	"github.com/figment-networks/graph-demo/manager/client"
+	"github.com/figment-networks/graph-demo/manager/scheduler"
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const chainID = "cosmoshub-4"
+
+// serviceMock records the data stored by the worker
+type serviceMock struct {
+	blocks chan structs.Block
+	txs    chan []structs.Transaction
+}
+
+func (sm *serviceMock) StoreBlock(ctx context.Context, block structs.Block) error {
+	sm.blocks <- block
+	return nil
+}
+
+func (sm *serviceMock) StoreTransactions(ctx context.Context, txs []structs.Transaction) error {
+	sm.txs <- txs
+	return nil
+}
+
+// clienterMock gets the heights from the worker, reporting the processed ones
+type clienterMock struct {
+	processed chan uint64
+}
+
+func (cm *clienterMock) ProcessHeight(ctx context.Context, nc client.NetworkClient, chainID string, height uint64) error {
+	if err := nc.GetAll(ctx, height); err != nil {
+		return err
+	}
+	cm.processed <- height
+	return nil
+}
+
+func (cm *clienterMock) FetchHeight(ctx context.Context, nc client.NetworkClient, chainID string, height uint64) error {
+	return cm.ProcessHeight(ctx, nc, chainID, height)
+}
+
+func (cm *clienterMock) Flush(ctx context.Context) error { return nil }
+
+func (cm *clienterMock) GetLatest(ctx context.Context, nc client.NetworkClient) (uint64, error) {
+	return nc.GetLatest(ctx)
+}
+
+func (cm *clienterMock) GetLatestFromStorage(ctx context.Context, chainID string) (uint64, error) {
+	return 0, nil
+}
+
+func (cm *clienterMock) SetLatestFromStorage(ctx context.Context, chainID string, height uint64) error {
+	return nil
+}
+
+// persistor stores the data of the worker, like the manager connection of the worker
+type persistor interface {
+	StoreBlock(ctx context.Context, block structs.Block) error
+	StoreTransactions(ctx context.Context, txs []structs.Transaction) error
+}
+
+// cosmosMock stores the data of the height through the persistor, like the cosmos client of the worker does
+type cosmosMock struct {
+	p persistor
+}
+
+func (cm *cosmosMock) GetAll(ctx context.Context, height uint64) error {
+	block := structs.Block{ChainID: chainID, Height: height, Hash: "BLOCK1"}
+	if err := cm.p.StoreBlock(ctx, block); err != nil {
+		return err
+	}
+	return cm.p.StoreTransactions(ctx, []structs.Transaction{{ChainID: chainID, Height: height, Hash: "TX1", BlockHash: block.Hash}})
+}
+
+func (cm *cosmosMock) GetLatest(ctx context.Context) (uint64, error) {
+	return 1, nil
+}
+
+func TestRoundTrip(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	svc := &serviceMock{blocks: make(chan structs.Block, 1), txs: make(chan []structs.Transaction, 1)}
+	cm := &clienterMock{processed: make(chan uint64, 1)}
+	sched := scheduler.NewScheduler(ctx, zap.NewNop(), cm, nil, 1)
+	hello := handshake.Hello{Component: "manager", Protocol: handshake.ProtocolVersion}
+
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	workerpb.RegisterManagerServer(gs, managerGRPC.NewServer(zap.NewNop(), svc, sched, wsConn.NewRegistry(), auth.Open{}, hello, []string{"get_all", "get_latest"}))
+	go gs.Serve(lis)
+	defer gs.Stop()
+
+	cosmos := &cosmosMock{}
+	ph := workerGRPC.NewProcessHandler(zap.NewNop(), map[string]workerGRPC.CosmosClient{chainID: cosmos})
+	cosmos.p = ph
+
+	ph.SetDialOptions(grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	require.NoError(t, ph.Connect(ctx, "bufnet", "worker-1", wsConn.DialOptions{}))
+
+	// get_all -> store_block -> store_transactions -> result of get_all
+	select {
+	case block := <-svc.blocks:
+		assert.Equal(t, uint64(1), block.Height)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for block")
+	}
+
+	select {
+	case txs := <-svc.txs:
+		require.Len(t, txs, 1)
+		assert.Equal(t, "TX1", txs[0].Hash)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for transactions")
+	}
+
+	select {
+	case h := <-cm.processed:
+		assert.Equal(t, uint64(1), h)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for processed height")
+	}
+}
+
+func TestRefusesWorkerWithoutHandshake(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sched := scheduler.NewScheduler(ctx, zap.NewNop(), &clienterMock{}, nil, 1)
+	srv := managerGRPC.NewServer(zap.NewNop(), &serviceMock{}, sched, wsConn.NewRegistry(), auth.Open{}, handshake.Hello{Protocol: handshake.ProtocolVersion}, nil)
+
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	workerpb.RegisterManagerServer(gs, srv)
+	go gs.Serve(lis)
+	defer gs.Stop()
+
+	cc, err := grpc.DialContext(ctx, "bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	require.NoError(t, err)
+	defer cc.Close()
+
+	// stream without hello headers
+	stream, err := workerpb.NewManagerClient(cc).Connect(ctx)
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
@@ -4,9 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"strings"
 	"sync"
 
 	"github.com/figment-networks/graph-demo/connectivity"
+	"github.com/figment-networks/graph-demo/connectivity/auth"
+	"github.com/figment-networks/graph-demo/manager/client"
 	"github.com/figment-networks/graph-demo/manager/scheduler"
 	"github.com/figment-networks/graph-demo/manager/structs"
 
@@ -78,23 +82,18 @@ func (ph *ProcessHandler) StoreBlock(ctx context.Context, req connectivity.Reque
 	enc := json.NewEncoder(b)
 	r := JSONGraphQLResponse{}
 
-	args := req.Arguments()
-	if len(args) == 0 {
+	var block structs.Block
+	if err := req.Decode(0, &block); err != nil {
 		r.Errors = append(r.Errors, ErrorMessage{
-			Message: "Missing args (GraphQLRequest)",
+			Message: "Error unmarshaling query " + err.Error(),
 		})
 		enc.Encode(r)
 		resp.Send(json.RawMessage(b.Bytes()), nil)
 		return
 	}
 
-	var block structs.Block
-	if err := json.Unmarshal(args[0], &block); err != nil {
-		r.Errors = append(r.Errors, ErrorMessage{
-			Message: "Error unmarshaling query " + err.Error(),
-		})
-		enc.Encode(r)
-		resp.Send(json.RawMessage(b.Bytes()), nil)
+	if !ph.reg.AllowsChain(req.ConnID(), block.ChainID) {
+		resp.Send(nil, auth.ErrUnauthorized)
 		return
 	}
 
@@ -115,18 +114,8 @@ func (ph *ProcessHandler) StoreTransactions(ctx context.Context, req connectivit
 	enc := json.NewEncoder(b)
 	r := JSONGraphQLResponse{}
 
-	args := req.Arguments()
-	if len(args) == 0 {
-		r.Errors = append(r.Errors, ErrorMessage{
-			Message: "Missing args (StoreTransactions)",
-		})
-		enc.Encode(r)
-		resp.Send(json.RawMessage(b.Bytes()), nil)
-		return
-	}
-
 	var txs []structs.Transaction
-	if err := json.Unmarshal(args[0], &txs); err != nil {
+	if err := req.Decode(0, &txs); err != nil {
 		r.Errors = append(r.Errors, ErrorMessage{
 			Message: "Error unmarshaling query " + err.Error(),
 		})
@@ -135,6 +124,13 @@ func (ph *ProcessHandler) StoreTransactions(ctx context.Context, req connectivit
 		return
 	}
 
+	for _, tx := range txs {
+		if !ph.reg.AllowsChain(req.ConnID(), tx.ChainID) {
+			resp.Send(nil, auth.ErrUnauthorized)
+			return
+		}
+	}
+
 	if err := ph.service.StoreTransactions(ctx, txs); err != nil {
 		r.Errors = append(r.Errors, ErrorMessage{
 			Message: "Error storing block " + err.Error(),
@@ -165,8 +161,8 @@ func (ph *ProcessHandler) Register(ctx context.Context, req connectivity.Request
 		return
 	}
 
-	var chainID string
-	if err := json.Unmarshal(args[0], &chainID); err != nil {
+	reg, err := parseRegister(args[0])
+	if err != nil {
 		r.Errors = append(r.Errors, ErrorMessage{
 			Message: "Error unmarshaling query " + err.Error(),
 		})
@@ -185,8 +181,47 @@ func (ph *ProcessHandler) Register(ctx context.Context, req connectivity.Request
 		return
 	}
 
-	go ph.sched.Start(ctx, cliTr.NewCosmosWSTransport(ss), req.ConnID(), chainID)
+	if reg.Name != "" {
+		ph.reg.SetMetadata(req.ConnID(), "name", reg.Name)
+	}
+
+	chains := make([]string, 0, len(reg.Chains))
+	for _, ch := range reg.Chains {
+		if !ph.reg.AllowsChain(req.ConnID(), ch.ChainID) {
+			ph.log.Warn("worker is not allowed to register chain", zap.String("conn_id", req.ConnID()), zap.String("chain_id", ch.ChainID))
+			continue
+		}
+		if !ch.Supports(structs.CapabilityGetAll, structs.CapabilityGetLatest) {
+			ph.log.Warn("worker chain lacks required capabilities", zap.String("conn_id", req.ConnID()), zap.String("chain_id", ch.ChainID), zap.Strings("capabilities", ch.Capabilities))
+			continue
+		}
+		nc := client.NewBreakerClient(cliTr.NewCosmosWSTransport(ss, ch.ChainID), client.DefaultBreakerConfig)
+		ph.sched.AddWorker(ctx, nc, req.ConnID(), ch.ChainID)
+		chains = append(chains, ch.ChainID)
+	}
+	ph.reg.SetMetadata(req.ConnID(), "chains", strings.Join(chains, ","))
 
 	resp.Send(json.RawMessage([]byte(`"ACK"`)), nil)
 
 }
+
+// parseRegister reads the registration of the worker. Older workers send just the chain id,
+// that is treated as a single chain supporting all capabilities.
+func parseRegister(arg json.RawMessage) (reg structs.Register, err error) {
+	var chainID string
+	if err := json.Unmarshal(arg, &chainID); err == nil {
+		return structs.Register{Chains: []structs.RegisterChain{{
+			ChainID:      chainID,
+			Capabilities: []string{structs.CapabilityGetAll, structs.CapabilityGetLatest},
+		}}}, nil
+	}
+
+	if err := json.Unmarshal(arg, &reg); err != nil {
+		return reg, err
+	}
+
+	if len(reg.Chains) == 0 {
+		return reg, errors.New("no chains to register")
+	}
+	return reg, nil
+}
@@ -0,0 +1,185 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/figment-networks/graph-demo/manager/store"
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrEmptyChainID   = errors.New("empty chain_id")
+	ErrInvalidRange   = errors.New("invalid range, expected 0 < from <= to")
+	ErrInvalidStatus  = errors.New("operation not allowed in current job status")
+	ErrJobDoesntExist = errors.New("job does not exist")
+)
+
+type Scheduler interface {
+	AddJob(j structs.BackfillJob)
+	RemoveJob(chainID string, id uuid.UUID)
+}
+
+// Jobs manages backfill jobs. Jobs are persisted in the store and executed by the scheduler
+// through the workers of the chain, alongside live sync.
+type Jobs struct {
+	log   *zap.Logger
+	st    store.Storager
+	sched Scheduler
+
+	// l serializes job updates, so the progress reported by the scheduler
+	// does not override the status changed by the user
+	l sync.Mutex
+}
+
+func NewJobs(log *zap.Logger, st store.Storager, sched Scheduler) *Jobs {
+	return &Jobs{
+		log:   log,
+		st:    st,
+		sched: sched,
+	}
+}
+
+// Restore resumes processing of all the jobs that were running before the restart
+func (j *Jobs) Restore(ctx context.Context) error {
+	jobs, err := j.st.GetJobs(ctx, "", structs.JobStatusRunning)
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		j.log.Info("restoring backfill job", zap.Stringer("id", job.ID), zap.String("chain_id", job.ChainID), zap.Uint64("current", job.Current), zap.Uint64("to", job.To))
+		j.sched.AddJob(job)
+	}
+	return nil
+}
+
+func (j *Jobs) Create(ctx context.Context, chainID string, from, to uint64) (structs.BackfillJob, error) {
+	if chainID == "" {
+		return structs.BackfillJob{}, ErrEmptyChainID
+	}
+
+	if from == 0 || from > to {
+		return structs.BackfillJob{}, ErrInvalidRange
+	}
+
+	job, err := j.st.CreateJob(ctx, structs.BackfillJob{
+		ChainID: chainID,
+		From:    from,
+		To:      to,
+		Current: from - 1,
+		Status:  structs.JobStatusRunning,
+	})
+	if err != nil {
+		return job, err
+	}
+
+	j.sched.AddJob(job)
+	return job, nil
+}
+
+func (j *Jobs) List(ctx context.Context, chainID string) ([]structs.BackfillJob, error) {
+	return j.st.GetJobs(ctx, chainID)
+}
+
+func (j *Jobs) Get(ctx context.Context, id uuid.UUID) (structs.BackfillJob, error) {
+	job, err := j.st.GetJob(ctx, id)
+	if err == structs.ErrNotFound {
+		return job, ErrJobDoesntExist
+	}
+	return job, err
+}
+
+func (j *Jobs) Pause(ctx context.Context, id uuid.UUID) (structs.BackfillJob, error) {
+	return j.changeStatus(ctx, id, structs.JobStatusPaused, structs.JobStatusRunning)
+}
+
+func (j *Jobs) Resume(ctx context.Context, id uuid.UUID) (structs.BackfillJob, error) {
+	return j.changeStatus(ctx, id, structs.JobStatusRunning, structs.JobStatusPaused)
+}
+
+func (j *Jobs) Cancel(ctx context.Context, id uuid.UUID) (structs.BackfillJob, error) {
+	return j.changeStatus(ctx, id, structs.JobStatusCancelled, structs.JobStatusRunning, structs.JobStatusPaused)
+}
+
+func (j *Jobs) changeStatus(ctx context.Context, id uuid.UUID, status structs.JobStatus, allowedFrom ...structs.JobStatus) (structs.BackfillJob, error) {
+	j.l.Lock()
+	defer j.l.Unlock()
+
+	job, err := j.Get(ctx, id)
+	if err != nil {
+		return job, err
+	}
+
+	var allowed bool
+	for _, s := range allowedFrom {
+		if job.Status == s {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return job, fmt.Errorf("%w: %s", ErrInvalidStatus, job.Status)
+	}
+
+	job.Status = status
+	if err := j.st.UpdateJob(ctx, job); err != nil {
+		return job, err
+	}
+
+	if status == structs.JobStatusRunning {
+		j.sched.AddJob(job)
+	} else {
+		j.sched.RemoveJob(job.ChainID, job.ID)
+	}
+
+	return job, nil
+}
+
+// JobProgress persists the height up to which job is processed, marking job as finished when it's done
+func (j *Jobs) JobProgress(ctx context.Context, id uuid.UUID, current uint64) error {
+	j.l.Lock()
+	defer j.l.Unlock()
+
+	job, err := j.st.GetJob(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if job.Status != structs.JobStatusRunning {
+		return nil
+	}
+
+	job.Current = current
+	if job.Current >= job.To {
+		job.Status = structs.JobStatusFinished
+		j.log.Info("backfill job finished", zap.Stringer("id", job.ID), zap.String("chain_id", job.ChainID))
+	}
+
+	return j.st.UpdateJob(ctx, job)
+}
+
+// JobError records the error of processing given height
+func (j *Jobs) JobError(ctx context.Context, id uuid.UUID, height uint64, er error) error {
+	j.l.Lock()
+	defer j.l.Unlock()
+
+	job, err := j.st.GetJob(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if job.Status != structs.JobStatusRunning {
+		return nil
+	}
+
+	job.LastError = fmt.Sprintf("height %d: %s", height, er.Error())
+	job.ErrorCount++
+
+	return j.st.UpdateJob(ctx, job)
+}
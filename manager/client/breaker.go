@@ -0,0 +1,107 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type BreakerConfig struct {
+	// Threshold - number of consecutive failures that opens the circuit
+	Threshold int
+	// Cooldown - time the circuit stays open before a single probe call is let through
+	Cooldown time.Duration
+}
+
+var DefaultBreakerConfig = BreakerConfig{
+	Threshold: 5,
+	Cooldown:  30 * time.Second,
+}
+
+// BreakerClient is NetworkClient guarded by a circuit breaker.
+// It's meant to wrap the transport of a single worker session.
+type BreakerClient struct {
+	nc  NetworkClient
+	cfg BreakerConfig
+
+	failures  int
+	openUntil time.Time
+	probing   bool
+	l         sync.Mutex
+}
+
+func NewBreakerClient(nc NetworkClient, cfg BreakerConfig) *BreakerClient {
+	return &BreakerClient{nc: nc, cfg: cfg}
+}
+
+func (bc *BreakerClient) GetAll(ctx context.Context, height uint64) error {
+	if err := bc.allow(); err != nil {
+		return err
+	}
+
+	err := bc.nc.GetAll(ctx, height)
+	bc.record(err)
+	return err
+}
+
+func (bc *BreakerClient) GetLatest(ctx context.Context) (uint64, error) {
+	if err := bc.allow(); err != nil {
+		return 0, err
+	}
+
+	h, err := bc.nc.GetLatest(ctx)
+	bc.record(err)
+	return h, err
+}
+
+// OpenUntil returns the time until the circuit stays open
+func (bc *BreakerClient) OpenUntil() time.Time {
+	bc.l.Lock()
+	defer bc.l.Unlock()
+	return bc.openUntil
+}
+
+// Closed reports whether the session of the worker is closed, transports without sessions are never closed
+func (bc *BreakerClient) Closed() bool {
+	c, ok := bc.nc.(interface{ Closed() bool })
+	return ok && c.Closed()
+}
+
+func (bc *BreakerClient) allow() error {
+	bc.l.Lock()
+	defer bc.l.Unlock()
+
+	if bc.failures < bc.cfg.Threshold {
+		return nil
+	}
+
+	if time.Now().Before(bc.openUntil) || bc.probing {
+		return fmt.Errorf("%w until %s", ErrCircuitOpen, bc.openUntil.Format(time.RFC3339))
+	}
+
+	// half-open, let a single call through
+	bc.probing = true
+	return nil
+}
+
+func (bc *BreakerClient) record(err error) {
+	bc.l.Lock()
+	defer bc.l.Unlock()
+
+	bc.probing = false
+
+	// height that is not there yet is not a fault of the worker
+	if err == nil || Classify(err) == ClassHeightNotAvailable {
+		bc.failures = 0
+		return
+	}
+
+	bc.failures++
+	if bc.failures >= bc.cfg.Threshold {
+		bc.openUntil = time.Now().Add(bc.cfg.Cooldown)
+	}
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ncMock returns err from every call, counting the calls that reached it
+type ncMock struct {
+	err    error
+	calls  int
+	closed bool
+}
+
+func (nm *ncMock) GetAll(ctx context.Context, height uint64) error {
+	nm.calls++
+	return nm.err
+}
+
+func (nm *ncMock) GetLatest(ctx context.Context) (uint64, error) {
+	nm.calls++
+	return 0, nm.err
+}
+
+func (nm *ncMock) Closed() bool {
+	return nm.closed
+}
+
+func TestBreakerTransitions(t *testing.T) {
+	errBroken := errors.New("broken")
+	nm := &ncMock{err: errBroken}
+	bc := NewBreakerClient(nm, BreakerConfig{Threshold: 3, Cooldown: 50 * time.Millisecond})
+	ctx := context.Background()
+
+	// closed, failures below threshold go through
+	for i := 0; i < 3; i++ {
+		require.ErrorIs(t, bc.GetAll(ctx, 1), errBroken)
+	}
+	assert.Equal(t, 3, nm.calls)
+
+	// open, calls are rejected without reaching the worker
+	err := bc.GetAll(ctx, 1)
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, ClassCircuitOpen, Classify(err))
+	assert.Equal(t, 3, nm.calls)
+	assert.True(t, bc.OpenUntil().After(time.Now()))
+
+	// half-open, failed probe opens the circuit again
+	time.Sleep(60 * time.Millisecond)
+	require.ErrorIs(t, bc.GetAll(ctx, 1), errBroken)
+	assert.Equal(t, 4, nm.calls)
+	require.ErrorIs(t, bc.GetAll(ctx, 1), ErrCircuitOpen)
+	assert.Equal(t, 4, nm.calls)
+
+	// half-open, successful probe closes the circuit
+	time.Sleep(60 * time.Millisecond)
+	nm.err = nil
+	_, err = bc.GetLatest(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 5, nm.calls)
+
+	// closed, failures are counted from zero
+	nm.err = errBroken
+	for i := 0; i < 2; i++ {
+		require.ErrorIs(t, bc.GetAll(ctx, 1), errBroken)
+	}
+	nm.err = nil
+	require.NoError(t, bc.GetAll(ctx, 1))
+	assert.Equal(t, 8, nm.calls)
+}
+
+func TestBreakerLetsSingleProbeThrough(t *testing.T) {
+	nm := &ncMock{err: errors.New("broken")}
+	bc := NewBreakerClient(nm, BreakerConfig{Threshold: 1, Cooldown: time.Millisecond})
+
+	require.Error(t, bc.GetAll(context.Background(), 1))
+	time.Sleep(5 * time.Millisecond)
+
+	// probe in flight, the other calls are rejected
+	require.NoError(t, bc.allow())
+	require.ErrorIs(t, bc.allow(), ErrCircuitOpen)
+
+	bc.record(nil)
+	require.NoError(t, bc.allow())
+}
+
+func TestBreakerIgnoresHeightNotAvailable(t *testing.T) {
+	nm := &ncMock{err: structs.ErrHeightNotAvailable}
+	bc := NewBreakerClient(nm, BreakerConfig{Threshold: 2, Cooldown: time.Minute})
+
+	for i := 0; i < 5; i++ {
+		require.ErrorIs(t, bc.GetAll(context.Background(), 100), structs.ErrHeightNotAvailable)
+	}
+	assert.Equal(t, 5, nm.calls, "circuit stays closed")
+}
+
+func TestBreakerClosed(t *testing.T) {
+	nm := &ncMock{}
+	bc := NewBreakerClient(nm, DefaultBreakerConfig)
+	assert.False(t, bc.Closed())
+
+	nm.closed = true
+	assert.True(t, bc.Closed())
+}
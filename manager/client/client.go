@@ -6,6 +6,7 @@ import (
 
 	"github.com/figment-networks/graph-demo/manager/store"
 	"github.com/figment-networks/graph-demo/manager/structs"
+	"github.com/figment-networks/graph-demo/manager/subscription"
 	"go.uber.org/zap"
 )
 
@@ -15,61 +16,124 @@ type NetworkClient interface {
 }
 
 type SubscriptionClient interface {
-	PopulateEvent(ctx context.Context, event string, height uint64, data interface{}) error
+	PopulateEvent(ctx context.Context, event, chainID string, height uint64, data interface{}) error
+	PopulateBatch(ctx context.Context, chainID string, height uint64, evts []subscription.Evt) error
+}
+
+// HeightWriter writes the data stored by worker while it fetched the height, once fetching succeeds
+type HeightWriter interface {
+	Commit(ctx context.Context, chainID string, height uint64, flush bool) error
+	Discard(chainID string, height uint64)
+	Flush(ctx context.Context) error
 }
 
 type Client struct {
 	sc SubscriptionClient
 	l  *zap.Logger
 	st store.Storager
+	hw HeightWriter
+
+	retryPolicies map[ErrorClass]RetryPolicy
 }
 
 func NewClient(l *zap.Logger, st store.Storager, sc SubscriptionClient) *Client {
 	return &Client{
-		l:  l,
-		st: st,
-		sc: sc,
+		l:             l,
+		st:            st,
+		sc:            sc,
+		retryPolicies: DefaultRetryPolicies,
 	}
 }
 
-func (c *Client) ProcessHeight(ctx context.Context, nc NetworkClient, height uint64) error {
-	if err := c.getByHeight(ctx, nc, height); err != nil {
+// LinkWriter sets the writer of fetched heights. Without it worker stores the data directly.
+func (c *Client) LinkWriter(hw HeightWriter) {
+	c.hw = hw
+}
+
+func (c *Client) ProcessHeight(ctx context.Context, nc NetworkClient, chainID string, height uint64) error {
+	if err := c.getByHeight(ctx, nc, chainID, height); err != nil {
 		return err
 	}
 
-	// We can populate some errors from here
-	if err := c.PopulateEvent(ctx, structs.EVENT_NEW_BLOCK, height, structs.EventNewBlock{
-		Height: height,
-	}); err != nil {
-		return err
+	// events make subscribers query the height, so it's written right away
+	if c.hw != nil {
+		if err := c.hw.Commit(ctx, chainID, height, true); err != nil {
+			return err
+		}
 	}
 
-	txs, err := c.st.GetTransactionsByParam(ctx, "cosmoshub-4", "height", height)
+	txs, err := c.st.FindTransactions(ctx, chainID, structs.TxFilter{
+		Conditions: []structs.TxCondition{{Field: structs.TxFieldHeight, Op: structs.OpEq, Value: height}},
+	})
 	if err != nil {
 		return err
 	}
 
+	// all events of the block are populated at once
+	evts := make([]subscription.Evt, 0, len(txs)+1)
+	evts = append(evts, subscription.Evt{EvType: structs.EVENT_NEW_BLOCK, Data: structs.EventNewBlock{
+		ChainID: chainID,
+		Height:  height,
+	}})
 	for _, tx := range txs {
-		if err := c.PopulateEvent(ctx, structs.EVENT_NEW_TRANSACTION, height, structs.EventNewTransaction{
-			Hash:   tx.Hash,
-			Height: height,
-		}); err != nil {
-			return err
-		}
+		evts = append(evts, subscription.Evt{EvType: structs.EVENT_NEW_TRANSACTION, Data: structs.EventNewTransaction{
+			ChainID: chainID,
+			Hash:    tx.Hash,
+			Height:  height,
+		}})
 	}
 
+	return c.PopulateBatch(ctx, chainID, height, evts)
+}
+
+// FetchHeight makes worker fetch and store data of given height without populating any events.
+// It's used for backfilling of already processed heights, their data may be written in batches
+// so Flush has to be called before the heights are reported as done.
+func (c *Client) FetchHeight(ctx context.Context, nc NetworkClient, chainID string, height uint64) error {
+	if err := c.getByHeight(ctx, nc, chainID, height); err != nil {
+		return err
+	}
+
+	if c.hw != nil {
+		return c.hw.Commit(ctx, chainID, height, false)
+	}
 	return nil
 }
 
-func (c *Client) PopulateEvent(ctx context.Context, event string, height uint64, data interface{}) error {
+// Flush writes the heights fetched so far
+func (c *Client) Flush(ctx context.Context) error {
+	if c.hw != nil {
+		return c.hw.Flush(ctx)
+	}
+	return nil
+}
+
+func (c *Client) PopulateEvent(ctx context.Context, event, chainID string, height uint64, data interface{}) error {
 	if c.sc == nil {
 		return errors.New("there is now subscription client linked")
 	}
-	return c.sc.PopulateEvent(ctx, event, height, data)
+	return c.sc.PopulateEvent(ctx, event, chainID, height, data)
 }
 
-func (c *Client) getByHeight(ctx context.Context, nc NetworkClient, height uint64) error {
-	return nc.GetAll(ctx, height)
+func (c *Client) PopulateBatch(ctx context.Context, chainID string, height uint64, evts []subscription.Evt) error {
+	if c.sc == nil {
+		return errors.New("there is now subscription client linked")
+	}
+	return c.sc.PopulateBatch(ctx, chainID, height, evts)
+}
+
+// getByHeight makes worker fetch given height, retrying according to the class of the error
+func (c *Client) getByHeight(ctx context.Context, nc NetworkClient, chainID string, height uint64) error {
+	return retry(ctx, c.retryPolicies, func() error {
+		err := nc.GetAll(ctx, height)
+		if err != nil {
+			if c.hw != nil {
+				c.hw.Discard(chainID, height)
+			}
+			c.l.Debug("error getting height", zap.Uint64("height", height), zap.String("class", string(Classify(err))), zap.Error(err))
+		}
+		return err
+	})
 }
 
 func (c *Client) GetLatest(ctx context.Context, nc NetworkClient) (uint64, error) {
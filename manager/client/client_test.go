@@ -0,0 +1,156 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/figment-networks/graph-demo/manager/api"
+	"github.com/figment-networks/graph-demo/manager/client"
+	"github.com/figment-networks/graph-demo/manager/store"
+	"github.com/figment-networks/graph-demo/manager/store/memory"
+	"github.com/figment-networks/graph-demo/manager/structs"
+	"github.com/figment-networks/graph-demo/manager/subscription"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// workerMock stores the data of the height through manager's service, like the worker connected to manager does
+type workerMock struct {
+	chainID string
+	svc     *api.Service
+
+	// failAfterBlock makes worker fail after storing the block
+	failAfterBlock bool
+}
+
+func (wm *workerMock) GetAll(ctx context.Context, height uint64) error {
+	now := time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC)
+	block := structs.Block{
+		Hash:    fmt.Sprintf("BLOCK%d", height),
+		Height:  height,
+		Time:    now,
+		ChainID: wm.chainID,
+		Data:    structs.BlockData{Txs: [][]byte{[]byte("tx1"), []byte("tx2")}},
+	}
+	if err := wm.svc.StoreBlock(ctx, block); err != nil {
+		return err
+	}
+
+	if wm.failAfterBlock {
+		return fmt.Errorf("%w: broken transaction", structs.ErrMapping)
+	}
+
+	var txs []structs.Transaction
+	for i := 0; i < 2; i++ {
+		txs = append(txs, structs.Transaction{
+			ChainID:   wm.chainID,
+			Height:    height,
+			Hash:      fmt.Sprintf("TX%d-%d", height, i),
+			BlockHash: block.Hash,
+			Time:      now,
+		})
+	}
+	return wm.svc.StoreTransactions(ctx, txs)
+}
+
+func (wm *workerMock) GetLatest(ctx context.Context) (uint64, error) {
+	return 0, nil
+}
+
+type subscriptionMock struct {
+	batches [][]subscription.Evt
+}
+
+func (sm *subscriptionMock) PopulateEvent(ctx context.Context, event, chainID string, height uint64, data interface{}) error {
+	return nil
+}
+
+func (sm *subscriptionMock) PopulateBatch(ctx context.Context, chainID string, height uint64, evts []subscription.Evt) error {
+	sm.batches = append(sm.batches, evts)
+	return nil
+}
+
+func newClient(batchHeights int) (*client.Client, *memory.Driver, *subscriptionMock, *workerMock) {
+	d := memory.NewDriver()
+	w := store.NewWriter(store.NewStore(d), batchHeights)
+	sm := &subscriptionMock{}
+
+	c := client.NewClient(zap.NewNop(), w, sm)
+	c.LinkWriter(w)
+	return c, d, sm, &workerMock{chainID: "chain", svc: api.NewService(w)}
+}
+
+func TestProcessHeight(t *testing.T) {
+	ctx := context.Background()
+	c, d, sm, wm := newClient(10)
+
+	require.NoError(t, c.ProcessHeight(ctx, wm, "chain", 5))
+
+	// live height is written right away, in spite of the batch not being full
+	b, err := d.GetBlockByHeight(ctx, 5, "chain")
+	require.NoError(t, err)
+	assert.Equal(t, "BLOCK5", b.Hash)
+
+	require.Len(t, sm.batches, 1)
+	evts := sm.batches[0]
+	require.Len(t, evts, 3)
+	assert.Equal(t, structs.EVENT_NEW_BLOCK, evts[0].EvType)
+	assert.Equal(t, structs.EventNewBlock{ChainID: "chain", Height: 5}, evts[0].Data)
+	for i, ev := range evts[1:] {
+		assert.Equal(t, structs.EVENT_NEW_TRANSACTION, ev.EvType)
+		assert.Equal(t, structs.EventNewTransaction{ChainID: "chain", Hash: fmt.Sprintf("TX5-%d", i), Height: 5}, ev.Data)
+	}
+
+	mismatches, err := d.GetTxCountMismatches(ctx, "chain", 5, 5)
+	require.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
+
+func TestProcessHeightDiscardsFailedHeight(t *testing.T) {
+	ctx := context.Background()
+	c, d, sm, wm := newClient(1)
+
+	wm.failAfterBlock = true
+	require.Error(t, c.ProcessHeight(ctx, wm, "chain", 5))
+	assert.Empty(t, sm.batches)
+
+	// the block stored before the failure is not written without its transactions
+	missing, err := d.GetMissingHeights(ctx, "chain", 5, 5)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{5}, missing)
+
+	wm.failAfterBlock = false
+	require.NoError(t, c.ProcessHeight(ctx, wm, "chain", 5))
+
+	txs, err := d.GetTransactions(ctx, structs.RangeQuery{ChainID: "chain", Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, txs, 2)
+}
+
+func TestFetchHeightBatches(t *testing.T) {
+	ctx := context.Background()
+	c, d, sm, wm := newClient(3)
+
+	for h := uint64(1); h <= 4; h++ {
+		require.NoError(t, c.FetchHeight(ctx, wm, "chain", h))
+	}
+	assert.Empty(t, sm.batches, "backfilled heights don't populate events")
+
+	// the first three heights filled the batch, the last one waits for flush
+	missing, err := d.GetMissingHeights(ctx, "chain", 1, 4)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{4}, missing)
+
+	require.NoError(t, c.Flush(ctx))
+	missing, err = d.GetMissingHeights(ctx, "chain", 1, 4)
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+
+	mismatches, err := d.GetTxCountMismatches(ctx, "chain", 1, 4)
+	require.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+)
+
+type ErrorClass string
+
+const (
+	ClassUnknown            ErrorClass = "unknown"
+	ClassWorkerDisconnected ErrorClass = "worker_disconnected"
+	ClassNodeTimeout        ErrorClass = "node_timeout"
+	ClassHeightNotAvailable ErrorClass = "height_not_available"
+	ClassMapping            ErrorClass = "mapping"
+	ClassCircuitOpen        ErrorClass = "circuit_open"
+)
+
+// Classify returns the class of error returned by NetworkClient
+func Classify(err error) ErrorClass {
+	switch {
+	case errors.Is(err, ErrCircuitOpen):
+		return ClassCircuitOpen
+	case errors.Is(err, structs.ErrWorkerDisconnected):
+		return ClassWorkerDisconnected
+	case errors.Is(err, structs.ErrNodeTimeout), errors.Is(err, context.DeadlineExceeded):
+		return ClassNodeTimeout
+	case errors.Is(err, structs.ErrHeightNotAvailable):
+		return ClassHeightNotAvailable
+	case errors.Is(err, structs.ErrMapping):
+		return ClassMapping
+	default:
+		return ClassUnknown
+	}
+}
+
+// RetryPolicy describes how many times and how often the call is retried
+type RetryPolicy struct {
+	// MaxAttempts - number of calls including the first one
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Backoff returns exponential backoff with jitter to wait before given (1-indexed) retry.
+// The result is randomized in range [b/2, b) where b is the exponential backoff.
+func (rp RetryPolicy) Backoff(retry int) time.Duration {
+	b := rp.InitialBackoff
+	for i := 1; i < retry && b < rp.MaxBackoff; i++ {
+		b *= 2
+	}
+	if b > rp.MaxBackoff {
+		b = rp.MaxBackoff
+	}
+
+	half := int64(b / 2)
+	if half <= 0 {
+		return b
+	}
+	return time.Duration(half + rand.Int63n(half))
+}
+
+// DefaultRetryPolicies - workers that are disconnected or fail to map the data are not retried,
+// so the height can be rerouted to a different worker as soon as possible.
+var DefaultRetryPolicies = map[ErrorClass]RetryPolicy{
+	ClassUnknown:            {MaxAttempts: 2, InitialBackoff: time.Second, MaxBackoff: 5 * time.Second},
+	ClassNodeTimeout:        {MaxAttempts: 3, InitialBackoff: 500 * time.Millisecond, MaxBackoff: 5 * time.Second},
+	ClassHeightNotAvailable: {MaxAttempts: 5, InitialBackoff: time.Second, MaxBackoff: 10 * time.Second},
+	ClassWorkerDisconnected: {MaxAttempts: 1},
+	ClassMapping:            {MaxAttempts: 1},
+	ClassCircuitOpen:        {MaxAttempts: 1},
+}
+
+// retry calls f until it succeeds or the retry policy of the error class is exhausted
+func retry(ctx context.Context, policies map[ErrorClass]RetryPolicy, f func() error) error {
+	attempts := make(map[ErrorClass]int)
+	for {
+		err := f()
+		if err == nil {
+			return nil
+		}
+
+		class := Classify(err)
+		attempts[class]++
+
+		p, ok := policies[class]
+		if !ok || attempts[class] >= p.MaxAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(p.Backoff(attempts[class])):
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fastPolicies are DefaultRetryPolicies with backoffs short enough for tests
+func fastPolicies() map[ErrorClass]RetryPolicy {
+	policies := make(map[ErrorClass]RetryPolicy, len(DefaultRetryPolicies))
+	for class, p := range DefaultRetryPolicies {
+		p.InitialBackoff = time.Millisecond
+		p.MaxBackoff = 2 * time.Millisecond
+		policies[class] = p
+	}
+	return policies
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err   error
+		class ErrorClass
+	}{
+		{err: fmt.Errorf("%w until now", ErrCircuitOpen), class: ClassCircuitOpen},
+		{err: fmt.Errorf("%w: connection closed", structs.ErrWorkerDisconnected), class: ClassWorkerDisconnected},
+		{err: fmt.Errorf("%w: node", structs.ErrNodeTimeout), class: ClassNodeTimeout},
+		{err: fmt.Errorf("%w: get_all", context.DeadlineExceeded), class: ClassNodeTimeout},
+		{err: fmt.Errorf("%w: 100", structs.ErrHeightNotAvailable), class: ClassHeightNotAvailable},
+		{err: fmt.Errorf("%w: bad tx", structs.ErrMapping), class: ClassMapping},
+		{err: errors.New("write: broken pipe"), class: ClassUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.class), func(t *testing.T) {
+			assert.Equal(t, tt.class, Classify(tt.err))
+		})
+	}
+}
+
+func TestRetryPolicyPerClass(t *testing.T) {
+	tests := []struct {
+		class ErrorClass
+		err   error
+		calls int
+	}{
+		{class: ClassUnknown, err: errors.New("unexpected response"), calls: 2},
+		{class: ClassNodeTimeout, err: structs.ErrNodeTimeout, calls: 3},
+		{class: ClassHeightNotAvailable, err: structs.ErrHeightNotAvailable, calls: 5},
+		{class: ClassWorkerDisconnected, err: structs.ErrWorkerDisconnected, calls: 1},
+		{class: ClassMapping, err: structs.ErrMapping, calls: 1},
+		{class: ClassCircuitOpen, err: ErrCircuitOpen, calls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.class), func(t *testing.T) {
+			var calls int
+			err := retry(context.Background(), fastPolicies(), func() error {
+				calls++
+				return tt.err
+			})
+			require.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.calls, calls)
+			assert.Equal(t, DefaultRetryPolicies[tt.class].MaxAttempts, calls)
+		})
+	}
+}
+
+func TestRetryAttemptsCountedPerClass(t *testing.T) {
+	errs := []error{structs.ErrNodeTimeout, structs.ErrHeightNotAvailable, structs.ErrNodeTimeout, nil}
+
+	var calls int
+	err := retry(context.Background(), fastPolicies(), func() error {
+		err := errs[calls]
+		calls++
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 4, calls, "timeouts interleaved with other class don't exhaust the timeout policy earlier")
+}
+
+func TestRetryStopsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	policies := map[ErrorClass]RetryPolicy{
+		ClassNodeTimeout: {MaxAttempts: 10, InitialBackoff: time.Minute, MaxBackoff: time.Minute},
+	}
+
+	var calls int
+	err := retry(ctx, policies, func() error {
+		calls++
+		cancel()
+		return structs.ErrNodeTimeout
+	})
+	require.ErrorIs(t, err, structs.ErrNodeTimeout)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	rp := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+
+	for retry, b := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 300 * time.Millisecond, 6: 300 * time.Millisecond} {
+		d := rp.Backoff(retry)
+		assert.GreaterOrEqual(t, int64(d), int64(b/2), "retry %d", retry)
+		assert.Less(t, int64(d), int64(b), "retry %d", retry)
+	}
+}
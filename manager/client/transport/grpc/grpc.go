@@ -0,0 +1,68 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	grpcConn "github.com/figment-networks/graph-demo/connectivity/grpc"
+	"github.com/figment-networks/graph-demo/connectivity/grpc/workerpb"
+	"github.com/figment-networks/graph-demo/manager/structs"
+)
+
+// Caller sends request to the worker on its stream and waits for the result
+type Caller interface {
+	Call(ctx context.Context, msg *workerpb.ManagerMessage) (*workerpb.Result, error)
+}
+
+// CosmosGRPCTransport calls worker stream for the data of a single chain
+type CosmosGRPCTransport struct {
+	c       Caller
+	chainID string
+}
+
+func NewCosmosGRPCTransport(c Caller, chainID string) *CosmosGRPCTransport {
+	return &CosmosGRPCTransport{c: c, chainID: chainID}
+}
+
+func (ng *CosmosGRPCTransport) GetAll(ctx context.Context, height uint64) error {
+	_, err := ng.c.Call(ctx, &workerpb.ManagerMessage{
+		Body: &workerpb.ManagerMessage_GetAll{GetAll: &workerpb.GetAll{ChainId: ng.chainID, Height: height}},
+	})
+	if err != nil {
+		return mapError(err)
+	}
+	return nil
+}
+
+func (ng *CosmosGRPCTransport) GetLatest(ctx context.Context) (h uint64, err error) {
+	res, err := ng.c.Call(ctx, &workerpb.ManagerMessage{
+		Body: &workerpb.ManagerMessage_GetLatest{GetLatest: &workerpb.GetLatest{ChainId: ng.chainID}},
+	})
+	if err != nil {
+		return h, mapError(err)
+	}
+	return res.GetHeight(), nil
+}
+
+// Closed reports whether the stream of the worker is closed
+func (ng *CosmosGRPCTransport) Closed() bool {
+	c, ok := ng.c.(interface{ Err() error })
+	return ok && c.Err() != nil
+}
+
+// mapError maps the error of Call to the errors of processing heights.
+// Only closed stream means the worker is disconnected, other errors are left unknown.
+func mapError(err error) error {
+	pbErr := &workerpb.Error{}
+	switch {
+	case errors.As(err, &pbErr):
+		return pbErr.Err()
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%w: %s", structs.ErrNodeTimeout, err.Error())
+	case errors.Is(err, grpcConn.ErrConnectionClosed):
+		return fmt.Errorf("%w: %s", structs.ErrWorkerDisconnected, err.Error())
+	default:
+		return err
+	}
+}
@@ -3,82 +3,103 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strconv"
 
 	"github.com/figment-networks/graph-demo/manager/structs"
 	"go.uber.org/zap"
 )
 
+// Storer stores the data pulled from worker
+type Storer interface {
+	StoreBlock(ctx context.Context, block structs.Block) error
+	StoreTransactions(ctx context.Context, txs []structs.Transaction) error
+}
+
+// CosmosHTTPTransport pulls the data of a single chain from worker over HTTP and stores it in manager.
+// Unlike websocket and gRPC transports the worker doesn't connect to the manager at all.
 type CosmosHTTPTransport struct {
 	address string
+	chainID string
 	c       *http.Client
+	st      Storer
 	log     *zap.Logger
 }
 
-func NewCosmosHTTPTransport(address string, c *http.Client, log *zap.Logger) *CosmosHTTPTransport {
+func NewCosmosHTTPTransport(address, chainID string, c *http.Client, st Storer, log *zap.Logger) *CosmosHTTPTransport {
 	return &CosmosHTTPTransport{
 		address: address,
+		chainID: chainID,
 		c:       c,
+		st:      st,
 		log:     log,
 	}
 }
 
-func (ng *CosmosHTTPTransport) GetAll(ctx context.Context, height uint64) (bTx structs.BlockAndTx, er error) {
-	ng.log.Debug("[HTTP] Getting a block", zap.Uint64("height", height))
+func (ng *CosmosHTTPTransport) GetAll(ctx context.Context, height uint64) error {
+	ng.log.Debug("[HTTP] Getting a block", zap.Uint64("height", height), zap.String("chain_id", ng.chainID))
 
-	resp, err := http.Get(fmt.Sprintf("%s/getAll/%d", ng.address, height))
-	if err != nil {
-		ng.log.Error("[HTTP] Error while getting a block from worker", zap.Uint64("height", height), zap.Error(err))
-		return structs.BlockAndTx{}, err
+	var bTx structs.BlockAndTx
+	if err := ng.get(ctx, "/getAll/"+strconv.FormatUint(height, 10), &bTx); err != nil {
+		ng.log.Debug("[HTTP] Error while getting a block from worker", zap.Uint64("height", height), zap.Error(err))
+		return err
 	}
-	defer resp.Body.Close()
 
-	byteResp, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		ng.log.Error("[HTTP] Error while reading block response body", zap.Uint64("height", height), zap.Error(err))
-		return structs.BlockAndTx{}, err
+	ng.log.Debug("[HTTP] Got a block", zap.Uint64("height", height), zap.Int("txs", len(bTx.Transactions)))
+
+	if err := ng.st.StoreBlock(ctx, bTx.Block); err != nil {
+		return err
 	}
 
-	if err = json.Unmarshal(byteResp, &bTx); err != nil {
-		ng.log.Error("[HTTP] Error while decoding block with transactions", zap.Uint64("height", height), zap.Error(err))
-		return structs.BlockAndTx{}, err
+	if len(bTx.Transactions) > 0 {
+		return ng.st.StoreTransactions(ctx, bTx.Transactions)
 	}
+	return nil
+}
 
-	ng.log.Debug("[HTTP] Got a block", zap.Uint64("height", height), zap.Int("txs", len(bTx.Block.Data.Txs)))
+func (ng *CosmosHTTPTransport) GetLatest(ctx context.Context) (h uint64, err error) {
+	ng.log.Debug("[HTTP] Getting latest height", zap.String("chain_id", ng.chainID))
 
-	return structs.BlockAndTx{
-		Block:        bTx.Block,
-		Transactions: bTx.Transactions,
-	}, nil
+	if err := ng.get(ctx, "/getLatest", &h); err != nil {
+		ng.log.Debug("[HTTP] Error while getting latest height from worker", zap.Error(err))
+		return 0, err
+	}
+	return h, nil
 }
 
-func (ng *CosmosHTTPTransport) GetLatest(ctx context.Context) (bTx structs.BlockAndTx, er error) {
-	ng.log.Debug("[HTTP] Getting latest block")
+// get calls the worker and decodes its response into v, errors are mapped to the errors of processing heights
+func (ng *CosmosHTTPTransport) get(ctx context.Context, path string, v interface{}) error {
+	u := ng.address + path + "?chain_id=" + url.QueryEscape(ng.chainID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
 
-	resp, err := http.Get(fmt.Sprintf("%s/getLatest", ng.address))
+	resp, err := ng.c.Do(req)
 	if err != nil {
-		ng.log.Error("[HTTP] Error while getting latest block from worker", zap.Error(err))
-		return structs.BlockAndTx{}, err
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("%w: %s", structs.ErrNodeTimeout, err.Error())
+		}
+		return fmt.Errorf("%w: %s", structs.ErrWorkerDisconnected, err.Error())
 	}
 	defer resp.Body.Close()
 
-	byteResp, err := ioutil.ReadAll(resp.Body)
+	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		ng.log.Error("[HTTP] Error while reading latest block response body", zap.Error(err))
-		return structs.BlockAndTx{}, err
+		return fmt.Errorf("%w: %s", structs.ErrWorkerDisconnected, err.Error())
 	}
 
-	if err = json.Unmarshal(byteResp, &bTx); err != nil {
-		ng.log.Error("[HTTP] Error while decoding latest block with transactions", zap.Error(err))
-		return structs.BlockAndTx{}, err
+	if resp.StatusCode != http.StatusOK {
+		var errResp structs.ErrorResponse
+		if err := json.Unmarshal(body, &errResp); err != nil {
+			return fmt.Errorf("unexpected response status %d: %s", resp.StatusCode, string(body))
+		}
+		return structs.ErrorFromCode(errResp.Code, errResp.Message)
 	}
 
-	ng.log.Debug("[HTTP] Got latest block", zap.Int64("height", bTx.Block.Header.Height), zap.Int("txs", len(bTx.Block.Data.Txs)))
-
-	return structs.BlockAndTx{
-		Block:        bTx.Block,
-		Transactions: bTx.Transactions,
-	}, nil
+	return json.Unmarshal(body, v)
 }
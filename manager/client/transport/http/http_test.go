@@ -0,0 +1,147 @@
+package http_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	workerHTTP "github.com/figment-networks/graph-demo/cosmos-worker/api/transport/http"
+	"github.com/figment-networks/graph-demo/manager/client"
+	transport "github.com/figment-networks/graph-demo/manager/client/transport/http"
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type cosmosMock struct {
+	chainID string
+	latest  uint64
+}
+
+func (cm *cosmosMock) FetchAll(ctx context.Context, height uint64) (bTx structs.BlockAndTx, err error) {
+	if height > cm.latest {
+		return bTx, fmt.Errorf("%w: %d", structs.ErrHeightNotAvailable, height)
+	}
+
+	now := time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC)
+	bTx.Block = structs.Block{
+		Hash:    fmt.Sprintf("BLOCK%d", height),
+		Height:  height,
+		Time:    now,
+		ChainID: cm.chainID,
+		Header:  structs.BlockHeader{ChainID: cm.chainID, Height: int64(height), Time: now},
+		Data:    structs.BlockData{Txs: [][]byte{[]byte("tx1"), []byte("tx2")}},
+	}
+	for i := 0; i < 2; i++ {
+		bTx.Transactions = append(bTx.Transactions, structs.Transaction{
+			ChainID:   cm.chainID,
+			Height:    height,
+			Hash:      fmt.Sprintf("TX%d-%d", height, i),
+			BlockHash: bTx.Block.Hash,
+			Time:      now,
+			Memo:      "memo",
+		})
+	}
+	return bTx, nil
+}
+
+func (cm *cosmosMock) GetLatest(ctx context.Context) (uint64, error) {
+	return cm.latest, nil
+}
+
+type storeMock struct {
+	blocks []structs.Block
+	txs    []structs.Transaction
+}
+
+func (sm *storeMock) StoreBlock(ctx context.Context, block structs.Block) error {
+	sm.blocks = append(sm.blocks, block)
+	return nil
+}
+
+func (sm *storeMock) StoreTransactions(ctx context.Context, txs []structs.Transaction) error {
+	sm.txs = append(sm.txs, txs...)
+	return nil
+}
+
+func newWorker(t *testing.T, clients map[string]workerHTTP.CosmosClient) *httptest.Server {
+	mux := http.NewServeMux()
+	workerHTTP.NewHandler(clients).AttachToMux(mux)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCosmosHTTPTransport(t *testing.T) {
+	srv := newWorker(t, map[string]workerHTTP.CosmosClient{
+		"chain-a": &cosmosMock{chainID: "chain-a", latest: 100},
+		"chain-b": &cosmosMock{chainID: "chain-b", latest: 200},
+	})
+
+	st := &storeMock{}
+	var nc client.NetworkClient = transport.NewCosmosHTTPTransport(srv.URL, "chain-b", srv.Client(), st, zap.NewNop())
+
+	t.Run("latest", func(t *testing.T) {
+		h, err := nc.GetLatest(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, uint64(200), h)
+	})
+
+	t.Run("get all stores block and transactions", func(t *testing.T) {
+		require.NoError(t, nc.GetAll(context.Background(), 150))
+
+		require.Len(t, st.blocks, 1)
+		assert.Equal(t, uint64(150), st.blocks[0].Height)
+		assert.Equal(t, "chain-b", st.blocks[0].ChainID)
+		assert.Equal(t, time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC), st.blocks[0].Time)
+		assert.Equal(t, [][]byte{[]byte("tx1"), []byte("tx2")}, st.blocks[0].Data.Txs)
+
+		require.Len(t, st.txs, 2)
+		assert.Equal(t, "TX150-1", st.txs[1].Hash)
+		assert.Equal(t, "BLOCK150", st.txs[1].BlockHash)
+	})
+
+	t.Run("height not available", func(t *testing.T) {
+		err := nc.GetAll(context.Background(), 201)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, structs.ErrHeightNotAvailable), err.Error())
+		assert.Equal(t, client.ClassHeightNotAvailable, client.Classify(err))
+	})
+
+	t.Run("unknown chain", func(t *testing.T) {
+		_, err := transport.NewCosmosHTTPTransport(srv.URL, "chain-c", srv.Client(), st, zap.NewNop()).GetLatest(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "chain is not served by this worker")
+	})
+}
+
+func TestCosmosHTTPTransportWorkerDown(t *testing.T) {
+	srv := newWorker(t, map[string]workerHTTP.CosmosClient{"chain-a": &cosmosMock{chainID: "chain-a", latest: 100}})
+	srv.Close()
+
+	nc := transport.NewCosmosHTTPTransport(srv.URL, "chain-a", srv.Client(), &storeMock{}, zap.NewNop())
+	_, err := nc.GetLatest(context.Background())
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, structs.ErrWorkerDisconnected), err.Error())
+}
+
+func TestCosmosHTTPTransportTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	nc := transport.NewCosmosHTTPTransport(srv.URL, "chain-a", srv.Client(), &storeMock{}, zap.NewNop())
+	err := nc.GetAll(ctx, 1)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, structs.ErrNodeTimeout), err.Error())
+}
@@ -7,54 +7,59 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/figment-networks/graph-demo/connectivity/jsonrpc"
 	wsapi "github.com/figment-networks/graph-demo/connectivity/ws"
+	"github.com/figment-networks/graph-demo/manager/structs"
 )
 
+// CosmosWSTransport calls worker session for the data of a single chain
 type CosmosWSTransport struct {
-	sess wsapi.SyncSender
+	sess    wsapi.SyncSender
+	chainID json.RawMessage
 }
 
-func NewCosmosWSTransport(sess wsapi.SyncSender) *CosmosWSTransport {
-	return &CosmosWSTransport{sess: sess}
+func NewCosmosWSTransport(sess wsapi.SyncSender, chainID string) *CosmosWSTransport {
+	cID, _ := json.Marshal(chainID)
+	return &CosmosWSTransport{sess: sess, chainID: cID}
 }
 
 func (ng *CosmosWSTransport) GetAll(ctx context.Context, height uint64) error {
-	resp, err := ng.sess.SendSync("get_all", []json.RawMessage{[]byte(strconv.FormatUint(height, 10))})
-	if err != nil {
-		return err
+	if _, err := ng.sess.SendSync(ctx, "get_all", []json.RawMessage{[]byte(strconv.FormatUint(height, 10)), ng.chainID}); err != nil {
+		return mapError(err)
 	}
-	if resp.Error != nil && resp.Error.Message != "" {
-		return errors.New(resp.Error.Message)
-	}
-
-	return err
+	return nil
 }
 
 func (ng *CosmosWSTransport) GetLatest(ctx context.Context) (h uint64, err error) {
-	resp, err := ng.sess.SendSync("get_latest", nil)
+	resp, err := ng.sess.SendSync(ctx, "get_latest", []json.RawMessage{ng.chainID})
 	if err != nil {
-		return h, err
+		return h, mapError(err)
 	}
 
 	if err = json.Unmarshal(resp.Result, &h); err != nil {
-		var response errResponse
-		if err2 := json.Unmarshal(resp.Result, &response); err2 == nil {
-			err = errors.New("unexpected response")
-			for _, errStr := range response.Errors {
-				err = fmt.Errorf("%s: %s", err.Error(), errStr)
-			}
-		}
-		return h, err
+		return h, fmt.Errorf("error decoding latest height: %w", err)
 	}
-
 	return h, nil
-
 }
 
-type errResponse struct {
-	Errors []errMsg `json:"errors"`
+// Closed reports whether the session is closed
+func (ng *CosmosWSTransport) Closed() bool {
+	s, ok := ng.sess.(interface{ Err() error })
+	return ok && s.Err() != nil
 }
 
-type errMsg struct {
-	Message string `json:"message"`
+// mapError maps the error of SendSync to the errors of processing heights.
+// Only closed session means the worker is disconnected, other errors are left unknown.
+func mapError(err error) error {
+	rpcErr := &jsonrpc.Error{}
+	switch {
+	case errors.As(err, &rpcErr):
+		return structs.ErrorFromCode(rpcErr.Code, rpcErr.Message)
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%w: %s", structs.ErrNodeTimeout, err.Error())
+	case errors.Is(err, wsapi.ErrConnectionClosed):
+		return fmt.Errorf("%w: %s", structs.ErrWorkerDisconnected, err.Error())
+	default:
+		return err
+	}
 }
@@ -0,0 +1,62 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/figment-networks/graph-demo/connectivity/jsonrpc"
+	wsapi "github.com/figment-networks/graph-demo/connectivity/ws"
+	"github.com/figment-networks/graph-demo/manager/client"
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// senderMock responds to every call with resp and err
+type senderMock struct {
+	resp jsonrpc.Response
+	err  error
+}
+
+func (sm senderMock) SendSync(ctx context.Context, method string, params []json.RawMessage) (jsonrpc.Response, error) {
+	return sm.resp, sm.err
+}
+
+func TestGetAllErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		class client.ErrorClass
+	}{
+		{name: "height not available", err: &jsonrpc.Error{Code: structs.ErrCodeHeightNotAvailable, Message: "height 10"}, class: client.ClassHeightNotAvailable},
+		{name: "mapping", err: &jsonrpc.Error{Code: structs.ErrCodeMapping, Message: "bad tx"}, class: client.ClassMapping},
+		{name: "chain not served", err: &jsonrpc.Error{Code: structs.ErrCodeUnknown, Message: "chain is not served by this worker"}, class: client.ClassUnknown},
+		{name: "timeout", err: fmt.Errorf("%w: get_all", context.DeadlineExceeded), class: client.ClassNodeTimeout},
+		{name: "session closed", err: wsapi.ErrConnectionClosed, class: client.ClassWorkerDisconnected},
+		{name: "other", err: errors.New("write: broken pipe"), class: client.ClassUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewCosmosWSTransport(senderMock{err: tt.err}, "chain").GetAll(context.Background(), 10)
+			require.Error(t, err)
+			assert.Equal(t, tt.class, client.Classify(err))
+		})
+	}
+
+	err := NewCosmosWSTransport(senderMock{resp: jsonrpc.Response{Result: []byte(`"ACK"`)}}, "chain").GetAll(context.Background(), 10)
+	require.NoError(t, err)
+}
+
+func TestGetLatest(t *testing.T) {
+	h, err := NewCosmosWSTransport(senderMock{resp: jsonrpc.Response{Result: []byte(`42`)}}, "chain").GetLatest(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), h)
+
+	_, err = NewCosmosWSTransport(senderMock{err: &jsonrpc.Error{Code: structs.ErrCodeNodeTimeout, Message: "node"}}, "chain").GetLatest(context.Background())
+	assert.Equal(t, client.ClassNodeTimeout, client.Classify(err))
+}
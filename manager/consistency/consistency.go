@@ -0,0 +1,261 @@
+package consistency
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/figment-networks/graph-demo/manager/store"
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// checkWindow is the number of heights scanned by a single query
+	checkWindow = 10000
+	// fullCheckEvery is the default number of checks after which all the stored heights are rescanned
+	fullCheckEvery = 24
+)
+
+type Jobs interface {
+	Create(ctx context.Context, chainID string, from, to uint64) (structs.BackfillJob, error)
+	List(ctx context.Context, chainID string) ([]structs.BackfillJob, error)
+}
+
+// Checker periodically scans stored blocks of every chain looking for missing heights
+// and heights with incomplete transactions. Every finding is re-fetched using backfill jobs.
+// Heights below the verified watermark of the chain are skipped, except every fullEvery-th check.
+type Checker struct {
+	log           *zap.Logger
+	st            store.Storager
+	jobs          Jobs
+	lowestHeights map[string]uint64
+
+	fullEvery int
+	checks    int
+
+	reports map[string]structs.ConsistencyReport
+	rl      sync.RWMutex
+}
+
+func NewChecker(log *zap.Logger, st store.Storager, jobs Jobs, lowestHeights map[string]uint64) *Checker {
+	return &Checker{
+		log:           log,
+		st:            st,
+		jobs:          jobs,
+		lowestHeights: lowestHeights,
+		fullEvery:     fullCheckEvery,
+		reports:       make(map[string]structs.ConsistencyReport),
+	}
+}
+
+// SetFullCheckEvery sets the number of checks after which all the stored heights are rescanned, 1 rescans them every time
+func (c *Checker) SetFullCheckEvery(n int) {
+	c.fullEvery = n
+}
+
+// Run checks all the chains every interval until ctx is done
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	tckr := time.NewTicker(interval)
+	defer tckr.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tckr.C:
+			if err := c.Check(ctx); err != nil {
+				c.log.Error("error running consistency check", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Check checks every chain from its verified watermark up to the processed one, pruned heights are skipped.
+// Every fullEvery-th check starts from the lowest height instead. The watermark is moved to the lowest finding,
+// or above the checked range when there is none.
+func (c *Checker) Check(ctx context.Context) error {
+	heights, err := c.st.GetLatestHeights(ctx)
+	if err != nil {
+		return err
+	}
+
+	pruned, err := c.st.GetPrunedHeights(ctx)
+	if err != nil {
+		return err
+	}
+
+	verified, err := c.st.GetVerifiedHeights(ctx)
+	if err != nil {
+		return err
+	}
+
+	full := c.fullEvery <= 1 || c.checks%c.fullEvery == 0
+	c.checks++
+
+	for chainID, to := range heights {
+		from, ok := c.lowestHeights[chainID]
+		if !ok || from == 0 {
+			from = 1
+		}
+		if pruned[chainID] > from {
+			from = pruned[chainID]
+		}
+		if !full && verified[chainID] > from {
+			from = verified[chainID]
+		}
+
+		if from > to {
+			continue
+		}
+
+		report := c.CheckChain(ctx, chainID, from, to)
+		if report.Error != "" {
+			c.log.Error("error checking chain consistency", zap.String("chain_id", chainID), zap.String("error", report.Error))
+		} else if len(report.MissingHeights) > 0 || len(report.TxCountMismatches) > 0 {
+			c.log.Warn("found inconsistencies", zap.String("chain_id", chainID),
+				zap.Any("missing", report.MissingHeights), zap.Uint64s("tx_count_mismatches", report.TxCountMismatches))
+		}
+
+		if report.Error == "" {
+			if err := c.st.SetVerifiedHeight(ctx, chainID, verifiedBelow(report)); err != nil {
+				c.log.Error("error storing verified height", zap.String("chain_id", chainID), zap.Error(err))
+			}
+		}
+
+		c.rl.Lock()
+		c.reports[chainID] = report
+		c.rl.Unlock()
+	}
+
+	return nil
+}
+
+// verifiedBelow returns the height below which the checked range has no findings
+func verifiedBelow(report structs.ConsistencyReport) uint64 {
+	below := report.Checked.To + 1
+	if len(report.MissingHeights) > 0 && report.MissingHeights[0].From < below {
+		below = report.MissingHeights[0].From
+	}
+	if len(report.TxCountMismatches) > 0 && report.TxCountMismatches[0] < below {
+		below = report.TxCountMismatches[0]
+	}
+	return below
+}
+
+// CheckChain checks heights [from, to] of given chain and schedules re-fetch of the inconsistent ones
+func (c *Checker) CheckChain(ctx context.Context, chainID string, from, to uint64) (report structs.ConsistencyReport) {
+	report = structs.ConsistencyReport{
+		ChainID:           chainID,
+		CheckedAt:         time.Now(),
+		Checked:           structs.HeightRange{From: from, To: to},
+		MissingHeights:    []structs.HeightRange{},
+		TxCountMismatches: []uint64{},
+		ScheduledJobs:     []uuid.UUID{},
+	}
+
+	var missing []uint64
+	for wFrom := from; wFrom <= to; wFrom += checkWindow {
+		wTo := wFrom + checkWindow - 1
+		if wTo > to {
+			wTo = to
+		}
+
+		m, err := c.st.GetMissingHeights(ctx, chainID, wFrom, wTo)
+		if err != nil {
+			report.Error = err.Error()
+			return report
+		}
+		missing = append(missing, m...)
+
+		mm, err := c.st.GetTxCountMismatches(ctx, chainID, wFrom, wTo)
+		if err != nil {
+			report.Error = err.Error()
+			return report
+		}
+		report.TxCountMismatches = append(report.TxCountMismatches, mm...)
+	}
+
+	if mr := toRanges(missing); mr != nil {
+		report.MissingHeights = mr
+	}
+
+	all := append(append([]uint64{}, missing...), report.TxCountMismatches...)
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+
+	scheduled, err := c.scheduled(ctx, chainID)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+
+	for _, r := range toRanges(all) {
+		if covered(scheduled, r) {
+			continue
+		}
+
+		job, err := c.jobs.Create(ctx, chainID, r.From, r.To)
+		if err != nil {
+			report.Error = err.Error()
+			return report
+		}
+		report.ScheduledJobs = append(report.ScheduledJobs, job.ID)
+	}
+
+	return report
+}
+
+// scheduled returns the remaining ranges of jobs that are not done yet
+func (c *Checker) scheduled(ctx context.Context, chainID string) (ranges []structs.HeightRange, err error) {
+	jobs, err := c.jobs.List(ctx, chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, j := range jobs {
+		if j.Status == structs.JobStatusRunning || j.Status == structs.JobStatusPaused {
+			ranges = append(ranges, structs.HeightRange{From: j.Current + 1, To: j.To})
+		}
+	}
+	return ranges, nil
+}
+
+// Reports returns the last reports of given chain or of all the chains if chainID is empty
+func (c *Checker) Reports(chainID string) []structs.ConsistencyReport {
+	c.rl.RLock()
+	defer c.rl.RUnlock()
+
+	reports := []structs.ConsistencyReport{}
+	for id, r := range c.reports {
+		if chainID == "" || chainID == id {
+			reports = append(reports, r)
+		}
+	}
+
+	sort.Slice(reports, func(i, j int) bool { return reports[i].ChainID < reports[j].ChainID })
+	return reports
+}
+
+// toRanges groups sorted heights into ranges of consecutive heights
+func toRanges(heights []uint64) (ranges []structs.HeightRange) {
+	for _, h := range heights {
+		if l := len(ranges); l > 0 && (ranges[l-1].To == h || ranges[l-1].To+1 == h) {
+			ranges[l-1].To = h
+			continue
+		}
+		ranges = append(ranges, structs.HeightRange{From: h, To: h})
+	}
+	return ranges
+}
+
+func covered(ranges []structs.HeightRange, r structs.HeightRange) bool {
+	for _, s := range ranges {
+		if s.From <= r.From && r.To <= s.To {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package consistency
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/figment-networks/graph-demo/manager/store/memory"
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const chainID = "cosmoshub-4"
+
+// jobsMock records the created jobs, listing them as running
+type jobsMock struct {
+	created []structs.HeightRange
+	running []structs.BackfillJob
+}
+
+func (jm *jobsMock) Create(ctx context.Context, chainID string, from, to uint64) (structs.BackfillJob, error) {
+	jm.created = append(jm.created, structs.HeightRange{From: from, To: to})
+	return structs.BackfillJob{ID: uuid.New(), ChainID: chainID, From: from, To: to, Status: structs.JobStatusRunning}, nil
+}
+
+func (jm *jobsMock) List(ctx context.Context, chainID string) ([]structs.BackfillJob, error) {
+	return jm.running, nil
+}
+
+// storeHeights stores heights [from, to] of the chain, every block with txs transactions
+func storeHeights(t *testing.T, d *memory.Driver, from, to uint64, txs int) {
+	for h := from; h <= to; h++ {
+		b := structs.Block{ChainID: chainID, Height: h, Hash: fmt.Sprintf("BLOCK%d", h)}
+		var transactions []structs.Transaction
+		for i := 0; i < txs; i++ {
+			b.Data.Txs = append(b.Data.Txs, []byte{byte(i)})
+			transactions = append(transactions, structs.Transaction{ChainID: chainID, Height: h, Hash: fmt.Sprintf("TX%d-%d", h, i), BlockHash: b.Hash})
+		}
+		require.NoError(t, d.StoreHeights(context.Background(), []structs.BlockAndTx{{Block: b, Transactions: transactions}}))
+	}
+}
+
+func TestToRanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		heights []uint64
+		ranges  []structs.HeightRange
+	}{
+		{name: "empty"},
+		{name: "single", heights: []uint64{5}, ranges: []structs.HeightRange{{From: 5, To: 5}}},
+		{name: "consecutive", heights: []uint64{5, 6, 7}, ranges: []structs.HeightRange{{From: 5, To: 7}}},
+		{name: "gaps", heights: []uint64{1, 2, 4, 7, 8}, ranges: []structs.HeightRange{{From: 1, To: 2}, {From: 4, To: 4}, {From: 7, To: 8}}},
+		{name: "duplicates", heights: []uint64{3, 3, 4, 4}, ranges: []structs.HeightRange{{From: 3, To: 4}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.ranges, toRanges(tt.heights))
+		})
+	}
+}
+
+func TestCovered(t *testing.T) {
+	scheduled := []structs.HeightRange{{From: 10, To: 20}, {From: 30, To: 30}}
+
+	tests := []struct {
+		name    string
+		r       structs.HeightRange
+		covered bool
+	}{
+		{name: "inside", r: structs.HeightRange{From: 12, To: 15}, covered: true},
+		{name: "same", r: structs.HeightRange{From: 10, To: 20}, covered: true},
+		{name: "single", r: structs.HeightRange{From: 30, To: 30}, covered: true},
+		{name: "overlapping", r: structs.HeightRange{From: 18, To: 22}},
+		{name: "spanning two", r: structs.HeightRange{From: 20, To: 30}},
+		{name: "outside", r: structs.HeightRange{From: 1, To: 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.covered, covered(scheduled, tt.r))
+		})
+	}
+	assert.False(t, covered(nil, structs.HeightRange{From: 1, To: 1}))
+}
+
+func TestCheckChain(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("gap", func(t *testing.T) {
+		d := memory.NewDriver()
+		storeHeights(t, d, 1, 4, 1)
+		storeHeights(t, d, 7, 10, 1)
+
+		jm := &jobsMock{}
+		report := NewChecker(zap.NewNop(), d, jm, nil).CheckChain(ctx, chainID, 1, 10)
+		assert.Empty(t, report.Error)
+		assert.Equal(t, []structs.HeightRange{{From: 5, To: 6}}, report.MissingHeights)
+		assert.Empty(t, report.TxCountMismatches)
+		assert.Equal(t, []structs.HeightRange{{From: 5, To: 6}}, jm.created)
+		assert.Len(t, report.ScheduledJobs, 1)
+	})
+
+	t.Run("tx count mismatch", func(t *testing.T) {
+		d := memory.NewDriver()
+		storeHeights(t, d, 1, 5, 2)
+		// block of height 3 with a transaction missing
+		b := structs.Block{ChainID: chainID, Height: 3, Hash: "BLOCK3", Data: structs.BlockData{Txs: [][]byte{{0}, {1}, {2}}}}
+		require.NoError(t, d.StoreBlock(ctx, b))
+
+		jm := &jobsMock{}
+		report := NewChecker(zap.NewNop(), d, jm, nil).CheckChain(ctx, chainID, 1, 5)
+		assert.Empty(t, report.MissingHeights)
+		assert.Equal(t, []uint64{3}, report.TxCountMismatches)
+		assert.Equal(t, []structs.HeightRange{{From: 3, To: 3}}, jm.created)
+	})
+
+	t.Run("already scheduled", func(t *testing.T) {
+		d := memory.NewDriver()
+		storeHeights(t, d, 1, 2, 1)
+
+		jm := &jobsMock{running: []structs.BackfillJob{{ChainID: chainID, From: 1, To: 10, Current: 2, Status: structs.JobStatusRunning}}}
+		report := NewChecker(zap.NewNop(), d, jm, nil).CheckChain(ctx, chainID, 1, 5)
+		assert.Equal(t, []structs.HeightRange{{From: 3, To: 5}}, report.MissingHeights)
+		assert.Empty(t, jm.created, "range is fetched by running job")
+	})
+}
+
+func TestCheckSkipsPrunedHeights(t *testing.T) {
+	ctx := context.Background()
+	d := memory.NewDriver()
+	storeHeights(t, d, 1, 10, 1)
+	require.NoError(t, d.SetLatestHeight(ctx, chainID, 10))
+	require.NoError(t, d.PruneHeights(ctx, chainID, 6))
+
+	jm := &jobsMock{}
+	c := NewChecker(zap.NewNop(), d, jm, map[string]uint64{chainID: 1})
+	require.NoError(t, c.Check(ctx))
+
+	reports := c.Reports(chainID)
+	require.Len(t, reports, 1)
+	assert.Equal(t, structs.HeightRange{From: 6, To: 10}, reports[0].Checked)
+	assert.Empty(t, reports[0].MissingHeights)
+	assert.Empty(t, jm.created)
+}
+
+func TestCheckWatermark(t *testing.T) {
+	ctx := context.Background()
+	d := memory.NewDriver()
+	storeHeights(t, d, 1, 4, 1)
+	storeHeights(t, d, 6, 10, 1)
+	require.NoError(t, d.SetLatestHeight(ctx, chainID, 10))
+
+	jm := &jobsMock{}
+	c := NewChecker(zap.NewNop(), d, jm, nil)
+	c.SetFullCheckEvery(3)
+
+	checked := func() structs.HeightRange {
+		require.NoError(t, c.Check(ctx))
+		return c.Reports(chainID)[0].Checked
+	}
+	verified := func() uint64 {
+		v, err := d.GetVerifiedHeights(ctx)
+		require.NoError(t, err)
+		return v[chainID]
+	}
+
+	assert.Equal(t, structs.HeightRange{From: 1, To: 10}, checked(), "first check is full")
+	assert.Equal(t, uint64(5), verified(), "watermark stops at the gap")
+
+	storeHeights(t, d, 5, 5, 1)
+	storeHeights(t, d, 11, 12, 1)
+	require.NoError(t, d.SetLatestHeight(ctx, chainID, 12))
+	assert.Equal(t, structs.HeightRange{From: 5, To: 12}, checked(), "only heights above the watermark are scanned")
+	assert.Equal(t, uint64(13), verified())
+
+	// inconsistency below the watermark is found by the full check only
+	b := structs.Block{ChainID: chainID, Height: 3, Hash: "BLOCK3", Data: structs.BlockData{Txs: [][]byte{{0}, {1}}}}
+	require.NoError(t, d.StoreBlock(ctx, b))
+	storeHeights(t, d, 13, 13, 1)
+	require.NoError(t, d.SetLatestHeight(ctx, chainID, 13))
+	assert.Equal(t, structs.HeightRange{From: 13, To: 13}, checked())
+	assert.Equal(t, uint64(14), verified())
+
+	assert.Equal(t, structs.HeightRange{From: 1, To: 13}, checked(), "every third check is full")
+	assert.Equal(t, uint64(3), verified())
+	assert.Equal(t, []structs.HeightRange{{From: 5, To: 5}, {From: 3, To: 3}}, jm.created)
+}
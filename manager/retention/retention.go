@@ -0,0 +1,121 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/figment-networks/graph-demo/manager/store"
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"go.uber.org/zap"
+)
+
+// Pruner periodically applies retention policies of the chains to stored data,
+// it also guards the requests of pruned heights.
+type Pruner struct {
+	log      *zap.Logger
+	st       store.Storager
+	policies map[string]structs.RetentionPolicy
+
+	pruned map[string]uint64
+	pl     sync.RWMutex
+}
+
+func NewPruner(log *zap.Logger, st store.Storager, policies map[string]structs.RetentionPolicy) *Pruner {
+	return &Pruner{
+		log:      log,
+		st:       st,
+		policies: policies,
+		pruned:   make(map[string]uint64),
+	}
+}
+
+// Load reads the heights pruned before from the store
+func (p *Pruner) Load(ctx context.Context) error {
+	heights, err := p.st.GetPrunedHeights(ctx)
+	if err != nil {
+		return err
+	}
+
+	p.pl.Lock()
+	defer p.pl.Unlock()
+	for chainID, below := range heights {
+		if below > p.pruned[chainID] {
+			p.pruned[chainID] = below
+		}
+	}
+	return nil
+}
+
+// Run prunes all the chains every interval until ctx is done
+func (p *Pruner) Run(ctx context.Context, interval time.Duration) {
+	tckr := time.NewTicker(interval)
+	defer tckr.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tckr.C:
+			if err := p.Prune(ctx); err != nil {
+				p.log.Error("error pruning heights", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Prune applies the retention policy of every chain relative to its processed height
+func (p *Pruner) Prune(ctx context.Context) error {
+	if len(p.policies) == 0 {
+		return nil
+	}
+
+	heights, err := p.st.GetLatestHeights(ctx)
+	if err != nil {
+		return err
+	}
+
+	for chainID, policy := range p.policies {
+		latest, ok := heights[chainID]
+		if !ok {
+			continue
+		}
+
+		if below := policy.PruneBelow(latest); below > p.PrunedBelow(chainID) {
+			if err := p.st.PruneHeights(ctx, chainID, below); err != nil {
+				return fmt.Errorf("error pruning chain %s: %w", chainID, err)
+			}
+
+			p.pl.Lock()
+			p.pruned[chainID] = below
+			p.pl.Unlock()
+			p.log.Info("pruned heights", zap.String("chain_id", chainID), zap.Uint64("below", below))
+		}
+
+		if below := policy.SlimBelow(latest); below > p.PrunedBelow(chainID) {
+			if err := p.st.SlimHeights(ctx, chainID, below); err != nil {
+				return fmt.Errorf("error slimming chain %s: %w", chainID, err)
+			}
+			p.log.Debug("slimmed heights", zap.String("chain_id", chainID), zap.Uint64("below", below))
+		}
+	}
+
+	return nil
+}
+
+// PrunedBelow returns the height below which the data of the chain is pruned
+func (p *Pruner) PrunedBelow(chainID string) uint64 {
+	p.pl.RLock()
+	defer p.pl.RUnlock()
+	return p.pruned[chainID]
+}
+
+// CheckHeight returns ErrPruned if the data of given height is pruned, zero height is never pruned
+func (p *Pruner) CheckHeight(chainID string, height uint64) error {
+	if below := p.PrunedBelow(chainID); height > 0 && height < below {
+		return fmt.Errorf("%w: data of chain %s is kept from height %d", structs.ErrPruned, chainID, below)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package retention_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/figment-networks/graph-demo/manager/retention"
+	"github.com/figment-networks/graph-demo/manager/store/memory"
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRetentionPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     structs.RetentionPolicy
+		latest     uint64
+		pruneBelow uint64
+		slimBelow  uint64
+	}{
+		{name: "keep everything", latest: 100},
+		{name: "keep last", policy: structs.RetentionPolicy{KeepLast: 10}, latest: 100, pruneBelow: 91},
+		{name: "keep last, not reached", policy: structs.RetentionPolicy{KeepLast: 10}, latest: 10},
+		{name: "keep from", policy: structs.RetentionPolicy{KeepFrom: 50}, latest: 100, pruneBelow: 50},
+		{name: "keep from lower than keep last", policy: structs.RetentionPolicy{KeepLast: 10, KeepFrom: 50}, latest: 100, pruneBelow: 50},
+		{name: "keep last lower than keep from", policy: structs.RetentionPolicy{KeepLast: 10, KeepFrom: 95}, latest: 100, pruneBelow: 91},
+		{name: "slim after", policy: structs.RetentionPolicy{SlimAfter: 20}, latest: 100, slimBelow: 81},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.pruneBelow, tt.policy.PruneBelow(tt.latest))
+			assert.Equal(t, tt.slimBelow, tt.policy.SlimBelow(tt.latest))
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	ctx := context.Background()
+	d := memory.NewDriver()
+
+	var heights []structs.BlockAndTx
+	for h := uint64(1); h <= 10; h++ {
+		hash := fmt.Sprintf("BLOCK%d", h)
+		heights = append(heights, structs.BlockAndTx{
+			Block: structs.Block{ChainID: "chain", Height: h, Hash: hash, LastCommit: &structs.Commit{Height: int64(h) - 1}},
+			Transactions: []structs.Transaction{
+				{ChainID: "chain", Height: h, Hash: fmt.Sprintf("TX%d", h), BlockHash: hash, RawLog: []byte("log")},
+			},
+		})
+	}
+	require.NoError(t, d.StoreHeights(ctx, heights))
+	require.NoError(t, d.SetLatestHeight(ctx, "chain", 10))
+
+	p := retention.NewPruner(zap.NewNop(), d, map[string]structs.RetentionPolicy{
+		"chain": {KeepLast: 6, SlimAfter: 3},
+	})
+	require.NoError(t, p.Prune(ctx))
+
+	missing, err := d.GetMissingHeights(ctx, "chain", 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2, 3, 4}, missing)
+
+	blocks, err := d.GetBlocks(ctx, structs.RangeQuery{ChainID: "chain", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, blocks, 6)
+	for _, b := range blocks {
+		assert.Equal(t, b.Height < 8, b.LastCommit == nil, "last commit of height %d", b.Height)
+	}
+
+	txs, err := d.GetTransactions(ctx, structs.RangeQuery{ChainID: "chain", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, txs, 6)
+	for _, tx := range txs {
+		assert.Equal(t, tx.Height < 8, len(tx.RawLog) == 0, "raw log of height %d", tx.Height)
+	}
+
+	assert.ErrorIs(t, p.CheckHeight("chain", 4), structs.ErrPruned)
+	assert.NoError(t, p.CheckHeight("chain", 5))
+	assert.NoError(t, p.CheckHeight("chain", 0))
+	assert.NoError(t, p.CheckHeight("other", 1))
+
+	// pruned heights survive restart
+	restarted := retention.NewPruner(zap.NewNop(), d, nil)
+	require.NoError(t, restarted.Load(ctx))
+	assert.Equal(t, uint64(5), restarted.PrunedBelow("chain"))
+}
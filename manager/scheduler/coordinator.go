@@ -0,0 +1,386 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/figment-networks/graph-demo/manager/client"
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	dispatchInterval = time.Second
+	latestInterval   = 10 * time.Second
+	retryInterval    = 10 * time.Second
+)
+
+type worker struct {
+	connID string
+	nc     client.NetworkClient
+	cancel context.CancelFunc
+
+	busy        bool
+	pausedUntil time.Time
+}
+
+type leaseResult struct {
+	connID string
+	// jobID is uuid.Nil for live sync leases
+	jobID uuid.UUID
+	// heights processed successfully
+	done []uint64
+	// heights that were not processed, the first one is the one that failed
+	failed []uint64
+	err    error
+}
+
+type jobState struct {
+	id uuid.UUID
+	q  *queue
+}
+
+// Coordinator owns the queues of heights of a single chain.
+// It leases ranges of heights to all the workers connected for that chain,
+// retries failed heights on other workers and commits progress
+// only up to the highest contiguous completed height.
+//
+// Live sync takes precedence over backfill jobs, jobs are processed in the order they were added.
+type Coordinator struct {
+	log       *zap.Logger
+	c         Clienter
+	jr        JobReporter
+	chainID   string
+	leaseSize uint64
+
+	workers map[string]*worker
+	live    *queue
+	jobs    []*jobState
+	l       sync.Mutex
+
+	lastRefresh time.Time
+
+	results chan leaseResult
+	wake    chan struct{}
+}
+
+func NewCoordinator(log *zap.Logger, c Clienter, jr JobReporter, chainID string, leaseSize uint64) *Coordinator {
+	return &Coordinator{
+		log:       log,
+		c:         c,
+		jr:        jr,
+		chainID:   chainID,
+		leaseSize: leaseSize,
+		workers:   make(map[string]*worker),
+		results:   make(chan leaseResult, 10),
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+func (co *Coordinator) AddWorker(connID string, nc client.NetworkClient) {
+	co.l.Lock()
+	co.workers[connID] = &worker{connID: connID, nc: nc}
+	co.l.Unlock()
+
+	co.notify()
+}
+
+func (co *Coordinator) RemoveWorker(connID string) {
+	co.l.Lock()
+	defer co.l.Unlock()
+
+	w, ok := co.workers[connID]
+	if !ok {
+		return
+	}
+	if w.cancel != nil {
+		w.cancel()
+	}
+	delete(co.workers, connID)
+}
+
+// AddJob adds backfill job of heights (j.Current, j.To]
+func (co *Coordinator) AddJob(j structs.BackfillJob) {
+	co.l.Lock()
+	for _, js := range co.jobs {
+		if js.id == j.ID {
+			co.l.Unlock()
+			return
+		}
+	}
+	co.jobs = append(co.jobs, &jobState{id: j.ID, q: newQueue(j.Current, j.To)})
+	co.l.Unlock()
+
+	co.notify()
+}
+
+// RemoveJob stops processing of the job, heights that are being processed are not interrupted
+func (co *Coordinator) RemoveJob(id uuid.UUID) {
+	co.l.Lock()
+	defer co.l.Unlock()
+
+	for i, js := range co.jobs {
+		if js.id == id {
+			co.jobs = append(co.jobs[:i], co.jobs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (co *Coordinator) notify() {
+	select {
+	case co.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run processes the queues until ctx is done. Live sync starts from the height following
+// the one persisted in the storage or from lowestHeight if it's greater.
+func (co *Coordinator) Run(ctx context.Context, lowestHeight uint64) {
+	h, err := co.c.GetLatestFromStorage(ctx, co.chainID)
+	if err != nil {
+		co.log.Error("error getting height", zap.String("chain_id", co.chainID), zap.Uint64("height", h), zap.Error(err))
+	}
+
+	if lowestHeight > 0 && h < lowestHeight {
+		h = lowestHeight - 1
+	}
+
+	co.l.Lock()
+	co.live = newQueue(h, h)
+	co.l.Unlock()
+
+	tckr := time.NewTicker(dispatchInterval)
+	defer tckr.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-co.wake:
+		case r := <-co.results:
+			co.handleResult(ctx, r)
+		case <-tckr.C:
+		}
+
+		co.refreshLatest(ctx)
+		co.dispatch(ctx)
+	}
+}
+
+// refreshLatest asks workers for the latest height of the chain. It happens
+// every latestInterval or as soon as live sync has nothing more to lease.
+func (co *Coordinator) refreshLatest(ctx context.Context) {
+	since := time.Since(co.lastRefresh)
+	if since < dispatchInterval {
+		return
+	}
+
+	co.l.Lock()
+	if since < latestInterval && co.live.next <= co.live.last {
+		co.l.Unlock()
+		return
+	}
+
+	ncs := make([]client.NetworkClient, 0, len(co.workers))
+	now := time.Now()
+	for _, w := range co.workers {
+		if now.After(w.pausedUntil) {
+			ncs = append(ncs, w.nc)
+		}
+	}
+	co.l.Unlock()
+
+	for _, nc := range ncs {
+		lb, err := co.c.GetLatest(ctx, nc)
+		if err != nil {
+			co.log.Error("error getting latest height", zap.String("chain_id", co.chainID), zap.Error(err))
+			continue
+		}
+		co.lastRefresh = time.Now()
+
+		co.l.Lock()
+		if lb > co.live.last {
+			co.live.last = lb
+		}
+		co.l.Unlock()
+		return
+	}
+}
+
+func (co *Coordinator) dispatch(ctx context.Context) {
+	co.l.Lock()
+	defer co.l.Unlock()
+
+	now := time.Now()
+	for _, w := range co.workers {
+		if w.busy || now.Before(w.pausedUntil) {
+			continue
+		}
+
+		jobID := uuid.Nil
+		heights := co.live.lease(w.connID, co.leaseSize, co.workers)
+		for _, js := range co.jobs {
+			if len(heights) > 0 {
+				break
+			}
+			heights = js.q.lease(w.connID, co.leaseSize, co.workers)
+			jobID = js.id
+		}
+
+		if len(heights) == 0 {
+			continue
+		}
+
+		w.busy = true
+		wCtx, cancel := context.WithCancel(ctx)
+		w.cancel = cancel
+		go co.process(ctx, wCtx, w.connID, w.nc, jobID, heights)
+	}
+}
+
+// process processes leased heights with wCtx, cancelled once the worker is removed.
+// The result is sent unless ctx of Run is done, so heights of removed worker are rerouted.
+func (co *Coordinator) process(ctx, wCtx context.Context, connID string, nc client.NetworkClient, jobID uuid.UUID, heights []uint64) {
+	r := leaseResult{connID: connID, jobID: jobID}
+	for i, h := range heights {
+		var err error
+		if jobID == uuid.Nil {
+			err = co.c.ProcessHeight(wCtx, nc, co.chainID, h)
+		} else {
+			err = co.c.FetchHeight(wCtx, nc, co.chainID, h)
+		}
+
+		if err != nil {
+			r.failed = heights[i:]
+			r.err = err
+			break
+		}
+		r.done = append(r.done, h)
+	}
+
+	// backfilled heights may be written in batches, they're done once they're written
+	if jobID != uuid.Nil && len(r.done) > 0 {
+		if err := co.c.Flush(wCtx); err != nil {
+			r.failed = append(r.done, r.failed...)
+			r.done = nil
+			r.err = err
+		}
+	}
+
+	select {
+	case co.results <- r:
+	case <-ctx.Done():
+	}
+}
+
+func (co *Coordinator) handleResult(ctx context.Context, r leaseResult) {
+	co.l.Lock()
+	if w, ok := co.workers[r.connID]; ok {
+		w.busy = false
+		w.cancel()
+		if r.err != nil {
+			co.penalize(w, r.err)
+		}
+	}
+
+	q := co.live
+	if r.jobID != uuid.Nil {
+		q = nil
+		for _, js := range co.jobs {
+			if js.id == r.jobID {
+				q = js.q
+				break
+			}
+		}
+	}
+
+	if q == nil { // job was removed in the meantime
+		co.l.Unlock()
+		return
+	}
+
+	q.done(r.done)
+	if len(r.failed) > 0 {
+		co.log.Error("error processing height", zap.String("chain_id", co.chainID), zap.String("conn_id", r.connID),
+			zap.Stringer("job_id", r.jobID), zap.Uint64("height", r.failed[0]), zap.Error(r.err))
+		q.fail(r.connID, r.failed)
+	}
+
+	h := q.contiguous()
+	co.l.Unlock()
+
+	if r.jobID == uuid.Nil {
+		co.commitLive(ctx, h)
+		return
+	}
+
+	if r.err != nil && co.jr != nil {
+		if err := co.jr.JobError(ctx, r.jobID, r.failed[0], r.err); err != nil {
+			co.log.Error("error reporting job error", zap.Stringer("job_id", r.jobID), zap.Error(err))
+		}
+	}
+	co.commitJob(ctx, r.jobID, q, h)
+}
+
+// penalize excludes worker from leasing after failure, the heights it failed are rerouted to other workers.
+// Worker is removed only once its session is closed, otherwise failures are left to its circuit breaker.
+// The one with open circuit breaker waits until the circuit is half-open.
+func (co *Coordinator) penalize(w *worker, err error) {
+	switch client.Classify(err) {
+	case client.ClassWorkerDisconnected:
+		if c, ok := w.nc.(interface{ Closed() bool }); ok && c.Closed() {
+			delete(co.workers, w.connID)
+			return
+		}
+		w.pausedUntil = time.Now().Add(retryInterval)
+	case client.ClassCircuitOpen:
+		w.pausedUntil = time.Now().Add(retryInterval)
+		if b, ok := w.nc.(interface{ OpenUntil() time.Time }); ok {
+			w.pausedUntil = b.OpenUntil()
+		}
+	default:
+		w.pausedUntil = time.Now().Add(retryInterval)
+	}
+}
+
+// commitLive persists the highest contiguous completed height of live sync
+func (co *Coordinator) commitLive(ctx context.Context, h uint64) {
+	if h == co.live.committed {
+		return
+	}
+
+	if err := co.c.SetLatestFromStorage(ctx, co.chainID, h); err != nil {
+		co.log.Error("error setting latest height", zap.String("chain_id", co.chainID), zap.Uint64("height", h), zap.Error(err))
+		return
+	}
+
+	co.l.Lock()
+	co.live.commit(h)
+	co.l.Unlock()
+}
+
+// commitJob reports job progress, removing finished job from the coordinator
+func (co *Coordinator) commitJob(ctx context.Context, id uuid.UUID, q *queue, h uint64) {
+	if h == q.committed {
+		return
+	}
+
+	if co.jr != nil {
+		if err := co.jr.JobProgress(ctx, id, h); err != nil {
+			co.log.Error("error reporting job progress", zap.Stringer("job_id", id), zap.Uint64("height", h), zap.Error(err))
+			return
+		}
+	}
+
+	co.l.Lock()
+	q.commit(h)
+	co.l.Unlock()
+
+	if q.finished() {
+		co.RemoveJob(id)
+	}
+}
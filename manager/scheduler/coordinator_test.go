@@ -0,0 +1,181 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/figment-networks/graph-demo/manager/client"
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type ncMock struct {
+	closed bool
+}
+
+func (nm *ncMock) GetAll(ctx context.Context, height uint64) error { return nil }
+
+func (nm *ncMock) GetLatest(ctx context.Context) (uint64, error) { return 0, nil }
+
+func (nm *ncMock) Closed() bool { return nm.closed }
+
+type clienterMock struct{}
+
+func (cm clienterMock) ProcessHeight(ctx context.Context, nc client.NetworkClient, chainID string, height uint64) error {
+	return nil
+}
+
+func (cm clienterMock) FetchHeight(ctx context.Context, nc client.NetworkClient, chainID string, height uint64) error {
+	return nil
+}
+
+func (cm clienterMock) Flush(ctx context.Context) error { return nil }
+
+func (cm clienterMock) GetLatest(ctx context.Context, nc client.NetworkClient) (uint64, error) {
+	return 0, nil
+}
+
+func (cm clienterMock) GetLatestFromStorage(ctx context.Context, chainID string) (uint64, error) {
+	return 0, nil
+}
+
+func (cm clienterMock) SetLatestFromStorage(ctx context.Context, chainID string, height uint64) error {
+	return nil
+}
+
+func TestProcessReturnsOnceRunIsDone(t *testing.T) {
+	co := NewCoordinator(zap.NewNop(), clienterMock{}, nil, "chain", 10)
+	// results are not read anymore
+	for i := 0; i < cap(co.results); i++ {
+		co.results <- leaseResult{}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		co.process(ctx, ctx, "w1", &ncMock{}, uuid.Nil, []uint64{1, 2})
+		close(done)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("process is blocked sending the result")
+	}
+}
+
+func TestPenalizeDisconnected(t *testing.T) {
+	errDisconnected := fmt.Errorf("%w: connection closed", structs.ErrWorkerDisconnected)
+
+	t.Run("session open", func(t *testing.T) {
+		co := NewCoordinator(zap.NewNop(), nil, nil, "chain", 10)
+		co.AddWorker("w1", &ncMock{})
+
+		co.penalize(co.workers["w1"], errDisconnected)
+		w, ok := co.workers["w1"]
+		assert.True(t, ok, "worker is kept")
+		assert.True(t, w.pausedUntil.After(time.Now()), "worker is paused")
+	})
+
+	t.Run("session closed", func(t *testing.T) {
+		co := NewCoordinator(zap.NewNop(), nil, nil, "chain", 10)
+		co.AddWorker("w1", &ncMock{closed: true})
+
+		co.penalize(co.workers["w1"], errDisconnected)
+		_, ok := co.workers["w1"]
+		assert.False(t, ok, "worker is removed")
+	})
+}
+
+// workerNC fails the heights in fail, reporting the heights it got
+type workerNC struct {
+	id   string
+	fail map[uint64]bool
+	got  chan<- processed
+}
+
+type processed struct {
+	connID string
+	height uint64
+	err    error
+}
+
+func (wn *workerNC) GetAll(ctx context.Context, height uint64) error {
+	var err error
+	if wn.fail[height] {
+		err = errors.New("broken height")
+	}
+	wn.got <- processed{connID: wn.id, height: height, err: err}
+	return err
+}
+
+func (wn *workerNC) GetLatest(ctx context.Context) (uint64, error) { return 5, nil }
+
+// liveClienter gets the heights from the workers and records the committed ones
+type liveClienter struct {
+	clienterMock
+	committed chan uint64
+}
+
+func (lc liveClienter) ProcessHeight(ctx context.Context, nc client.NetworkClient, chainID string, height uint64) error {
+	return nc.GetAll(ctx, height)
+}
+
+func (lc liveClienter) GetLatest(ctx context.Context, nc client.NetworkClient) (uint64, error) {
+	return nc.GetLatest(ctx)
+}
+
+func (lc liveClienter) SetLatestFromStorage(ctx context.Context, chainID string, height uint64) error {
+	lc.committed <- height
+	return nil
+}
+
+func TestCoordinatorReroutesFailedHeight(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got := make(chan processed, 20)
+	lc := liveClienter{committed: make(chan uint64, 20)}
+	co := NewCoordinator(zap.NewNop(), lc, nil, "chain", 1)
+	co.AddWorker("w1", &workerNC{id: "w1", fail: map[uint64]bool{2: true}, got: got})
+	co.AddWorker("w2", &workerNC{id: "w2", got: got})
+	go co.Run(ctx, 0)
+
+	done := make(map[uint64]string)
+	record := func(p processed) {
+		if p.err == nil {
+			done[p.height] = p.connID
+		}
+	}
+
+	var committed uint64
+	for committed < 5 {
+		select {
+		case p := <-got:
+			record(p)
+		case c := <-lc.committed:
+			// heights are reported before their results reach the coordinator
+			for len(got) > 0 {
+				record(<-got)
+			}
+			require.Greater(t, c, committed)
+			for h := uint64(1); h <= c; h++ {
+				_, ok := done[h]
+				require.True(t, ok, "height %d committed before it's done", h)
+			}
+			committed = c
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout, committed %d, done %v", committed, done)
+		}
+	}
+
+	assert.Equal(t, "w2", done[2], "failed height is processed by the other worker")
+}
@@ -0,0 +1,120 @@
+package scheduler
+
+import (
+	"sort"
+)
+
+// queue keeps track of heights in range (committed, last]
+type queue struct {
+	// next is the lowest height that was never leased
+	next uint64
+	// last is the highest height that may be leased
+	last uint64
+	// committed is the highest contiguous completed height
+	committed uint64
+
+	retry     []uint64
+	failedOn  map[uint64]map[string]struct{}
+	completed map[uint64]struct{}
+}
+
+func newQueue(committed, last uint64) *queue {
+	return &queue{
+		next:      committed + 1,
+		last:      last,
+		committed: committed,
+		failedOn:  make(map[uint64]map[string]struct{}),
+		completed: make(map[uint64]struct{}),
+	}
+}
+
+// lease picks up to size heights for given worker. Heights to be retried go first,
+// the rest is filled with the ones that were never leased.
+func (q *queue) lease(connID string, size uint64, workers map[string]*worker) (heights []uint64) {
+	rest := q.retry[:0]
+	for _, h := range q.retry {
+		if uint64(len(heights)) < size && q.canProcess(h, connID, workers) {
+			heights = append(heights, h)
+			continue
+		}
+		rest = append(rest, h)
+	}
+	q.retry = rest
+
+	for uint64(len(heights)) < size && q.next <= q.last {
+		heights = append(heights, q.next)
+		q.next++
+	}
+
+	return heights
+}
+
+// canProcess checks if height didn't fail on given worker.
+// When height failed on every connected worker, all of them are allowed to try again.
+func (q *queue) canProcess(height uint64, connID string, workers map[string]*worker) bool {
+	fo, ok := q.failedOn[height]
+	if !ok {
+		return true
+	}
+
+	if _, ok := fo[connID]; !ok {
+		return true
+	}
+
+	for id := range workers {
+		if _, ok := fo[id]; !ok {
+			return false
+		}
+	}
+
+	delete(q.failedOn, height)
+	return true
+}
+
+func (q *queue) done(heights []uint64) {
+	for _, h := range heights {
+		q.completed[h] = struct{}{}
+		delete(q.failedOn, h)
+	}
+}
+
+// fail puts heights back to the queue, marking the first one as failed on given worker
+func (q *queue) fail(connID string, heights []uint64) {
+	if len(heights) == 0 {
+		return
+	}
+
+	fo, ok := q.failedOn[heights[0]]
+	if !ok {
+		fo = make(map[string]struct{})
+		q.failedOn[heights[0]] = fo
+	}
+	fo[connID] = struct{}{}
+
+	q.retry = append(q.retry, heights...)
+	sort.Slice(q.retry, func(i, j int) bool { return q.retry[i] < q.retry[j] })
+}
+
+// contiguous returns the highest contiguous completed height
+func (q *queue) contiguous() uint64 {
+	h := q.committed
+	for {
+		if _, ok := q.completed[h+1]; !ok {
+			return h
+		}
+		h++
+	}
+}
+
+// commit moves committed height up to h
+func (q *queue) commit(h uint64) {
+	for i := q.committed + 1; i <= h; i++ {
+		delete(q.completed, i)
+	}
+	q.committed = h
+}
+
+// finished reports if every height of the queue is committed
+func (q *queue) finished() bool {
+	return q.committed >= q.last
+}
@@ -0,0 +1,161 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func workersOf(ids ...string) map[string]*worker {
+	workers := make(map[string]*worker, len(ids))
+	for _, id := range ids {
+		workers[id] = &worker{connID: id}
+	}
+	return workers
+}
+
+func TestQueueLease(t *testing.T) {
+	tests := []struct {
+		name    string
+		queue   func() *queue
+		connID  string
+		size    uint64
+		workers map[string]*worker
+		heights []uint64
+		next    uint64
+	}{
+		{
+			name:    "new heights",
+			queue:   func() *queue { return newQueue(10, 20) },
+			connID:  "w1",
+			size:    3,
+			workers: workersOf("w1", "w2"),
+			heights: []uint64{11, 12, 13},
+			next:    14,
+		},
+		{
+			name:    "up to last",
+			queue:   func() *queue { return newQueue(10, 12) },
+			connID:  "w1",
+			size:    5,
+			workers: workersOf("w1"),
+			heights: []uint64{11, 12},
+			next:    13,
+		},
+		{
+			name: "retried heights first",
+			queue: func() *queue {
+				q := newQueue(10, 20)
+				q.lease("w2", 3, nil)
+				q.fail("w2", []uint64{12, 13})
+				return q
+			},
+			connID:  "w1",
+			size:    3,
+			workers: workersOf("w1", "w2"),
+			heights: []uint64{12, 13, 14},
+			next:    15,
+		},
+		{
+			name: "failed height skipped on the worker that failed it",
+			queue: func() *queue {
+				q := newQueue(10, 20)
+				q.lease("w1", 3, nil)
+				q.fail("w1", []uint64{12, 13})
+				return q
+			},
+			connID:  "w1",
+			size:    2,
+			workers: workersOf("w1", "w2"),
+			heights: []uint64{13, 14},
+			next:    15,
+		},
+		{
+			name: "failed height retried when it failed on every worker",
+			queue: func() *queue {
+				q := newQueue(10, 20)
+				q.lease("w1", 1, nil)
+				q.fail("w1", []uint64{11})
+				return q
+			},
+			connID:  "w1",
+			size:    1,
+			workers: workersOf("w1"),
+			heights: []uint64{11},
+			next:    12,
+		},
+		{
+			name:    "nothing to lease",
+			queue:   func() *queue { return newQueue(20, 20) },
+			connID:  "w1",
+			size:    1,
+			workers: workersOf("w1"),
+			next:    21,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.queue()
+			assert.Equal(t, tt.heights, q.lease(tt.connID, tt.size, tt.workers))
+			assert.Equal(t, tt.next, q.next)
+		})
+	}
+}
+
+func TestQueueFailMovesHeightToOtherWorker(t *testing.T) {
+	q := newQueue(0, 3)
+	workers := workersOf("w1", "w2")
+
+	assert.Equal(t, []uint64{1, 2, 3}, q.lease("w1", 3, workers))
+	q.done([]uint64{1})
+	q.fail("w1", []uint64{2, 3})
+
+	// only the first height failed on the worker, the rest is retried anywhere
+	assert.Equal(t, []uint64{3}, q.lease("w1", 3, workers))
+	assert.Equal(t, []uint64{2}, q.lease("w2", 3, workers))
+	assert.Empty(t, q.retry)
+
+	q.done([]uint64{2})
+	_, ok := q.failedOn[2]
+	assert.False(t, ok, "done height is forgotten as failed")
+}
+
+func TestQueueContiguous(t *testing.T) {
+	tests := []struct {
+		name       string
+		committed  uint64
+		done       []uint64
+		contiguous uint64
+	}{
+		{name: "nothing done", committed: 10, contiguous: 10},
+		{name: "all done", committed: 10, done: []uint64{11, 12, 13}, contiguous: 13},
+		{name: "out of order", committed: 10, done: []uint64{13, 11, 12}, contiguous: 13},
+		{name: "gap", committed: 10, done: []uint64{11, 13, 14}, contiguous: 11},
+		{name: "gap at start", committed: 10, done: []uint64{12, 13}, contiguous: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newQueue(tt.committed, 20)
+			q.done(tt.done)
+			assert.Equal(t, tt.contiguous, q.contiguous())
+		})
+	}
+}
+
+func TestQueueCommit(t *testing.T) {
+	q := newQueue(10, 14)
+	q.done([]uint64{11, 12, 14})
+
+	q.commit(q.contiguous())
+	assert.Equal(t, uint64(12), q.committed)
+	assert.Len(t, q.completed, 1, "committed heights are forgotten")
+	assert.False(t, q.finished())
+
+	q.done([]uint64{13})
+	q.commit(q.contiguous())
+	assert.Equal(t, uint64(14), q.committed)
+	assert.Empty(t, q.completed)
+	assert.True(t, q.finished())
+}
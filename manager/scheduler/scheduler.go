@@ -2,85 +2,116 @@ package scheduler
 
 import (
 	"context"
-	"time"
+	"sync"
 
 	"github.com/figment-networks/graph-demo/manager/client"
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
 type Clienter interface {
-	ProcessHeight(ctx context.Context, nc client.NetworkClient, height uint64) (err error)
+	ProcessHeight(ctx context.Context, nc client.NetworkClient, chainID string, height uint64) (err error)
+	FetchHeight(ctx context.Context, nc client.NetworkClient, chainID string, height uint64) (err error)
+	Flush(ctx context.Context) (err error)
 	GetLatest(ctx context.Context, nc client.NetworkClient) (height uint64, err error)
 
 	GetLatestFromStorage(ctx context.Context, chainID string) (height uint64, err error)
 	SetLatestFromStorage(ctx context.Context, chainID string, height uint64) (err error)
 }
 
+// JobReporter receives the progress of backfill jobs
+type JobReporter interface {
+	JobProgress(ctx context.Context, id uuid.UUID, current uint64) error
+	JobError(ctx context.Context, id uuid.UUID, height uint64, err error) error
+}
+
+// Scheduler keeps one Coordinator per chain and hands every connected worker to it
 type Scheduler struct {
+	ctx           context.Context
 	log           *zap.Logger
 	c             Clienter
+	jr            JobReporter
 	lowestHeights map[string]uint64
-}
+	leaseSize     uint64
 
-func NewScheduler(log *zap.Logger, c Clienter, lowestHeights map[string]uint64) *Scheduler {
-	return &Scheduler{log: log, c: c, lowestHeights: lowestHeights}
+	coordinators map[string]*Coordinator
+	cl           sync.Mutex
 }
 
-func (s *Scheduler) Start(ctx context.Context, nc client.NetworkClient, connID, chainID string) {
+func NewScheduler(ctx context.Context, log *zap.Logger, c Clienter, lowestHeights map[string]uint64, leaseSize uint64) *Scheduler {
+	if leaseSize == 0 {
+		leaseSize = 1
+	}
 
-	h, err := s.c.GetLatestFromStorage(ctx, chainID)
-	if err != nil {
-		s.log.Error("error getting height", zap.Uint64("height", h), zap.Error(err))
+	return &Scheduler{
+		ctx:           ctx,
+		log:           log,
+		c:             c,
+		lowestHeights: lowestHeights,
+		leaseSize:     leaseSize,
+		coordinators:  make(map[string]*Coordinator),
 	}
+}
+
+// LinkJobReporter sets the receiver of backfill jobs progress.
+// It has to be called before any worker or job is added.
+func (s *Scheduler) LinkJobReporter(jr JobReporter) {
+	s.jr = jr
+}
 
-	if lh, ok := s.lowestHeights[chainID]; ok && h < lh {
-		h = lh
-	} else {
-		h++
+// coordinator returns the coordinator of given chain, starting it if needed
+func (s *Scheduler) coordinator(chainID string) *Coordinator {
+	s.cl.Lock()
+	defer s.cl.Unlock()
+
+	co, ok := s.coordinators[chainID]
+	if !ok {
+		co = NewCoordinator(s.log, s.c, s.jr, chainID, s.leaseSize)
+		s.coordinators[chainID] = co
+		go co.Run(s.ctx, s.lowestHeights[chainID])
 	}
+	return co
+}
 
-	tckr := time.NewTicker(1 * time.Second)
-	defer tckr.Stop()
+// AddWorker links worker with the coordinator of given chain.
+// Worker is removed from the coordinator after ctx is done.
+func (s *Scheduler) AddWorker(ctx context.Context, nc client.NetworkClient, connID, chainID string) {
+	co := s.coordinator(chainID)
+	co.AddWorker(connID, nc)
 
-LatestPrefetch:
-	for {
+	go func() {
 		select {
 		case <-ctx.Done():
-			return
-		case <-tckr.C:
-			lb, err := s.c.GetLatest(ctx, nc)
-			if err != nil {
-				s.log.Error("error getting latest height", zap.Error(err))
-				continue
-			}
-
-			if lb-h > 2 {
-				tckr.Reset(time.Millisecond)
-			} else {
-				tckr.Reset(10 * time.Second)
-			}
-			break LatestPrefetch
+		case <-s.ctx.Done():
 		}
+		co.RemoveWorker(connID)
+	}()
+}
+
+// RemoveWorker removes worker from every coordinator it's linked to
+func (s *Scheduler) RemoveWorker(connID string) {
+	s.cl.Lock()
+	defer s.cl.Unlock()
+
+	for _, co := range s.coordinators {
+		co.RemoveWorker(connID)
 	}
+}
 
-	for {
-		select {
-		case <-tckr.C:
-			// Here we can create multiple queries depending on number of currently connected workers
-			err = s.c.ProcessHeight(ctx, nc, h)
-			if err != nil {
-				s.log.Error("error getting height", zap.Uint64("height", h), zap.Error(err))
-				tckr.Reset(10 * time.Second)
-				continue
-			}
-
-			if err := s.c.SetLatestFromStorage(ctx, chainID, h); err != nil {
-				s.log.Error("error setting latest height", zap.Uint64("height", h), zap.Error(err))
-				continue
-			}
-			h++
-		case <-ctx.Done():
-			return
-		}
+// AddJob starts processing of backfill job alongside the live sync of the chain
+func (s *Scheduler) AddJob(j structs.BackfillJob) {
+	s.coordinator(j.ChainID).AddJob(j)
+}
+
+// RemoveJob stops processing of backfill job
+func (s *Scheduler) RemoveJob(chainID string, id uuid.UUID) {
+	s.cl.Lock()
+	co, ok := s.coordinators[chainID]
+	s.cl.Unlock()
+
+	if ok {
+		co.RemoveJob(id)
 	}
 }
@@ -0,0 +1,156 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+)
+
+// maxHeight returns the upper bound of height range, 0 leaves it open
+func maxHeight(to uint64) uint64 {
+	if to == 0 {
+		return math.MaxInt64
+	}
+	return to
+}
+
+func (d *Driver) GetBlockByHeight(ctx context.Context, height uint64, chainID string) (b structs.Block, err error) {
+	d.l.RLock()
+	defer d.l.RUnlock()
+
+	if c, ok := d.chains[chainID]; ok {
+		for _, b := range c.blocks {
+			if b.Height == height {
+				return b, nil
+			}
+		}
+	}
+
+	return structs.Block{Height: height, ChainID: chainID}, fmt.Errorf("%w, height: %d", structs.ErrNotFound, height)
+}
+
+// GetBlockAtTime returns the latest block of the chain created at or before given time
+func (d *Driver) GetBlockAtTime(ctx context.Context, chainID string, t time.Time) (b structs.Block, err error) {
+	d.l.RLock()
+	defer d.l.RUnlock()
+
+	var found bool
+	if c, ok := d.chains[chainID]; ok {
+		for _, cb := range c.blocks {
+			if cb.Time.After(t) {
+				continue
+			}
+			if !found || cb.Time.After(b.Time) || (cb.Time.Equal(b.Time) && cb.Height > b.Height) {
+				b, found = cb, true
+			}
+		}
+	}
+
+	if !found {
+		return structs.Block{ChainID: chainID}, fmt.Errorf("%w, time: %s", structs.ErrNotFound, t.Format(time.RFC3339))
+	}
+	return b, nil
+}
+
+// inTimeRange checks if the time is within the time range of the query
+func inTimeRange(t time.Time, q structs.RangeQuery) bool {
+	return (q.TimeFrom.IsZero() || !t.Before(q.TimeFrom)) && (q.TimeTo.IsZero() || !t.After(q.TimeTo))
+}
+
+// GetBlocks returns blocks of the chain from the height range ordered by height, starting after the cursor
+func (d *Driver) GetBlocks(ctx context.Context, q structs.RangeQuery) (blocks []structs.Block, err error) {
+	from := q.From
+	if q.After != nil && q.After.Height >= from {
+		from = q.After.Height + 1
+	}
+
+	d.l.RLock()
+	defer d.l.RUnlock()
+
+	c, ok := d.chains[q.ChainID]
+	if !ok {
+		return nil, nil
+	}
+
+	for _, b := range c.blocks {
+		if b.Height >= from && b.Height <= maxHeight(q.To) && inTimeRange(b.Time, q) {
+			blocks = append(blocks, b)
+		}
+	}
+
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Height < blocks[j].Height })
+	if uint64(len(blocks)) > q.Limit {
+		blocks = blocks[:q.Limit]
+	}
+	return blocks, nil
+}
+
+// GetMissingHeights returns heights from range [from, to] that have no block stored
+func (d *Driver) GetMissingHeights(ctx context.Context, chainID string, from, to uint64) (heights []uint64, err error) {
+	d.l.RLock()
+	defer d.l.RUnlock()
+
+	stored := make(map[uint64]bool)
+	if c, ok := d.chains[chainID]; ok {
+		for _, b := range c.blocks {
+			stored[b.Height] = true
+		}
+	}
+
+	for h := from; h <= to; h++ {
+		if !stored[h] {
+			heights = append(heights, h)
+		}
+	}
+	return heights, nil
+}
+
+// GetTxCountMismatches returns heights from range [from, to] where the number of stored transactions
+// is different than the number of transactions in the block data
+func (d *Driver) GetTxCountMismatches(ctx context.Context, chainID string, from, to uint64) (heights []uint64, err error) {
+	d.l.RLock()
+	defer d.l.RUnlock()
+
+	c, ok := d.chains[chainID]
+	if !ok {
+		return nil, nil
+	}
+
+	counts := make(map[uint64]int)
+	for _, tx := range c.txs {
+		counts[tx.Height]++
+	}
+
+	for _, b := range c.blocks {
+		if b.Height >= from && b.Height <= to && len(b.Data.Txs) != counts[b.Height] {
+			heights = append(heights, b.Height)
+		}
+	}
+
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+	return heights, nil
+}
+
+// SetVerifiedHeight stores the height below which the data of the chain was checked to be consistent
+func (d *Driver) SetVerifiedHeight(ctx context.Context, chainID string, below uint64) error {
+	d.l.Lock()
+	defer d.l.Unlock()
+	d.verified[chainID] = below
+	return nil
+}
+
+// GetVerifiedHeights returns the height below which the data was checked to be consistent, for every chain
+func (d *Driver) GetVerifiedHeights(ctx context.Context) (heights map[string]uint64, err error) {
+	d.l.RLock()
+	defer d.l.RUnlock()
+
+	heights = make(map[string]uint64, len(d.verified))
+	for chainID, h := range d.verified {
+		heights[chainID] = h
+	}
+	return heights, nil
+}
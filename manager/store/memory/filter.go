@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"strings"
+	"time"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+)
+
+// matches checks if transaction matches the validated condition
+func matches(tx structs.Transaction, c structs.TxCondition) bool {
+	switch c.Field {
+	case structs.TxFieldHash:
+		return compareString(tx.Hash, c)
+	case structs.TxFieldBlockHash:
+		return compareString(tx.BlockHash, c)
+	case structs.TxFieldMemo:
+		return compareString(tx.Memo, c)
+	case structs.TxFieldHeight:
+		return compareUint(tx.Height, c)
+	case structs.TxFieldCode:
+		return compareUint(tx.Code, c)
+	case structs.TxFieldTime:
+		return compareTime(tx.Time, c)
+	case structs.TxFieldSigner:
+		// signers are the senders of messages found in the logs
+		for _, l := range tx.Logs {
+			for _, ev := range l.Events {
+				if ev.Type == "message" && ev.Attributes["sender"] == c.Value.(string) {
+					return true
+				}
+			}
+		}
+		return false
+	case structs.TxFieldAddress:
+		return contains(tx.Parties, c.Value.(string))
+	}
+	return false
+}
+
+func compareString(v string, c structs.TxCondition) bool {
+	switch c.Op {
+	case structs.OpEq:
+		return v == c.Value.(string)
+	case structs.OpIn:
+		return contains(c.Value.([]string), v)
+	case structs.OpContains:
+		return strings.Contains(v, c.Value.(string))
+	}
+	return false
+}
+
+func compareUint(v uint64, c structs.TxCondition) bool {
+	if c.Op == structs.OpIn {
+		for _, u := range c.Value.([]uint64) {
+			if v == u {
+				return true
+			}
+		}
+		return false
+	}
+
+	u := c.Value.(uint64)
+	switch c.Op {
+	case structs.OpEq:
+		return v == u
+	case structs.OpNeq:
+		return v != u
+	case structs.OpLt:
+		return v < u
+	case structs.OpLte:
+		return v <= u
+	case structs.OpGt:
+		return v > u
+	case structs.OpGte:
+		return v >= u
+	}
+	return false
+}
+
+func compareTime(v time.Time, c structs.TxCondition) bool {
+	t := c.Value.(time.Time)
+	switch c.Op {
+	case structs.OpEq:
+		return v.Equal(t)
+	case structs.OpLt:
+		return v.Before(t)
+	case structs.OpLte:
+		return !v.After(t)
+	case structs.OpGt:
+		return v.After(t)
+	case structs.OpGte:
+		return !v.Before(t)
+	}
+	return false
+}
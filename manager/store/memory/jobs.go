@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+	"github.com/google/uuid"
+)
+
+func (d *Driver) CreateJob(ctx context.Context, j structs.BackfillJob) (structs.BackfillJob, error) {
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	now := time.Now()
+	j.ID = uuid.New()
+	j.CreatedAt = &now
+	d.jobs = append(d.jobs, j)
+	return j, nil
+}
+
+func (d *Driver) UpdateJob(ctx context.Context, j structs.BackfillJob) error {
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	for i, stored := range d.jobs {
+		if stored.ID == j.ID {
+			now := time.Now()
+			stored.Current = j.Current
+			stored.Status = j.Status
+			stored.LastError = j.LastError
+			stored.ErrorCount = j.ErrorCount
+			stored.UpdatedAt = &now
+			d.jobs[i] = stored
+		}
+	}
+	return nil
+}
+
+func (d *Driver) GetJob(ctx context.Context, id uuid.UUID) (j structs.BackfillJob, err error) {
+	d.l.RLock()
+	defer d.l.RUnlock()
+
+	for _, j := range d.jobs {
+		if j.ID == id {
+			return j, nil
+		}
+	}
+	return j, structs.ErrNotFound
+}
+
+// GetJobs returns jobs of given chain (or every chain if chainID is empty) in the order of creation
+func (d *Driver) GetJobs(ctx context.Context, chainID string, statuses ...structs.JobStatus) (jobs []structs.BackfillJob, err error) {
+	d.l.RLock()
+	defer d.l.RUnlock()
+
+	for _, j := range d.jobs {
+		if chainID != "" && j.ChainID != chainID {
+			continue
+		}
+		if len(statuses) > 0 && !hasStatus(statuses, j.Status) {
+			continue
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, nil
+}
+
+func hasStatus(statuses []structs.JobStatus, s structs.JobStatus) bool {
+	for _, st := range statuses {
+		if st == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+)
+
+type txKey struct {
+	hash   string
+	height uint64
+}
+
+type msgKey struct {
+	txHash string
+	index  uint64
+}
+
+// chain keeps the data of a single chain, with the same unique keys as the tables of postgres driver
+type chain struct {
+	blocks map[string]structs.Block
+	txs    map[txKey]structs.Transaction
+	msgs   map[msgKey]structs.Message
+}
+
+// Driver is in-memory database driver implementation, it's meant for tests and demos.
+// Queries have the same semantics as the ones of postgres driver.
+type Driver struct {
+	chains   map[string]*chain
+	progress map[string]uint64
+	pruned   map[string]uint64
+	verified map[string]uint64
+	jobs     []structs.BackfillJob
+	l        sync.RWMutex
+}
+
+// NewDriver is Driver constructor
+func NewDriver() *Driver {
+	return &Driver{
+		chains:   make(map[string]*chain),
+		progress: make(map[string]uint64),
+		pruned:   make(map[string]uint64),
+		verified: make(map[string]uint64),
+	}
+}
+
+func (d *Driver) Close() error {
+	return nil
+}
+
+// chain returns the data of the chain, creating it if it doesn't exist. The caller has to hold write lock.
+func (d *Driver) chain(chainID string) *chain {
+	c, ok := d.chains[chainID]
+	if !ok {
+		c = &chain{
+			blocks: make(map[string]structs.Block),
+			txs:    make(map[txKey]structs.Transaction),
+			msgs:   make(map[msgKey]structs.Message),
+		}
+		d.chains[chainID] = c
+	}
+	return c
+}
+
+// StoreBlock stores the block
+func (d *Driver) StoreBlock(ctx context.Context, b structs.Block) error {
+	return d.StoreHeights(ctx, []structs.BlockAndTx{{Block: b}})
+}
+
+// StoreTransactions stores the transactions with their messages
+func (d *Driver) StoreTransactions(ctx context.Context, txs []structs.Transaction) error {
+	return d.StoreHeights(ctx, []structs.BlockAndTx{{Transactions: txs}})
+}
+
+// StoreHeights stores the blocks with their transactions and messages at once, blocks without hash are skipped
+func (d *Driver) StoreHeights(ctx context.Context, heights []structs.BlockAndTx) error {
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	for _, h := range heights {
+		if h.Block.Hash != "" {
+			d.chain(h.Block.ChainID).blocks[h.Block.Hash] = h.Block
+		}
+
+		for _, tx := range h.Transactions {
+			c := d.chain(tx.ChainID)
+			c.txs[txKey{tx.Hash, tx.Height}] = tx
+			for _, m := range structs.TransactionMessages(tx) {
+				c.msgs[msgKey{m.TxHash, m.Index}] = m
+			}
+		}
+	}
+	return nil
+}
+
+func (d *Driver) GetLatestHeight(ctx context.Context, chainID string) (height uint64, err error) {
+	d.l.RLock()
+	defer d.l.RUnlock()
+	return d.progress[chainID], nil
+}
+
+func (d *Driver) SetLatestHeight(ctx context.Context, chainID string, height uint64) (err error) {
+	d.l.Lock()
+	defer d.l.Unlock()
+	d.progress[chainID] = height
+	return nil
+}
+
+// GetLatestHeights returns processed heights of every chain
+func (d *Driver) GetLatestHeights(ctx context.Context) (heights map[string]uint64, err error) {
+	d.l.RLock()
+	defer d.l.RUnlock()
+
+	heights = make(map[string]uint64, len(d.progress))
+	for chainID, h := range d.progress {
+		heights[chainID] = h
+	}
+	return heights, nil
+}
@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+)
+
+// GetMessages returns the decoded messages of the chain selected by query
+func (d *Driver) GetMessages(ctx context.Context, q structs.MessageQuery) (msgs []structs.Message, err error) {
+	d.l.RLock()
+	defer d.l.RUnlock()
+
+	c, ok := d.chains[q.ChainID]
+	if !ok {
+		return nil, nil
+	}
+
+	for _, m := range c.msgs {
+		if (q.Type == "" || m.Type == q.Type) && (q.Module == "" || m.Module == q.Module) && (q.Height == 0 || m.Height == q.Height) {
+			msgs = append(msgs, m)
+		}
+	}
+
+	sort.Slice(msgs, func(i, j int) bool {
+		if msgs[i].Height != msgs[j].Height {
+			return msgs[i].Height < msgs[j].Height
+		}
+		if msgs[i].TxHash != msgs[j].TxHash {
+			return msgs[i].TxHash < msgs[j].TxHash
+		}
+		return msgs[i].Index < msgs[j].Index
+	})
+
+	if q.Limit > 0 && uint64(len(msgs)) > q.Limit {
+		msgs = msgs[:q.Limit]
+	}
+	return msgs, nil
+}
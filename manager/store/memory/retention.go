@@ -0,0 +1,64 @@
+package memory
+
+import "context"
+
+// PruneHeights removes blocks, transactions and messages of the chain below given height
+func (d *Driver) PruneHeights(ctx context.Context, chainID string, below uint64) error {
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	c := d.chain(chainID)
+	for hash, b := range c.blocks {
+		if b.Height < below {
+			delete(c.blocks, hash)
+		}
+	}
+	for k := range c.txs {
+		if k.height < below {
+			delete(c.txs, k)
+		}
+	}
+	for k, m := range c.msgs {
+		if m.Height < below {
+			delete(c.msgs, k)
+		}
+	}
+
+	if below > d.pruned[chainID] {
+		d.pruned[chainID] = below
+	}
+	return nil
+}
+
+// SlimHeights drops last commit of blocks and raw log of transactions of the chain below given height
+func (d *Driver) SlimHeights(ctx context.Context, chainID string, below uint64) error {
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	c := d.chain(chainID)
+	for hash, b := range c.blocks {
+		if b.Height < below && b.LastCommit != nil {
+			b.LastCommit = nil
+			c.blocks[hash] = b
+		}
+	}
+	for k, tx := range c.txs {
+		if k.height < below && len(tx.RawLog) > 0 {
+			tx.RawLog = nil
+			c.txs[k] = tx
+		}
+	}
+	return nil
+}
+
+// GetPrunedHeights returns the height below which the data is pruned, for every chain
+func (d *Driver) GetPrunedHeights(ctx context.Context) (heights map[string]uint64, err error) {
+	d.l.RLock()
+	defer d.l.RUnlock()
+
+	heights = make(map[string]uint64, len(d.pruned))
+	for chainID, h := range d.pruned {
+		heights[chainID] = h
+	}
+	return heights, nil
+}
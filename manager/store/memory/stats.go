@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+)
+
+// GetTransactionStats returns the aggregates of transactions of the chain
+func (d *Driver) GetTransactionStats(ctx context.Context, q structs.StatsQuery) (stats []structs.TransactionStats, err error) {
+	var truncate time.Duration
+	switch q.GroupBy {
+	case structs.StatsByHour:
+		truncate = time.Hour
+	case structs.StatsByDay:
+		truncate = 24 * time.Hour
+	case structs.StatsByMessageType:
+	default:
+		return nil, fmt.Errorf("unknown stats group %q", q.GroupBy)
+	}
+
+	txs := d.transactions(q.ChainID, func(tx structs.Transaction) bool {
+		return tx.Height >= q.From && tx.Height <= maxHeight(q.To)
+	})
+
+	groups := make(map[string]*structs.TransactionStats)
+	fees := make(map[string]map[string]*big.Int)
+	add := func(key string, tx structs.Transaction, msgs uint64, group structs.TransactionStats) {
+		s, ok := groups[key]
+		if !ok {
+			s = &group
+			groups[key] = s
+			fees[key] = make(map[string]*big.Int)
+		}
+
+		s.TxCount++
+		if tx.Code != 0 {
+			s.FailedTxCount++
+		}
+		s.MessageCount += msgs
+		s.GasWanted += tx.GasWanted
+		s.GasUsed += tx.GasUsed
+
+		if tx.AuthInfo == nil || tx.AuthInfo.Fee == nil {
+			return
+		}
+		for _, c := range tx.AuthInfo.Fee.Amount {
+			if c.Amount == nil {
+				continue
+			}
+			if _, ok := fees[key][c.Denom]; !ok {
+				fees[key][c.Denom] = new(big.Int)
+			}
+			fees[key][c.Denom].Add(fees[key][c.Denom], c.Amount)
+		}
+	}
+
+	for _, tx := range txs {
+		if q.GroupBy != structs.StatsByMessageType {
+			start := tx.Time.UTC().Truncate(truncate).Unix()
+			add(fmt.Sprintf("%020d", start), tx, uint64(len(tx.Messages)), structs.TransactionStats{Time: start})
+			continue
+		}
+
+		types := make(map[string]uint64)
+		for _, m := range tx.Messages {
+			types[m.TypeURL]++
+		}
+		for typeURL, msgs := range types {
+			add(typeURL, tx, msgs, structs.TransactionStats{MessageType: typeURL})
+		}
+	}
+
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if q.Limit > 0 && uint64(len(keys)) > q.Limit {
+		keys = keys[:q.Limit]
+	}
+
+	stats = []structs.TransactionStats{}
+	for _, k := range keys {
+		s := groups[k]
+		s.Fees = []structs.Coin{}
+		for denom, amount := range fees[k] {
+			s.Fees = append(s.Fees, structs.Coin{Denom: denom, Amount: amount})
+		}
+		sort.Slice(s.Fees, func(i, j int) bool { return s.Fees[i].Denom < s.Fees[j].Denom })
+		stats = append(stats, *s)
+	}
+	return stats, nil
+}
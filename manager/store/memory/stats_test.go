@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	msgSend     = "/cosmos.bank.v1beta1.MsgSend"
+	msgDelegate = "/cosmos.staking.v1beta1.MsgDelegate"
+)
+
+func statsTx(hash string, fee int64, gasUsed uint64, types ...string) structs.Transaction {
+	tx := structs.Transaction{
+		ChainID:   "chain",
+		Height:    10,
+		Hash:      hash,
+		Time:      time.Date(2021, 8, 1, 12, 30, 0, 0, time.UTC),
+		GasWanted: gasUsed * 2,
+		GasUsed:   gasUsed,
+		AuthInfo:  &structs.AuthInfo{Fee: &structs.Fee{Amount: []structs.Coin{{Denom: "uatom", Amount: big.NewInt(fee)}}}},
+	}
+	for _, t := range types {
+		tx.Messages = append(tx.Messages, structs.Any{TypeURL: t})
+	}
+	return tx
+}
+
+func TestTransactionStatsByMessageTypeOverlap(t *testing.T) {
+	ctx := context.Background()
+	d := NewDriver()
+	require.NoError(t, d.StoreTransactions(ctx, []structs.Transaction{
+		statsTx("TX1", 100, 1000, msgSend, msgSend, msgDelegate),
+		statsTx("TX2", 10, 200, msgSend),
+	}))
+
+	stats, err := d.GetTransactionStats(ctx, structs.StatsQuery{ChainID: "chain", GroupBy: structs.StatsByMessageType, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+
+	// the mixed transaction counts in both groups with its whole gas and fees, only the messages are split
+	send, delegate := stats[0], stats[1]
+	assert.Equal(t, msgSend, send.MessageType)
+	assert.Equal(t, uint64(2), send.TxCount)
+	assert.Equal(t, uint64(3), send.MessageCount)
+	assert.Equal(t, uint64(1200), send.GasUsed)
+	assert.Equal(t, uint64(2400), send.GasWanted)
+	assert.Equal(t, []structs.Coin{{Denom: "uatom", Amount: big.NewInt(110)}}, send.Fees)
+
+	assert.Equal(t, msgDelegate, delegate.MessageType)
+	assert.Equal(t, uint64(1), delegate.TxCount)
+	assert.Equal(t, uint64(1), delegate.MessageCount)
+	assert.Equal(t, uint64(1000), delegate.GasUsed)
+	assert.Equal(t, []structs.Coin{{Denom: "uatom", Amount: big.NewInt(100)}}, delegate.Fees)
+
+	// so the groups add up to more than the totals of the range
+	byDay, err := d.GetTransactionStats(ctx, structs.StatsQuery{ChainID: "chain", GroupBy: structs.StatsByDay, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, byDay, 1)
+	assert.Equal(t, uint64(2), byDay[0].TxCount)
+	assert.Equal(t, uint64(4), byDay[0].MessageCount)
+	assert.Equal(t, uint64(1200), byDay[0].GasUsed)
+	assert.Equal(t, []structs.Coin{{Denom: "uatom", Amount: big.NewInt(110)}}, byDay[0].Fees)
+	assert.Greater(t, send.GasUsed+delegate.GasUsed, byDay[0].GasUsed)
+}
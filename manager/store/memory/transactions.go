@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+)
+
+// FindTransactions returns transactions of the chain matching the filter ordered by height and hash
+func (d *Driver) FindTransactions(ctx context.Context, chainID string, f structs.TxFilter) (txs []structs.Transaction, err error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
+	txs = d.transactions(chainID, func(tx structs.Transaction) bool {
+		for _, c := range f.Conditions {
+			if !matches(tx, c) {
+				return false
+			}
+		}
+		return true
+	})
+
+	if f.Limit > 0 && uint64(len(txs)) > f.Limit {
+		txs = txs[:f.Limit]
+	}
+	return txs, nil
+}
+
+// GetTransactions returns transactions of the chain from the height range ordered by height and hash,
+// starting after the cursor
+func (d *Driver) GetTransactions(ctx context.Context, q structs.RangeQuery) (txs []structs.Transaction, err error) {
+	txs = d.transactions(q.ChainID, func(tx structs.Transaction) bool {
+		if tx.Height < q.From || tx.Height > maxHeight(q.To) || !inTimeRange(tx.Time, q) {
+			return false
+		}
+		if q.After != nil && !after(tx, *q.After) {
+			return false
+		}
+		return q.Address == "" || contains(tx.Parties, q.Address)
+	})
+
+	if uint64(len(txs)) > q.Limit {
+		txs = txs[:q.Limit]
+	}
+	return txs, nil
+}
+
+// transactions returns the transactions of the chain accepted by filter ordered by height and hash
+func (d *Driver) transactions(chainID string, filter func(tx structs.Transaction) bool) (txs []structs.Transaction) {
+	d.l.RLock()
+	defer d.l.RUnlock()
+
+	c, ok := d.chains[chainID]
+	if !ok {
+		return nil
+	}
+
+	for _, tx := range c.txs {
+		if filter(tx) {
+			txs = append(txs, tx)
+		}
+	}
+
+	sort.Slice(txs, func(i, j int) bool {
+		if txs[i].Height != txs[j].Height {
+			return txs[i].Height < txs[j].Height
+		}
+		return txs[i].Hash < txs[j].Hash
+	})
+	return txs
+}
+
+// after checks if transaction follows the cursor in the order of height and hash
+func after(tx structs.Transaction, c structs.Cursor) bool {
+	return tx.Height > c.Height || (tx.Height == c.Height && tx.Hash > c.Hash)
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -5,51 +5,29 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"math"
+	"strconv"
+	"time"
 
 	"github.com/figment-networks/graph-demo/manager/structs"
 )
 
 const (
-	insertBlock = `INSERT INTO public.blocks("chain_id", "height", "hash", "time", "header", "data", "evidence", "last_commit") VALUES
-	($1, $2, $3, $4, $5, $6, $7, $8)
-	ON CONFLICT (chain_id, hash)
-	DO UPDATE SET
-	height = EXCLUDED.height,
-	hash = EXCLUDED.hash,
-	time = EXCLUDED.time,
-	header = EXCLUDED.header,
-	data = EXCLUDED.data,
-	evidence = EXCLUDED.evidence,
-	last_commit = EXCLUDED.last_commit`
-
 	selectBlock = `SELECT hash, time, header, data, evidence, last_commit FROM public.blocks WHERE chain_id = $1 AND height = $2`
-)
-
-// StoreBlock appends data to buffer
-func (d *Driver) StoreBlock(ctx context.Context, b structs.Block) error {
 
-	header, err := json.Marshal(b.Header)
-	if err != nil {
-		return err
-	}
+	selectBlocks = `SELECT height, hash, time, header, data, evidence, last_commit FROM public.blocks
+	WHERE chain_id = $1 AND height BETWEEN $2 AND $3`
 
-	data, err := json.Marshal(b.Data)
-	if err != nil {
-		return err
-	}
-
-	evidence, err := json.Marshal(b.Evidence)
-	if err != nil {
-		return err
-	}
+	selectBlockAtTime = `SELECT height, hash, time, header, data, evidence, last_commit FROM public.blocks
+	WHERE chain_id = $1 AND time <= $2 ORDER BY time DESC, height DESC LIMIT 1`
+)
 
-	lastCommit, err := json.Marshal(*b.LastCommit)
-	if err != nil {
-		return err
+// maxHeight returns the upper bound of height range, 0 leaves it open
+func maxHeight(to uint64) uint64 {
+	if to == 0 {
+		return math.MaxInt64
 	}
-
-	_, err = d.db.ExecContext(ctx, insertBlock, b.ChainID, b.Height, b.Hash, b.Time, header, data, evidence, lastCommit)
-	return err
+	return to
 }
 
 func (d *Driver) GetBlockByHeight(ctx context.Context, height uint64, chainID string) (b structs.Block, err error) {
@@ -72,26 +50,89 @@ func (d *Driver) GetBlockByHeight(ctx context.Context, height uint64, chainID st
 		return b, fmt.Errorf("%s, height: %d", err.Error(), height)
 	}
 
-	if err = json.Unmarshal(header, &b.Header); err != nil {
+	return b, unmarshalBlock(&b, header, data, ev, lc)
+}
+
+// GetBlockAtTime returns the latest block of the chain created at or before given time
+func (d *Driver) GetBlockAtTime(ctx context.Context, chainID string, t time.Time) (b structs.Block, err error) {
+	var header, data, ev, lc []byte
+	b = structs.Block{ChainID: chainID}
+
+	row := d.db.QueryRowContext(ctx, selectBlockAtTime, chainID, t)
+	if err = row.Scan(&b.Height, &b.Hash, &b.Time, &header, &data, &ev, &lc); err != nil {
+		if err == sql.ErrNoRows {
+			return b, fmt.Errorf("%w, time: %s", structs.ErrNotFound, t.Format(time.RFC3339))
+		}
 		return b, err
 	}
 
+	return b, unmarshalBlock(&b, header, data, ev, lc)
+}
+
+// timeRange appends the conditions of time range of the query
+func timeRange(sq string, args []interface{}, q structs.RangeQuery) (string, []interface{}) {
+	if !q.TimeFrom.IsZero() {
+		args = append(args, q.TimeFrom)
+		sq += ` AND time >= $` + strconv.Itoa(len(args))
+	}
+	if !q.TimeTo.IsZero() {
+		args = append(args, q.TimeTo)
+		sq += ` AND time <= $` + strconv.Itoa(len(args))
+	}
+	return sq, args
+}
+
+// GetBlocks returns blocks of the chain from the height range ordered by height, starting after the cursor
+func (d *Driver) GetBlocks(ctx context.Context, q structs.RangeQuery) (blocks []structs.Block, err error) {
+	from := q.From
+	if q.After != nil && q.After.Height >= from {
+		from = q.After.Height + 1
+	}
+
+	bq, args := timeRange(selectBlocks, []interface{}{q.ChainID, from, maxHeight(q.To), q.Limit}, q)
+	rows, err := d.db.QueryContext(ctx, bq+` ORDER BY height LIMIT $4`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var header, data, ev, lc []byte
+		b := structs.Block{ChainID: q.ChainID}
+		if err = rows.Scan(&b.Height, &b.Hash, &b.Time, &header, &data, &ev, &lc); err != nil {
+			return nil, err
+		}
+		if err = unmarshalBlock(&b, header, data, ev, lc); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, b)
+	}
+
+	return blocks, rows.Err()
+}
+
+// unmarshalBlock sets the fields of block stored as JSON
+func unmarshalBlock(b *structs.Block, header, data, ev, lc []byte) (err error) {
+	if err = json.Unmarshal(header, &b.Header); err != nil {
+		return err
+	}
+
 	if err = json.Unmarshal(data, &b.Data); err != nil {
-		return b, err
+		return err
 	}
 
 	if err = json.Unmarshal(ev, &b.Evidence); err != nil {
-		return b, err
+		return err
 	}
 
 	if lc != nil {
 		b.LastCommit = &structs.Commit{}
 		if err = json.Unmarshal(lc, b.LastCommit); err != nil {
-			return b, err
+			return err
 		}
 	}
 
-	return b, err
+	return nil
 }
 
 func (d *Driver) GetLatestHeight(ctx context.Context, chainID string) (height uint64, err error) {
@@ -107,3 +148,102 @@ func (d *Driver) SetLatestHeight(ctx context.Context, chainID string, height uin
 	_, err = d.db.ExecContext(ctx, `INSERT INTO public.progress("chain_id", "height") VALUES ($1, $2) ON CONFLICT (chain_id) DO UPDATE SET height = EXCLUDED.height`, chainID, height)
 	return err
 }
+
+// GetLatestHeights returns processed heights of every chain
+func (d *Driver) GetLatestHeights(ctx context.Context) (heights map[string]uint64, err error) {
+	rows, err := d.db.QueryContext(ctx, `SELECT chain_id, height FROM public.progress`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	heights = make(map[string]uint64)
+	for rows.Next() {
+		var (
+			chainID string
+			height  uint64
+		)
+		if err = rows.Scan(&chainID, &height); err != nil {
+			return nil, err
+		}
+		heights[chainID] = height
+	}
+
+	return heights, rows.Err()
+}
+
+// GetMissingHeights returns heights from range [from, to] that have no block stored
+func (d *Driver) GetMissingHeights(ctx context.Context, chainID string, from, to uint64) (heights []uint64, err error) {
+	rows, err := d.db.QueryContext(ctx, `SELECT s.h FROM generate_series($2::bigint, $3::bigint) AS s(h)
+	LEFT JOIN public.blocks b ON b.chain_id = $1 AND b.height = s.h
+	WHERE b.height IS NULL ORDER BY s.h`, chainID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var h uint64
+		if err = rows.Scan(&h); err != nil {
+			return nil, err
+		}
+		heights = append(heights, h)
+	}
+
+	return heights, rows.Err()
+}
+
+// GetTxCountMismatches returns heights from range [from, to] where the number of stored transactions
+// is different than the number of transactions in the block data
+func (d *Driver) GetTxCountMismatches(ctx context.Context, chainID string, from, to uint64) (heights []uint64, err error) {
+	rows, err := d.db.QueryContext(ctx, `SELECT b.height FROM public.blocks b
+	LEFT JOIN (
+		SELECT height, COUNT(*) AS cnt FROM public.transactions WHERE chain_id = $1 AND height BETWEEN $2 AND $3 GROUP BY height
+	) t ON t.height = b.height
+	WHERE b.chain_id = $1 AND b.height BETWEEN $2 AND $3
+	AND COALESCE(jsonb_array_length(b.data->'txs'), 0) <> COALESCE(t.cnt, 0)
+	ORDER BY b.height`, chainID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var h uint64
+		if err = rows.Scan(&h); err != nil {
+			return nil, err
+		}
+		heights = append(heights, h)
+	}
+
+	return heights, rows.Err()
+}
+
+// SetVerifiedHeight stores the height below which the data of the chain was checked to be consistent
+func (d *Driver) SetVerifiedHeight(ctx context.Context, chainID string, below uint64) error {
+	_, err := d.db.ExecContext(ctx, `UPDATE public.progress SET verified_below = $2 WHERE chain_id = $1`, chainID, below)
+	return err
+}
+
+// GetVerifiedHeights returns the height below which the data was checked to be consistent, for every chain
+func (d *Driver) GetVerifiedHeights(ctx context.Context) (heights map[string]uint64, err error) {
+	rows, err := d.db.QueryContext(ctx, `SELECT chain_id, verified_below FROM public.progress`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	heights = make(map[string]uint64)
+	for rows.Next() {
+		var (
+			chainID string
+			below   uint64
+		)
+		if err = rows.Scan(&chainID, &below); err != nil {
+			return nil, err
+		}
+		heights[chainID] = below
+	}
+
+	return heights, rows.Err()
+}
@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// copyTable describes the table rows are upserted into through COPY
+type copyTable struct {
+	name     string
+	columns  []string
+	conflict []string
+}
+
+// copyUpsert copies the rows to temporary table and upserts them from there into the table,
+// rows are the values of table columns in order. Only the last of the rows with the same conflict key is kept,
+// as a single statement can't update the same row twice.
+func copyUpsert(ctx context.Context, tx *sql.Tx, t copyTable, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	// staging table has only the copied columns, without constraints and defaults of the table
+	tmp := "tmp_" + t.name
+	columns := strings.Join(t.columns, ", ")
+	if _, err := tx.ExecContext(ctx, `CREATE TEMP TABLE `+tmp+` ON COMMIT DROP AS SELECT `+columns+` FROM public.`+t.name+` WITH NO DATA`); err != nil {
+		return fmt.Errorf("error creating staging table of %s: %w", t.name, err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(tmp, t.columns...))
+	if err != nil {
+		return err
+	}
+
+	for _, row := range t.unique(rows) {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			stmt.Close()
+			return fmt.Errorf("error copying %s: %w", t.name, err)
+		}
+	}
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return fmt.Errorf("error copying %s: %w", t.name, err)
+	}
+	if err := stmt.Close(); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, t.upsert(tmp)); err != nil {
+		return fmt.Errorf("error upserting %s: %w", t.name, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `DROP TABLE `+tmp)
+	return err
+}
+
+// upsert builds the statement moving rows from the staging table
+func (t copyTable) upsert(tmp string) string {
+	conflict := make(map[string]bool, len(t.conflict))
+	for _, c := range t.conflict {
+		conflict[c] = true
+	}
+
+	updates := make([]string, 0, len(t.columns))
+	for _, c := range t.columns {
+		if !conflict[c] {
+			updates = append(updates, c+` = EXCLUDED.`+c)
+		}
+	}
+
+	columns := strings.Join(t.columns, ", ")
+	return `INSERT INTO public.` + t.name + ` (` + columns + `) SELECT ` + columns + ` FROM ` + tmp +
+		` ON CONFLICT (` + strings.Join(t.conflict, ", ") + `) DO UPDATE SET ` + strings.Join(updates, ", ")
+}
+
+// unique drops the rows followed by another row of the same conflict key
+func (t copyTable) unique(rows [][]interface{}) [][]interface{} {
+	keyIdx := make([]int, 0, len(t.conflict))
+	for _, c := range t.conflict {
+		for i, col := range t.columns {
+			if col == c {
+				keyIdx = append(keyIdx, i)
+			}
+		}
+	}
+
+	last := make(map[string]int, len(rows))
+	keys := make([]string, len(rows))
+	for i, row := range rows {
+		key := make([]string, len(keyIdx))
+		for j, k := range keyIdx {
+			key[j] = fmt.Sprint(row[k])
+		}
+		keys[i] = strings.Join(key, "\x00")
+		last[keys[i]] = i
+	}
+
+	if len(last) == len(rows) {
+		return rows
+	}
+
+	unique := make([][]interface{}, 0, len(last))
+	for i, row := range rows {
+		if last[keys[i]] == i {
+			unique = append(unique, row)
+		}
+	}
+	return unique
+}
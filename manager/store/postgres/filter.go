@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+	"github.com/lib/pq"
+)
+
+// txFilterColumns are the columns of transactions table the filter fields compare
+var txFilterColumns = map[structs.TxField]string{
+	structs.TxFieldHash:      "hash",
+	structs.TxFieldHeight:    "height",
+	structs.TxFieldBlockHash: "block_hash",
+	structs.TxFieldMemo:      "memo",
+	structs.TxFieldCode:      "code",
+	structs.TxFieldTime:      "time",
+}
+
+var sqlOperators = map[structs.Operator]string{
+	structs.OpEq:  "=",
+	structs.OpNeq: "<>",
+	structs.OpLt:  "<",
+	structs.OpLte: "<=",
+	structs.OpGt:  ">",
+	structs.OpGte: ">=",
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// txFilterQuery builds the query of transactions matching the filter. Only the columns and operators
+// from allow-lists end up in SQL, all the values are passed as parameters.
+func txFilterQuery(chainID string, f structs.TxFilter) (q string, args []interface{}, err error) {
+	if err := f.Validate(); err != nil {
+		return "", nil, err
+	}
+
+	args = []interface{}{chainID}
+	param := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	b := strings.Builder{}
+	b.WriteString(`SELECT ` + txColumns + `
+	FROM public.transactions WHERE chain_id = $1`)
+
+	for _, c := range f.Conditions {
+		b.WriteString(" AND ")
+
+		if c.Field == structs.TxFieldSigner {
+			// signers are the senders of messages found in the logs
+			contains, err := json.Marshal([]structs.Log{{Events: []structs.Event{{Type: "message", Attributes: map[string]string{"sender": c.Value.(string)}}}}})
+			if err != nil {
+				return "", nil, err
+			}
+			b.WriteString("logs @> " + param(string(contains)) + "::jsonb")
+			continue
+		}
+
+		if c.Field == structs.TxFieldAddress {
+			// uses GIN index of parties
+			b.WriteString("parties @> ARRAY[" + param(c.Value) + "]::text[]")
+			continue
+		}
+
+		column := txFilterColumns[c.Field]
+		switch c.Op {
+		case structs.OpIn:
+			b.WriteString(column + " = ANY(" + param(pq.Array(c.Value)) + ")")
+		case structs.OpContains:
+			b.WriteString(column + " LIKE " + param("%"+likeEscaper.Replace(c.Value.(string))+"%"))
+		default:
+			b.WriteString(column + " " + sqlOperators[c.Op] + " " + param(c.Value))
+		}
+	}
+
+	b.WriteString(" ORDER BY height, hash")
+	if f.Limit > 0 {
+		b.WriteString(" LIMIT " + param(f.Limit))
+	}
+
+	return b.String(), args, nil
+}
@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxFilterQueryRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter structs.TxFilter
+	}{
+		{
+			name:   "no conditions",
+			filter: structs.TxFilter{},
+		},
+		{
+			name: "arbitrary field name",
+			filter: structs.TxFilter{Conditions: []structs.TxCondition{
+				{Field: "height = 1 OR 1", Op: structs.OpEq, Value: uint64(1)},
+			}},
+		},
+		{
+			name: "column that is not allow-listed",
+			filter: structs.TxFilter{Conditions: []structs.TxCondition{
+				{Field: "raw_log", Op: structs.OpEq, Value: "x"},
+			}},
+		},
+		{
+			name: "field name with statement",
+			filter: structs.TxFilter{Conditions: []structs.TxCondition{
+				{Field: structs.TxFieldHash, Op: structs.OpEq, Value: "a"},
+				{Field: "hash = '' ; DROP TABLE transactions; --", Op: structs.OpEq, Value: "a"},
+			}},
+		},
+		{
+			name: "unknown operator",
+			filter: structs.TxFilter{Conditions: []structs.TxCondition{
+				{Field: structs.TxFieldHeight, Op: "= 1 OR 1 =", Value: uint64(1)},
+			}},
+		},
+		{
+			name: "operator not allowed for field",
+			filter: structs.TxFilter{Conditions: []structs.TxCondition{
+				{Field: structs.TxFieldHash, Op: structs.OpGt, Value: "a"},
+			}},
+		},
+		{
+			name: "wrong value type",
+			filter: structs.TxFilter{Conditions: []structs.TxCondition{
+				{Field: structs.TxFieldHeight, Op: structs.OpEq, Value: "1 OR 1=1"},
+			}},
+		},
+		{
+			name: "empty in",
+			filter: structs.TxFilter{Conditions: []structs.TxCondition{
+				{Field: structs.TxFieldHash, Op: structs.OpIn, Value: []string{}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, args, err := txFilterQuery("chain", tt.filter)
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, structs.ErrInvalidFilter), err.Error())
+			assert.Empty(t, q)
+			assert.Nil(t, args)
+		})
+	}
+}
+
+func TestTxFilterQuery(t *testing.T) {
+	from := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		filter structs.TxFilter
+		where  string
+		args   []interface{}
+	}{
+		{
+			name: "equality",
+			filter: structs.TxFilter{Conditions: []structs.TxCondition{
+				{Field: structs.TxFieldHash, Op: structs.OpEq, Value: "'; DROP TABLE transactions; --"},
+			}},
+			where: "WHERE chain_id = $1 AND hash = $2 ORDER BY height, hash",
+			args:  []interface{}{"chain", "'; DROP TABLE transactions; --"},
+		},
+		{
+			name: "ranges with limit",
+			filter: structs.TxFilter{Conditions: []structs.TxCondition{
+				{Field: structs.TxFieldHeight, Op: structs.OpGte, Value: uint64(10)},
+				{Field: structs.TxFieldHeight, Op: structs.OpLt, Value: uint64(20)},
+				{Field: structs.TxFieldTime, Op: structs.OpGte, Value: from},
+				{Field: structs.TxFieldCode, Op: structs.OpNeq, Value: uint64(0)},
+			}, Limit: 5},
+			where: "WHERE chain_id = $1 AND height >= $2 AND height < $3 AND time >= $4 AND code <> $5 ORDER BY height, hash LIMIT $6",
+			args:  []interface{}{"chain", uint64(10), uint64(20), from, uint64(0), uint64(5)},
+		},
+		{
+			name: "memo contains is escaped",
+			filter: structs.TxFilter{Conditions: []structs.TxCondition{
+				{Field: structs.TxFieldMemo, Op: structs.OpContains, Value: "100%_"},
+			}},
+			where: "WHERE chain_id = $1 AND memo LIKE $2 ORDER BY height, hash",
+			args:  []interface{}{"chain", `%100\%\_%`},
+		},
+		{
+			name: "signer",
+			filter: structs.TxFilter{Conditions: []structs.TxCondition{
+				{Field: structs.TxFieldSigner, Op: structs.OpEq, Value: `cosmos1"}]}]`},
+			}},
+			where: "WHERE chain_id = $1 AND logs @> $2::jsonb ORDER BY height, hash",
+			args:  []interface{}{"chain", `[{"msg_index":0,"log":"","events":[{"type":"message","attributes":{"sender":"cosmos1\"}]}]"}}]}]`},
+		},
+		{
+			name: "address",
+			filter: structs.TxFilter{Conditions: []structs.TxCondition{
+				{Field: structs.TxFieldAddress, Op: structs.OpEq, Value: "cosmos1']::text[] OR true --"},
+			}},
+			where: "WHERE chain_id = $1 AND parties @> ARRAY[$2]::text[] ORDER BY height, hash",
+			args:  []interface{}{"chain", "cosmos1']::text[] OR true --"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, args, err := txFilterQuery("chain", tt.filter)
+			require.NoError(t, err)
+			assert.True(t, strings.HasSuffix(q, tt.where), q)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+
+	t.Run("in", func(t *testing.T) {
+		q, args, err := txFilterQuery("chain", structs.TxFilter{Conditions: []structs.TxCondition{
+			{Field: structs.TxFieldBlockHash, Op: structs.OpIn, Value: []string{"A", "B"}},
+		}})
+		require.NoError(t, err)
+		assert.True(t, strings.HasSuffix(q, "WHERE chain_id = $1 AND block_hash = ANY($2) ORDER BY height, hash"), q)
+		require.Len(t, args, 2)
+		arr, ok := args[1].(driver.Valuer)
+		require.True(t, ok)
+		v, err := arr.Value()
+		require.NoError(t, err)
+		assert.Equal(t, `{"A","B"}`, v)
+	})
+}
@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+	"github.com/lib/pq"
+)
+
+var (
+	blocksTable = copyTable{
+		name:     "blocks",
+		columns:  []string{"chain_id", "height", "hash", "time", "header", "data", "evidence", "last_commit"},
+		conflict: []string{"chain_id", "hash"},
+	}
+
+	transactionsTable = copyTable{
+		name: "transactions",
+		columns: []string{"chain_id", "height", "hash", "block_hash", "time", "code_space", "code", "result", "logs", "info",
+			"tx_raw", "messages", "extension_options", "non_critical_extension_options", "auth_info", "signatures",
+			"gas_wanted", "gas_used", "memo", "raw_log", "parties"},
+		conflict: []string{"chain_id", "hash", "height"},
+	}
+
+	messagesTable = copyTable{
+		name:     "messages",
+		columns:  []string{"chain_id", "height", "tx_hash", "msg_index", "type_url", "module", "value"},
+		conflict: []string{"chain_id", "tx_hash", "msg_index"},
+	}
+)
+
+// StoreBlock stores the block
+func (d *Driver) StoreBlock(ctx context.Context, b structs.Block) error {
+	return d.StoreHeights(ctx, []structs.BlockAndTx{{Block: b}})
+}
+
+// StoreTransactions stores the transactions with their messages in one database transaction
+func (d *Driver) StoreTransactions(ctx context.Context, txs []structs.Transaction) error {
+	return d.StoreHeights(ctx, []structs.BlockAndTx{{Transactions: txs}})
+}
+
+// StoreHeights stores the blocks with their transactions and messages in one database transaction,
+// the rows of every table are copied at once. Blocks without hash are skipped, so the transactions
+// can be stored on their own.
+func (d *Driver) StoreHeights(ctx context.Context, heights []structs.BlockAndTx) (err error) {
+	var blocks, txs, msgs [][]interface{}
+	for _, h := range heights {
+		if h.Block.Hash != "" {
+			row, err := blockRow(h.Block)
+			if err != nil {
+				return err
+			}
+			blocks = append(blocks, row)
+		}
+
+		for _, t := range h.Transactions {
+			row, err := transactionRow(t)
+			if err != nil {
+				return err
+			}
+			txs = append(txs, row)
+
+			for _, m := range structs.TransactionMessages(t) {
+				msgs = append(msgs, messageRow(m))
+			}
+		}
+	}
+
+	if len(blocks) == 0 && len(txs) == 0 {
+		return nil
+	}
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = copyUpsert(ctx, tx, blocksTable, blocks); err != nil {
+		return err
+	}
+	if err = copyUpsert(ctx, tx, transactionsTable, txs); err != nil {
+		return err
+	}
+	if err = copyUpsert(ctx, tx, messagesTable, msgs); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func blockRow(b structs.Block) ([]interface{}, error) {
+	header, err := json.Marshal(b.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(b.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	evidence, err := json.Marshal(b.Evidence)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastCommit interface{}
+	if b.LastCommit != nil {
+		lc, err := json.Marshal(b.LastCommit)
+		if err != nil {
+			return nil, err
+		}
+		lastCommit = string(lc)
+	}
+
+	return []interface{}{b.ChainID, b.Height, b.Hash, b.Time, string(header), string(data), string(evidence), lastCommit}, nil
+}
+
+func transactionRow(t structs.Transaction) ([]interface{}, error) {
+	mf, err := getMarshaledFields(t)
+	if err != nil {
+		return nil, err
+	}
+
+	rawLog := t.RawLog
+	if rawLog == nil {
+		rawLog = []byte{}
+	}
+
+	return []interface{}{t.ChainID, t.Height, t.Hash, t.BlockHash, t.Time, t.CodeSpace, t.Code, t.Result, jsonb(mf.logs),
+		t.Info, jsonb(mf.txRaw), jsonb(mf.messages), jsonb(mf.extensionOptions), jsonb(mf.nonCriticalExtensionOptions),
+		jsonb(mf.authInfo), pq.Array(t.Signatures), t.GasWanted, t.GasUsed, t.Memo, rawLog, pq.Array(t.Parties)}, nil
+}
+
+func messageRow(m structs.Message) []interface{} {
+	return []interface{}{m.ChainID, m.Height, m.TxHash, m.Index, m.Type, m.Module, jsonb(m.Value)}
+}
+
+// jsonb passes JSON as text, COPY would encode bytes as bytea. Empty JSON is NULL.
+func jsonb(b []byte) interface{} {
+	if len(b) == 0 {
+		return nil
+	}
+	return string(b)
+}
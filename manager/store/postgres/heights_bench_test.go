@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+)
+
+// BenchmarkStoreHeights writes heights to the database given by TEST_DATABASE_URL, with all the migrations applied.
+// Rows are the blocks, transactions and messages written.
+//
+//	TEST_DATABASE_URL="postgres://..." go test ./manager/store/postgres -run - -bench StoreHeights
+func BenchmarkStoreHeights(b *testing.B) {
+	ctx := context.Background()
+	d, chainID := testDriver(b, "bench")
+
+	const txsPerHeight, msgsPerTx = 20, 2
+	var height uint64
+	for _, batch := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("heights=%d", batch), func(b *testing.B) {
+			var rows int
+			var elapsed time.Duration
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				heights := make([]structs.BlockAndTx, batch)
+				for j := range heights {
+					height++
+					heights[j] = benchHeight(chainID, height, txsPerHeight, msgsPerTx)
+				}
+				rows += batch * (1 + txsPerHeight*(1+msgsPerTx))
+				b.StartTimer()
+
+				start := time.Now()
+				if err := d.StoreHeights(ctx, heights); err != nil {
+					b.Fatal(err)
+				}
+				elapsed += time.Since(start)
+			}
+			b.ReportMetric(float64(rows)/elapsed.Seconds(), "rows/s")
+		})
+	}
+}
+
+func benchHeight(chainID string, height uint64, txs, msgs int) structs.BlockAndTx {
+	t := time.Now().UTC()
+	h := structs.BlockAndTx{
+		Block: structs.Block{
+			ChainID:    chainID,
+			Height:     height,
+			Hash:       fmt.Sprintf("B%064X", height),
+			Time:       t,
+			LastCommit: &structs.Commit{Height: int64(height) - 1},
+		},
+	}
+
+	msg, _ := json.Marshal(map[string]interface{}{
+		"from_address": "cosmos1qypqxpq9qcrsszg2pvxq6rs0zqg3yyc5lzv7xu",
+		"to_address":   "cosmos1g3jkcfj6ge3kyyjwzqqqqqqqqqqqqqqqj5hw2q",
+		"amount":       []map[string]string{{"denom": "uatom", "amount": "1000"}},
+	})
+
+	for i := 0; i < txs; i++ {
+		tx := structs.Transaction{
+			ChainID:    chainID,
+			Height:     height,
+			Hash:       fmt.Sprintf("T%048X%016X", height, i),
+			BlockHash:  h.Block.Hash,
+			Time:       t,
+			GasWanted:  200000,
+			GasUsed:    80000,
+			Memo:       "benchmark",
+			RawLog:     []byte(`[{"events":[]}]`),
+			Signatures: []string{"c2lnbmF0dXJl"},
+			Parties:    []string{"cosmos1g3jkcfj6ge3kyyjwzqqqqqqqqqqqqqqqj5hw2q", "cosmos1qypqxpq9qcrsszg2pvxq6rs0zqg3yyc5lzv7xu"},
+		}
+		for j := 0; j < msgs; j++ {
+			tx.Messages = append(tx.Messages, structs.Any{TypeURL: "/cosmos.bank.v1beta1.MsgSend", Value: msg})
+		}
+		h.Transactions = append(h.Transactions, tx)
+	}
+	return h
+}
@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// testDriver connects to the database given by TEST_DATABASE_URL, with all the migrations applied,
+// and returns unique chain ID whose data is removed when the test ends. The test is skipped if the database is not set.
+func testDriver(tb testing.TB, prefix string) (*Driver, string) {
+	dbURL := os.Getenv("TEST_DATABASE_URL")
+	if dbURL == "" {
+		tb.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	d, err := NewDriver(context.Background(), zap.NewNop(), dbURL)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	chainID := prefix + "-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	tb.Cleanup(func() {
+		for _, table := range []string{"messages", "transactions", "blocks", "progress"} {
+			if _, err := d.db.Exec(`DELETE FROM public.`+table+` WHERE chain_id = $1`, chainID); err != nil {
+				tb.Error(err)
+			}
+		}
+		d.Close()
+	})
+	return d, chainID
+}
+
+// TestStoreHeights writes heights through the staging tables and reads them back, it needs the database as the benchmark.
+//
+//	TEST_DATABASE_URL="postgres://..." go test ./manager/store/postgres -run StoreHeights
+func TestStoreHeights(t *testing.T) {
+	ctx := context.Background()
+	d, chainID := testDriver(t, "test")
+
+	heights := []structs.BlockAndTx{benchHeight(chainID, 1, 2, 2), benchHeight(chainID, 2, 2, 2)}
+	require.NoError(t, d.StoreHeights(ctx, heights))
+
+	// stored again, the rows are updated
+	txs := heights[1].Transactions
+	txs[0].Memo = "updated"
+	require.NoError(t, d.StoreTransactions(ctx, txs))
+
+	blocks, err := d.GetBlocks(ctx, structs.RangeQuery{ChainID: chainID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, blocks, 2)
+	for i, b := range blocks {
+		assert.Equal(t, heights[i].Block.Hash, b.Hash)
+		require.NotNil(t, b.LastCommit)
+		assert.Equal(t, int64(i), b.LastCommit.Height)
+	}
+
+	stored, err := d.GetTransactions(ctx, structs.RangeQuery{ChainID: chainID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, stored, 4)
+	for _, tx := range stored {
+		expected := "benchmark"
+		if tx.Hash == txs[0].Hash {
+			expected = "updated"
+		}
+		assert.Equal(t, expected, tx.Memo, tx.Hash)
+		assert.Len(t, tx.Messages, 2)
+		assert.Len(t, tx.Parties, 2)
+	}
+
+	msgs, err := d.GetMessages(ctx, structs.MessageQuery{ChainID: chainID, Module: "bank", Limit: 100})
+	require.NoError(t, err)
+	assert.Len(t, msgs, 8)
+
+	missing, err := d.GetMissingHeights(ctx, chainID, 1, 2)
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+}
@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	insertJob = `INSERT INTO public.backfill_jobs("chain_id", "height_from", "height_to", "current", "status") VALUES
+	($1, $2, $3, $4, $5) RETURNING id, created_at`
+
+	updateJob = `UPDATE public.backfill_jobs SET current = $2, status = $3, last_error = $4, error_count = $5, updated_at = NOW() WHERE id = $1`
+
+	selectJobs = `SELECT id, created_at, updated_at, chain_id, height_from, height_to, current, status, last_error, error_count FROM public.backfill_jobs`
+)
+
+func (d *Driver) CreateJob(ctx context.Context, j structs.BackfillJob) (structs.BackfillJob, error) {
+	row := d.db.QueryRowContext(ctx, insertJob, j.ChainID, j.From, j.To, j.Current, j.Status)
+	err := row.Scan(&j.ID, &j.CreatedAt)
+	return j, err
+}
+
+func (d *Driver) UpdateJob(ctx context.Context, j structs.BackfillJob) error {
+	_, err := d.db.ExecContext(ctx, updateJob, j.ID, j.Current, j.Status, j.LastError, j.ErrorCount)
+	return err
+}
+
+func (d *Driver) GetJob(ctx context.Context, id uuid.UUID) (j structs.BackfillJob, err error) {
+	row := d.db.QueryRowContext(ctx, selectJobs+` WHERE id = $1`, id)
+	if j, err = scanJob(row); err == sql.ErrNoRows {
+		return j, structs.ErrNotFound
+	}
+	return j, err
+}
+
+// GetJobs returns jobs of given chain (or every chain if chainID is empty) in the order of creation
+func (d *Driver) GetJobs(ctx context.Context, chainID string, statuses ...structs.JobStatus) (jobs []structs.BackfillJob, err error) {
+	sts := make([]string, len(statuses))
+	for i, s := range statuses {
+		sts[i] = string(s)
+	}
+
+	rows, err := d.db.QueryContext(ctx, selectJobs+` WHERE ($1 = '' OR chain_id = $1) AND (cardinality($2::text[]) = 0 OR status = ANY($2)) ORDER BY created_at`, chainID, pq.Array(sts))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+
+	return jobs, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanJob(row scanner) (j structs.BackfillJob, err error) {
+	var lastError sql.NullString
+	var status string
+	if err = row.Scan(&j.ID, &j.CreatedAt, &j.UpdatedAt, &j.ChainID, &j.From, &j.To, &j.Current, &status, &lastError, &j.ErrorCount); err != nil {
+		return j, err
+	}
+	j.Status = structs.JobStatus(status)
+	j.LastError = lastError.String
+	return j, nil
+}
@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+)
+
+const msgColumns = `height, tx_hash, msg_index, type_url, module, value`
+
+// GetMessages returns the decoded messages of the chain selected by query
+func (d *Driver) GetMessages(ctx context.Context, q structs.MessageQuery) (msgs []structs.Message, err error) {
+	args := []interface{}{q.ChainID}
+	mq := `SELECT ` + msgColumns + ` FROM public.messages WHERE chain_id = $1`
+	if q.Type != "" {
+		args = append(args, q.Type)
+		mq += ` AND type_url = $` + strconv.Itoa(len(args))
+	}
+	if q.Module != "" {
+		args = append(args, q.Module)
+		mq += ` AND module = $` + strconv.Itoa(len(args))
+	}
+	if q.Height != 0 {
+		args = append(args, q.Height)
+		mq += ` AND height = $` + strconv.Itoa(len(args))
+	}
+	mq += ` ORDER BY height, tx_hash, msg_index`
+	if q.Limit > 0 {
+		args = append(args, q.Limit)
+		mq += ` LIMIT $` + strconv.Itoa(len(args))
+	}
+
+	rows, err := d.db.QueryContext(ctx, mq, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		m := structs.Message{ChainID: q.ChainID}
+		var value []byte
+		if err = rows.Scan(&m.Height, &m.TxHash, &m.Index, &m.Type, &m.Module, &value); err != nil {
+			return nil, err
+		}
+		if len(value) > 0 {
+			m.Value = value
+		}
+		msgs = append(msgs, m)
+	}
+
+	return msgs, rows.Err()
+}
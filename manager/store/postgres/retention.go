@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+)
+
+const (
+	// slimmedFrom is the lowest height not slimmed yet, heavy columns of the heights below are already dropped
+	slimmedFrom = `COALESCE((SELECT slimmed_below FROM public.progress WHERE chain_id = $1), 0)`
+
+	slimBlocks = `UPDATE public.blocks SET last_commit = NULL
+	WHERE chain_id = $1 AND height >= ` + slimmedFrom + ` AND height < $2 AND last_commit IS NOT NULL`
+
+	slimTransactions = `UPDATE public.transactions SET raw_log = ''::bytea
+	WHERE chain_id = $1 AND height >= ` + slimmedFrom + ` AND height < $2 AND length(raw_log) > 0`
+)
+
+// PruneHeights removes blocks, transactions and messages of the chain below given height in one database transaction
+func (d *Driver) PruneHeights(ctx context.Context, chainID string, below uint64) error {
+	return d.inTx(ctx, func(tx *sql.Tx) error {
+		for _, table := range []string{"messages", "transactions", "blocks"} {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM public.`+table+` WHERE chain_id = $1 AND height < $2`, chainID, below); err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.ExecContext(ctx, `UPDATE public.progress SET pruned_below = GREATEST(pruned_below, $2) WHERE chain_id = $1`, chainID, below)
+		return err
+	})
+}
+
+// SlimHeights drops last commit of blocks and raw log of transactions of the chain below given height.
+// Heights slimmed already are skipped.
+func (d *Driver) SlimHeights(ctx context.Context, chainID string, below uint64) error {
+	return d.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, slimBlocks, chainID, below); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, slimTransactions, chainID, below); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, `UPDATE public.progress SET slimmed_below = GREATEST(slimmed_below, $2) WHERE chain_id = $1`, chainID, below)
+		return err
+	})
+}
+
+// GetPrunedHeights returns the height below which the data is pruned, for every chain
+func (d *Driver) GetPrunedHeights(ctx context.Context) (heights map[string]uint64, err error) {
+	rows, err := d.db.QueryContext(ctx, `SELECT chain_id, pruned_below FROM public.progress`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	heights = make(map[string]uint64)
+	for rows.Next() {
+		var (
+			chainID string
+			below   uint64
+		)
+		if err = rows.Scan(&chainID, &below); err != nil {
+			return nil, err
+		}
+		heights[chainID] = below
+	}
+
+	return heights, rows.Err()
+}
+
+// inTx runs fn in database transaction, rolled back if fn fails
+func (d *Driver) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+)
+
+const (
+	// txMsgCount is the number of messages of transaction
+	txMsgCount = `CASE WHEN jsonb_typeof(messages) = 'array' THEN jsonb_array_length(messages) ELSE 0 END`
+
+	// statsByTime is the source of statistics grouped by time, one row per transaction,
+	// bucket is the start of the hour or day (UTC) in unix seconds
+	statsByTime = `SELECT EXTRACT(EPOCH FROM date_trunc('%s', time AT TIME ZONE 'UTC'))::BIGINT AS bucket,
+		code, gas_wanted, gas_used, ` + txMsgCount + ` AS msgs, auth_info
+	FROM public.transactions WHERE chain_id = $1 AND height BETWEEN $2 AND $3`
+
+	// statsByMessageType is the source of statistics grouped by message type, one row per type of transaction's messages.
+	// Every row carries the whole gas and fees of the transaction, so the groups overlap for transactions of several types
+	statsByMessageType = `SELECT m.type_url AS bucket, t.code, t.gas_wanted, t.gas_used, m.msgs, t.auth_info
+	FROM (SELECT type_url, tx_hash, height, COUNT(*) AS msgs FROM public.messages
+		WHERE chain_id = $1 AND height BETWEEN $2 AND $3 GROUP BY type_url, tx_hash, height) m
+	JOIN public.transactions t ON t.chain_id = $1 AND t.hash = m.tx_hash AND t.height = m.height`
+
+	selectStats = `SELECT bucket, COUNT(*), COUNT(*) FILTER (WHERE code <> 0), COALESCE(SUM(msgs), 0),
+		COALESCE(SUM(gas_wanted), 0), COALESCE(SUM(gas_used), 0)
+	FROM (%s) s GROUP BY bucket ORDER BY bucket LIMIT $4`
+
+	selectStatsFees = `SELECT bucket, c->>'denom', SUM((c->>'amount')::NUMERIC)::TEXT
+	FROM (%s) s CROSS JOIN LATERAL jsonb_array_elements(
+		CASE WHEN jsonb_typeof(auth_info->'fee'->'amount') = 'array' THEN auth_info->'fee'->'amount' ELSE '[]' END) c
+	WHERE c->>'amount' IS NOT NULL GROUP BY 1, 2 ORDER BY 1, 2`
+)
+
+// GetTransactionStats returns the aggregates of transactions of the chain computed by the database
+func (d *Driver) GetTransactionStats(ctx context.Context, q structs.StatsQuery) (stats []structs.TransactionStats, err error) {
+	var source string
+	switch q.GroupBy {
+	case structs.StatsByHour:
+		source = fmt.Sprintf(statsByTime, "hour")
+	case structs.StatsByDay:
+		source = fmt.Sprintf(statsByTime, "day")
+	case structs.StatsByMessageType:
+		source = statsByMessageType
+	default:
+		return nil, fmt.Errorf("unknown stats group %q", q.GroupBy)
+	}
+
+	rows, err := d.db.QueryContext(ctx, fmt.Sprintf(selectStats, source), q.ChainID, q.From, maxHeight(q.To), q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make(map[string]int)
+	for rows.Next() {
+		var (
+			bucket string
+			s      structs.TransactionStats
+		)
+		if err = rows.Scan(&bucket, &s.TxCount, &s.FailedTxCount, &s.MessageCount, &s.GasWanted, &s.GasUsed); err != nil {
+			return nil, err
+		}
+		if err = setStatsGroup(&s, q.GroupBy, bucket); err != nil {
+			return nil, err
+		}
+		s.Fees = []structs.Coin{}
+		groups[bucket] = len(stats)
+		stats = append(stats, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(stats) == 0 {
+		return stats, nil
+	}
+
+	feeRows, err := d.db.QueryContext(ctx, fmt.Sprintf(selectStatsFees, source), q.ChainID, q.From, maxHeight(q.To))
+	if err != nil {
+		return nil, err
+	}
+	defer feeRows.Close()
+
+	for feeRows.Next() {
+		var bucket, denom, amount string
+		if err = feeRows.Scan(&bucket, &denom, &amount); err != nil {
+			return nil, err
+		}
+
+		i, ok := groups[bucket]
+		if !ok {
+			// group past the limit
+			continue
+		}
+
+		a, ok := new(big.Int).SetString(amount, 10)
+		if !ok {
+			return nil, fmt.Errorf("malformed fee amount %q", amount)
+		}
+		stats[i].Fees = append(stats[i].Fees, structs.Coin{Denom: denom, Amount: a})
+	}
+
+	return stats, feeRows.Err()
+}
+
+// setStatsGroup sets the group of statistics from the bucket selected by database
+func setStatsGroup(s *structs.TransactionStats, group structs.StatsGroup, bucket string) (err error) {
+	if group == structs.StatsByMessageType {
+		s.MessageType = bucket
+		return nil
+	}
+
+	_, err = fmt.Sscan(bucket, &s.Time)
+	return err
+}
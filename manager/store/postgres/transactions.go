@@ -4,61 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"strconv"
 
 	"github.com/figment-networks/graph-demo/manager/structs"
 	"github.com/lib/pq"
 )
 
-const (
-	txInsert = `INSERT INTO public.transactions("chain_id", "height", "hash", "block_hash", "time", "code_space", "code",
-	"result", "logs", "info", "tx_raw", "messages", "extension_options", "non_critical_extension_options", "auth_info",
-	"signatures", "gas_wanted", "gas_used", "memo", "raw_log") VALUES
-	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
-	ON CONFLICT (chain_id, hash, height)
-	DO UPDATE SET
-	height = EXCLUDED.height,
-	hash = EXCLUDED.hash,
-	block_hash = EXCLUDED.block_hash,
-	time = EXCLUDED.time,
-	code_space = EXCLUDED.code_space,
-	code = EXCLUDED.code,
-	result = EXCLUDED.result,
-	logs = EXCLUDED.logs,
-	info = EXCLUDED.info,
-	tx_raw = EXCLUDED.tx_raw,
-	messages = EXCLUDED.messages,
-	extension_options = EXCLUDED.extension_options,
-	non_critical_extension_options = EXCLUDED.non_critical_extension_options,
-	auth_info = EXCLUDED.auth_info,
-	signatures = EXCLUDED.signatures,
-	gas_wanted = EXCLUDED.gas_wanted,
-	gas_used = EXCLUDED.gas_used,
-	memo = EXCLUDED.memo,
-	raw_log = EXCLUDED.raw_log`
-)
-
-// StoreTransactions adds transactions to storage buffer
-func (d *Driver) StoreTransactions(ctx context.Context, txs []structs.Transaction) (err error) {
-	tx, err := d.db.Begin()
-	if err != nil {
-		return err
-	}
-
-	for _, t := range txs {
-
-		mf, err := getMarshaledFields(t)
-		if err != nil {
-			return err
-		}
-
-		if err = d.storeTx(ctx, t, mf); err != nil {
-			return err
-		}
-
-	}
-
-	return tx.Commit()
-}
+const txColumns = `height, hash, block_hash, time, code_space, code, result, logs, info, tx_raw, messages, extension_options,
+	non_critical_extension_options, auth_info, signatures, gas_wanted, gas_used, memo, raw_log, parties`
 
 type marshaledFields struct {
 	authInfo                    []byte
@@ -99,14 +52,6 @@ func getMarshaledFields(tx structs.Transaction) (mf marshaledFields, err error)
 	return
 }
 
-func (d *Driver) storeTx(ctx context.Context, t structs.Transaction, mf marshaledFields) (err error) {
-	_, err = d.db.ExecContext(ctx, txInsert, t.ChainID, t.Height, t.Hash, t.BlockHash, t.Time, t.CodeSpace, t.Code, t.Result, mf.logs,
-		t.Info, mf.txRaw, mf.messages, mf.extensionOptions, mf.nonCriticalExtensionOptions, mf.authInfo, pq.Array(t.Signatures),
-		t.GasWanted, t.GasUsed, t.Memo, t.RawLog)
-
-	return err
-}
-
 // Removes ASCII hex 0-7 causing utf-8 error in db
 func removeCharacters(r rune) rune {
 	if r < 7 {
@@ -115,31 +60,60 @@ func removeCharacters(r rune) rune {
 	return r
 }
 
-// GetTransactions gets transactions based on given criteria the order is forced to be time DESC
-func (d *Driver) GetTransactionsByParam(ctx context.Context, chainID string, param string, value interface{}) (txs []structs.Transaction, err error) {
+// FindTransactions returns transactions of the chain matching the filter ordered by height and hash
+func (d *Driver) FindTransactions(ctx context.Context, chainID string, f structs.TxFilter) (txs []structs.Transaction, err error) {
+	txq, args, err := txFilterQuery(chainID, f)
+	if err != nil {
+		return nil, err
+	}
 
-	txq := `SELECT height, hash, block_hash, time, code_space, code, result, logs, info, tx_raw, messages, extension_options,
-	non_critical_extension_options, auth_info, signatures, gas_wanted, gas_used, memo, raw_log
-	FROM public.transactions WHERE chain_id = $1 AND ` + param + ` = $2` // (lukanus): so errorprone! thanks god it's just a demo ;)
-	rows, err := d.db.QueryContext(ctx, txq, chainID, value)
+	rows, err := d.db.QueryContext(ctx, txq, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	if rows == nil {
-		return nil, sql.ErrNoRows
+	return scanTransactions(chainID, rows)
+}
+
+// GetTransactions returns transactions of the chain from the height range ordered by height and hash,
+// starting after the cursor
+func (d *Driver) GetTransactions(ctx context.Context, q structs.RangeQuery) (txs []structs.Transaction, err error) {
+	args := []interface{}{q.ChainID, q.From, maxHeight(q.To), q.Limit}
+	txq := `SELECT ` + txColumns + `
+	FROM public.transactions WHERE chain_id = $1 AND height BETWEEN $2 AND $3`
+	if q.After != nil {
+		txq += ` AND (height, hash) > ($5, $6)`
+		args = append(args, q.After.Height, q.After.Hash)
 	}
+	if q.Address != "" {
+		args = append(args, q.Address)
+		txq += ` AND parties @> ARRAY[$` + strconv.Itoa(len(args)) + `]::text[]`
+	}
+	txq, args = timeRange(txq, args, q)
+	txq += ` ORDER BY height, hash LIMIT $4`
 
+	rows, err := d.db.QueryContext(ctx, txq, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTransactions(q.ChainID, rows)
+}
+
+// scanTransactions reads the rows of txColumns
+func scanTransactions(chainID string, rows *sql.Rows) (txs []structs.Transaction, err error) {
 	for rows.Next() {
 		var authInfoBytes, eoBytes, logsBytes, msgsBytes, nceoBytes, txRawBytes []byte
-		var signatures pq.StringArray
+		var signatures, parties pq.StringArray
 		tx := structs.Transaction{
 			ChainID: chainID,
 		}
 
 		err = rows.Scan(&tx.Height, &tx.Hash, &tx.BlockHash, &tx.Time, &tx.CodeSpace, &tx.Code, &tx.Result, &logsBytes,
 			&tx.Info, &txRawBytes, &msgsBytes, &eoBytes, &nceoBytes, &authInfoBytes, &signatures,
-			&tx.GasWanted, &tx.GasUsed, &tx.Memo, &tx.RawLog)
+			&tx.GasWanted, &tx.GasUsed, &tx.Memo, &tx.RawLog, &parties)
 		if err != nil {
 			return nil, err
 		}
@@ -149,6 +123,10 @@ func (d *Driver) GetTransactionsByParam(ctx context.Context, chainID string, par
 			tx.Signatures[i] = signature
 		}
 
+		if len(parties) > 0 {
+			tx.Parties = []string(parties)
+		}
+
 		if err = json.Unmarshal(authInfoBytes, &tx.AuthInfo); err != nil {
 			return txs, err
 		}
@@ -176,5 +154,5 @@ func (d *Driver) GetTransactionsByParam(ctx context.Context, chainID string, par
 		txs = append(txs, tx)
 	}
 
-	return txs, nil
+	return txs, rows.Err()
 }
@@ -3,8 +3,10 @@ package store
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/figment-networks/graph-demo/manager/structs"
+	"github.com/google/uuid"
 )
 
 var (
@@ -17,11 +19,40 @@ type Storager interface {
 
 	StoreBlock(ctx context.Context, bl structs.Block) error
 	StoreTransactions(ctx context.Context, txs []structs.Transaction) error
+	// StoreHeights stores the blocks with their transactions atomically
+	StoreHeights(ctx context.Context, heights []structs.BlockAndTx) error
 	GetBlockByHeight(ctx context.Context, height uint64, chainID string) (structs.Block, error)
-	GetTransactionsByParam(ctx context.Context, chainID string, param string, value interface{}) ([]structs.Transaction, error)
+	// GetBlockAtTime returns the latest block of the chain created at or before given time
+	GetBlockAtTime(ctx context.Context, chainID string, t time.Time) (structs.Block, error)
+	FindTransactions(ctx context.Context, chainID string, f structs.TxFilter) ([]structs.Transaction, error)
+	GetBlocks(ctx context.Context, q structs.RangeQuery) ([]structs.Block, error)
+	GetTransactions(ctx context.Context, q structs.RangeQuery) ([]structs.Transaction, error)
+	GetMessages(ctx context.Context, q structs.MessageQuery) ([]structs.Message, error)
+	// GetTransactionStats returns the aggregates of transactions grouped as the query asks
+	GetTransactionStats(ctx context.Context, q structs.StatsQuery) ([]structs.TransactionStats, error)
 
 	SetLatestHeight(ctx context.Context, chainID string, height uint64) (err error)
 	GetLatestHeight(ctx context.Context, chainID string) (height uint64, err error)
+	GetLatestHeights(ctx context.Context) (heights map[string]uint64, err error)
+
+	GetMissingHeights(ctx context.Context, chainID string, from, to uint64) (heights []uint64, err error)
+	GetTxCountMismatches(ctx context.Context, chainID string, from, to uint64) (heights []uint64, err error)
+	// SetVerifiedHeight stores the height below which the data of the chain was checked to be consistent
+	SetVerifiedHeight(ctx context.Context, chainID string, below uint64) error
+	// GetVerifiedHeights returns the height below which the data was checked to be consistent, for every chain
+	GetVerifiedHeights(ctx context.Context) (heights map[string]uint64, err error)
+
+	// PruneHeights removes the data of the chain below given height
+	PruneHeights(ctx context.Context, chainID string, below uint64) error
+	// SlimHeights drops heavy columns of the chain's data below given height
+	SlimHeights(ctx context.Context, chainID string, below uint64) error
+	// GetPrunedHeights returns the height below which the data is pruned, for every chain
+	GetPrunedHeights(ctx context.Context) (heights map[string]uint64, err error)
+
+	CreateJob(ctx context.Context, j structs.BackfillJob) (structs.BackfillJob, error)
+	UpdateJob(ctx context.Context, j structs.BackfillJob) error
+	GetJob(ctx context.Context, id uuid.UUID) (structs.BackfillJob, error)
+	GetJobs(ctx context.Context, chainID string, statuses ...structs.JobStatus) ([]structs.BackfillJob, error)
 }
 
 type Store struct {
@@ -46,12 +77,36 @@ func (s *Store) StoreTransactions(ctx context.Context, txs []structs.Transaction
 	return s.driver.StoreTransactions(ctx, txs)
 }
 
+func (s *Store) StoreHeights(ctx context.Context, heights []structs.BlockAndTx) error {
+	return s.driver.StoreHeights(ctx, heights)
+}
+
 func (s *Store) GetBlockByHeight(ctx context.Context, height uint64, chainID string) (structs.Block, error) {
 	return s.driver.GetBlockByHeight(ctx, height, chainID)
 }
 
-func (s *Store) GetTransactionsByParam(ctx context.Context, chainID string, param string, value interface{}) ([]structs.Transaction, error) {
-	return s.driver.GetTransactionsByParam(ctx, chainID, param, value)
+func (s *Store) GetBlockAtTime(ctx context.Context, chainID string, t time.Time) (structs.Block, error) {
+	return s.driver.GetBlockAtTime(ctx, chainID, t)
+}
+
+func (s *Store) FindTransactions(ctx context.Context, chainID string, f structs.TxFilter) ([]structs.Transaction, error) {
+	return s.driver.FindTransactions(ctx, chainID, f)
+}
+
+func (s *Store) GetBlocks(ctx context.Context, q structs.RangeQuery) ([]structs.Block, error) {
+	return s.driver.GetBlocks(ctx, q)
+}
+
+func (s *Store) GetTransactions(ctx context.Context, q structs.RangeQuery) ([]structs.Transaction, error) {
+	return s.driver.GetTransactions(ctx, q)
+}
+
+func (s *Store) GetMessages(ctx context.Context, q structs.MessageQuery) ([]structs.Message, error) {
+	return s.driver.GetMessages(ctx, q)
+}
+
+func (s *Store) GetTransactionStats(ctx context.Context, q structs.StatsQuery) ([]structs.TransactionStats, error) {
+	return s.driver.GetTransactionStats(ctx, q)
 }
 
 func (s *Store) GetLatestHeight(ctx context.Context, chainID string) (height uint64, err error) {
@@ -61,3 +116,51 @@ func (s *Store) GetLatestHeight(ctx context.Context, chainID string) (height uin
 func (s *Store) SetLatestHeight(ctx context.Context, chainID string, height uint64) (err error) {
 	return s.driver.SetLatestHeight(ctx, chainID, height)
 }
+
+func (s *Store) GetLatestHeights(ctx context.Context) (heights map[string]uint64, err error) {
+	return s.driver.GetLatestHeights(ctx)
+}
+
+func (s *Store) GetMissingHeights(ctx context.Context, chainID string, from, to uint64) (heights []uint64, err error) {
+	return s.driver.GetMissingHeights(ctx, chainID, from, to)
+}
+
+func (s *Store) GetTxCountMismatches(ctx context.Context, chainID string, from, to uint64) (heights []uint64, err error) {
+	return s.driver.GetTxCountMismatches(ctx, chainID, from, to)
+}
+
+func (s *Store) SetVerifiedHeight(ctx context.Context, chainID string, below uint64) error {
+	return s.driver.SetVerifiedHeight(ctx, chainID, below)
+}
+
+func (s *Store) GetVerifiedHeights(ctx context.Context) (heights map[string]uint64, err error) {
+	return s.driver.GetVerifiedHeights(ctx)
+}
+
+func (s *Store) PruneHeights(ctx context.Context, chainID string, below uint64) error {
+	return s.driver.PruneHeights(ctx, chainID, below)
+}
+
+func (s *Store) SlimHeights(ctx context.Context, chainID string, below uint64) error {
+	return s.driver.SlimHeights(ctx, chainID, below)
+}
+
+func (s *Store) GetPrunedHeights(ctx context.Context) (heights map[string]uint64, err error) {
+	return s.driver.GetPrunedHeights(ctx)
+}
+
+func (s *Store) CreateJob(ctx context.Context, j structs.BackfillJob) (structs.BackfillJob, error) {
+	return s.driver.CreateJob(ctx, j)
+}
+
+func (s *Store) UpdateJob(ctx context.Context, j structs.BackfillJob) error {
+	return s.driver.UpdateJob(ctx, j)
+}
+
+func (s *Store) GetJob(ctx context.Context, id uuid.UUID) (structs.BackfillJob, error) {
+	return s.driver.GetJob(ctx, id)
+}
+
+func (s *Store) GetJobs(ctx context.Context, chainID string, statuses ...structs.JobStatus) ([]structs.BackfillJob, error) {
+	return s.driver.GetJobs(ctx, chainID, statuses...)
+}
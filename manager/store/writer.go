@@ -0,0 +1,144 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+)
+
+// stagedTTL is the time the staged height is kept before it's dropped. Heights are either committed
+// or discarded long before that, the ones left are written by workers after their height was discarded.
+const stagedTTL = 10 * time.Minute
+
+type heightKey struct {
+	chainID string
+	height  uint64
+}
+
+type stagedHeight struct {
+	structs.BlockAndTx
+	// expires is the time the height is dropped if it's not committed
+	expires time.Time
+}
+
+// Writer is the Storager staging the block and transactions stored by worker while it fetches a height.
+// Staged height is written atomically once it's committed; committed heights are written in batches
+// of up to batchSize heights, or right away when commit asks to flush.
+type Writer struct {
+	Storager
+
+	batchSize int
+	ttl       time.Duration
+	now       func() time.Time
+
+	staged map[heightKey]*stagedHeight
+	batch  []structs.BlockAndTx
+	l      sync.Mutex
+
+	// serializes flushes, so flush returns after all the heights committed before it are written
+	flushL sync.Mutex
+}
+
+// NewWriter creates Writer writing through st, batchSize lower than 1 writes every height on its own
+func NewWriter(st Storager, batchSize int) *Writer {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	return &Writer{
+		Storager:  st,
+		batchSize: batchSize,
+		ttl:       stagedTTL,
+		now:       time.Now,
+		staged:    make(map[heightKey]*stagedHeight),
+	}
+}
+
+// StoreBlock stages the block until its height is committed
+func (w *Writer) StoreBlock(ctx context.Context, b structs.Block) error {
+	w.l.Lock()
+	defer w.l.Unlock()
+
+	h := w.stagedHeight(heightKey{b.ChainID, b.Height})
+	h.Block = b
+	return nil
+}
+
+// StoreTransactions stages the transactions until their height is committed
+func (w *Writer) StoreTransactions(ctx context.Context, txs []structs.Transaction) error {
+	w.l.Lock()
+	defer w.l.Unlock()
+
+	for _, tx := range txs {
+		h := w.stagedHeight(heightKey{tx.ChainID, tx.Height})
+		h.Transactions = append(h.Transactions, tx)
+	}
+	return nil
+}
+
+// stagedHeight returns the staged height, creating it if needed. Expired heights are dropped first.
+func (w *Writer) stagedHeight(k heightKey) *stagedHeight {
+	now := w.now()
+	for key, h := range w.staged {
+		if now.After(h.expires) {
+			delete(w.staged, key)
+		}
+	}
+
+	h, ok := w.staged[k]
+	if !ok {
+		h = &stagedHeight{expires: now.Add(w.ttl)}
+		w.staged[k] = h
+	}
+	return h
+}
+
+// Commit moves the staged data of the height to the write batch. The batch is written when it's full
+// or when flush is set.
+func (w *Writer) Commit(ctx context.Context, chainID string, height uint64, flush bool) error {
+	w.l.Lock()
+	k := heightKey{chainID, height}
+	if h, ok := w.staged[k]; ok {
+		w.batch = append(w.batch, h.BlockAndTx)
+		delete(w.staged, k)
+	}
+	full := len(w.batch) >= w.batchSize
+	w.l.Unlock()
+
+	if flush || full {
+		return w.Flush(ctx)
+	}
+	return nil
+}
+
+// Discard drops the staged data of the height, after its fetching failed.
+// Data stored for the height after that is staged again, until it expires.
+func (w *Writer) Discard(chainID string, height uint64) {
+	w.l.Lock()
+	defer w.l.Unlock()
+	delete(w.staged, heightKey{chainID, height})
+}
+
+// Flush writes the batch of committed heights. The batch is kept to be written by the next flush if it fails.
+func (w *Writer) Flush(ctx context.Context) error {
+	w.flushL.Lock()
+	defer w.flushL.Unlock()
+
+	w.l.Lock()
+	batch := w.batch
+	w.batch = nil
+	w.l.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	if err := w.Storager.StoreHeights(ctx, batch); err != nil {
+		w.l.Lock()
+		w.batch = append(batch, w.batch...)
+		w.l.Unlock()
+		return err
+	}
+	return nil
+}
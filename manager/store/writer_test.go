@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/figment-networks/graph-demo/manager/structs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// storagerMock records the written heights
+type storagerMock struct {
+	Storager
+	written []structs.BlockAndTx
+}
+
+func (sm *storagerMock) StoreHeights(ctx context.Context, heights []structs.BlockAndTx) error {
+	sm.written = append(sm.written, heights...)
+	return nil
+}
+
+func TestWriterCommit(t *testing.T) {
+	sm := &storagerMock{}
+	w := NewWriter(sm, 2)
+	ctx := context.Background()
+
+	require.NoError(t, w.StoreBlock(ctx, structs.Block{ChainID: "chain", Height: 1}))
+	require.NoError(t, w.StoreTransactions(ctx, []structs.Transaction{{ChainID: "chain", Height: 1, Hash: "TX1"}}))
+	require.NoError(t, w.Commit(ctx, "chain", 1, false))
+	assert.Empty(t, sm.written, "batch is not full")
+
+	require.NoError(t, w.StoreBlock(ctx, structs.Block{ChainID: "chain", Height: 2}))
+	require.NoError(t, w.Commit(ctx, "chain", 2, false))
+	require.Len(t, sm.written, 2)
+	assert.Equal(t, uint64(1), sm.written[0].Block.Height)
+	assert.Len(t, sm.written[0].Transactions, 1)
+	assert.Empty(t, w.staged)
+}
+
+func TestWriterDropsLateWrites(t *testing.T) {
+	sm := &storagerMock{}
+	w := NewWriter(sm, 1)
+	ctx := context.Background()
+
+	now := time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC)
+	w.now = func() time.Time { return now }
+
+	require.NoError(t, w.StoreBlock(ctx, structs.Block{ChainID: "chain", Height: 1}))
+	w.Discard("chain", 1)
+	assert.Empty(t, w.staged)
+
+	// reply of the worker that came after its height was discarded
+	require.NoError(t, w.StoreBlock(ctx, structs.Block{ChainID: "chain", Height: 1}))
+	require.NoError(t, w.StoreTransactions(ctx, []structs.Transaction{{ChainID: "chain", Height: 1, Hash: "TX1"}}))
+	assert.Len(t, w.staged, 1)
+
+	now = now.Add(stagedTTL + time.Second)
+	require.NoError(t, w.StoreBlock(ctx, structs.Block{ChainID: "chain", Height: 2}))
+	assert.Len(t, w.staged, 1, "late write is dropped once it expires")
+	_, ok := w.staged[heightKey{"chain", 2}]
+	assert.True(t, ok)
+
+	require.NoError(t, w.Commit(ctx, "chain", 2, true))
+	require.Len(t, sm.written, 1)
+	assert.Equal(t, uint64(2), sm.written[0].Block.Height)
+}
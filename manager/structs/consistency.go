@@ -0,0 +1,30 @@
+package structs
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HeightRange is an inclusive range of heights
+type HeightRange struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+}
+
+// ConsistencyReport contains findings of the consistency check of a single chain
+type ConsistencyReport struct {
+	ChainID   string    `json:"chain_id"`
+	CheckedAt time.Time `json:"checked_at"`
+	// Checked - range of heights that was checked
+	Checked HeightRange `json:"checked"`
+
+	// MissingHeights - ranges of heights without stored block
+	MissingHeights []HeightRange `json:"missing_heights"`
+	// TxCountMismatches - heights where number of stored transactions differs from the block data
+	TxCountMismatches []uint64 `json:"tx_count_mismatches"`
+
+	// ScheduledJobs - backfill jobs created to fix the findings
+	ScheduledJobs []uuid.UUID `json:"scheduled_jobs"`
+	Error         string      `json:"error,omitempty"`
+}
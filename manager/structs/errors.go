@@ -0,0 +1,54 @@
+package structs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors of processing heights shared by manager and workers
+var (
+	ErrWorkerDisconnected = errors.New("worker disconnected")
+	ErrNodeTimeout        = errors.New("node timeout")
+	ErrHeightNotAvailable = errors.New("height not yet available")
+	ErrMapping            = errors.New("mapping error")
+)
+
+// Error codes used in JSON-RPC responses of the worker (implementation-defined server errors range)
+const (
+	ErrCodeWorkerDisconnected int64 = -32001
+	ErrCodeNodeTimeout        int64 = -32002
+	ErrCodeHeightNotAvailable int64 = -32003
+	ErrCodeMapping            int64 = -32004
+	ErrCodeUnknown            int64 = -32000
+)
+
+var codedErrors = map[int64]error{
+	ErrCodeWorkerDisconnected: ErrWorkerDisconnected,
+	ErrCodeNodeTimeout:        ErrNodeTimeout,
+	ErrCodeHeightNotAvailable: ErrHeightNotAvailable,
+	ErrCodeMapping:            ErrMapping,
+}
+
+// ErrorCode returns JSON-RPC error code of the error
+func ErrorCode(err error) int64 {
+	for code, e := range codedErrors {
+		if errors.Is(err, e) {
+			return code
+		}
+	}
+	return ErrCodeUnknown
+}
+
+// ErrorFromCode recreates the error sent with given JSON-RPC error code
+func ErrorFromCode(code int64, message string) error {
+	if e, ok := codedErrors[code]; ok {
+		return fmt.Errorf("%w: %s", e, message)
+	}
+	return errors.New(message)
+}
+
+// ErrorResponse is the body of failed HTTP response of the worker, code is the same as in JSON-RPC errors
+type ErrorResponse struct {
+	Code    int64  `json:"code"`
+	Message string `json:"message"`
+}
@@ -5,22 +5,66 @@ const (
 	EVENT_NEW_TRANSACTION = "newTransaction"
 )
 
+// EventSchemaVersions are the versions of event payload schemas sent to runners, by event name
+var EventSchemaVersions = map[string][]string{
+	EVENT_NEW_BLOCK:       {"1"},
+	EVENT_NEW_TRANSACTION: {"1"},
+}
+
 type EventNewBlock struct {
-	ID     string `json:"id"`
-	Height uint64 `json:"height"`
+	ID      string `json:"id"`
+	ChainID string `json:"chain_id"`
+	Height  uint64 `json:"height"`
 }
 
 type EventNewTransaction struct {
-	Height uint64 `json:"height"`
-	Hash   string `json:"hash"`
+	ChainID string `json:"chain_id"`
+	Height  uint64 `json:"height"`
+	Hash    string `json:"hash"`
 }
 
+// Subs is a subscription for event, ChainID limits the events to given chain (empty means all chains)
 type Subs struct {
 	Name           string
+	ChainID        string
 	StartingHeight uint64
 }
 
+// Capabilities of the worker for a chain - the names of methods it serves
+const (
+	CapabilityGetAll    = "get_all"
+	CapabilityGetLatest = "get_latest"
+)
+
+// Register is sent by worker to register for processing of given chains
 type Register struct {
-	Name    string `json:"name"`
-	ChainID string `json:"chainID"`
+	Name   string          `json:"name"`
+	Chains []RegisterChain `json:"chains"`
+}
+
+type RegisterChain struct {
+	ChainID string `json:"chain_id"`
+	// Capabilities - if empty, worker is expected to serve all the methods
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// Supports checks if worker serves all the given methods for the chain
+func (rc RegisterChain) Supports(capabilities ...string) bool {
+	if len(rc.Capabilities) == 0 {
+		return true
+	}
+
+	for _, c := range capabilities {
+		var found bool
+		for _, rcc := range rc.Capabilities {
+			if rcc == c {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,145 @@
+package structs
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// TxField is the field transactions can be filtered by
+type TxField string
+
+const (
+	TxFieldHash      TxField = "hash"
+	TxFieldHeight    TxField = "height"
+	TxFieldBlockHash TxField = "block_hash"
+	TxFieldMemo      TxField = "memo"
+	TxFieldCode      TxField = "code"
+	TxFieldTime      TxField = "time"
+	// TxFieldSigner is the sender of transaction messages
+	TxFieldSigner TxField = "signer"
+	// TxFieldAddress is any of the addresses involved in transaction
+	TxFieldAddress TxField = "address"
+)
+
+// Operator compares the field with the value of condition
+type Operator string
+
+const (
+	OpEq  Operator = "eq"
+	OpNeq Operator = "neq"
+	OpLt  Operator = "lt"
+	OpLte Operator = "lte"
+	OpGt  Operator = "gt"
+	OpGte Operator = "gte"
+	// OpIn matches any of the values given as slice
+	OpIn Operator = "in"
+	// OpContains matches text containing the value
+	OpContains Operator = "contains"
+)
+
+type valueKind int
+
+const (
+	kindString valueKind = iota
+	kindUint
+	kindTime
+)
+
+type fieldRule struct {
+	kind      valueKind
+	operators []Operator
+}
+
+var (
+	orderOperators = []Operator{OpEq, OpNeq, OpLt, OpLte, OpGt, OpGte, OpIn}
+
+	txFieldRules = map[TxField]fieldRule{
+		TxFieldHash:      {kindString, []Operator{OpEq, OpIn}},
+		TxFieldBlockHash: {kindString, []Operator{OpEq, OpIn}},
+		TxFieldHeight:    {kindUint, orderOperators},
+		TxFieldCode:      {kindUint, orderOperators},
+		TxFieldMemo:      {kindString, []Operator{OpEq, OpContains}},
+		TxFieldTime:      {kindTime, []Operator{OpEq, OpLt, OpLte, OpGt, OpGte}},
+		TxFieldSigner:    {kindString, []Operator{OpEq}},
+		TxFieldAddress:   {kindString, []Operator{OpEq}},
+	}
+)
+
+// TxCondition compares the field of transaction with the value.
+// Values are string for hashes, memo, signer and address, uint64 for height and code and time.Time for time,
+// or the slices of them for OpIn.
+type TxCondition struct {
+	Field TxField
+	Op    Operator
+	Value interface{}
+}
+
+// TxFilter selects transactions matching all the conditions, up to Limit of them if it's not 0
+type TxFilter struct {
+	Conditions []TxCondition
+	Limit      uint64
+}
+
+// Validate checks that the filter uses only known fields with the operators and values allowed for them
+func (f TxFilter) Validate() error {
+	if len(f.Conditions) == 0 {
+		return fmt.Errorf("%w: no conditions", ErrInvalidFilter)
+	}
+
+	for _, c := range f.Conditions {
+		if err := c.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks that the condition uses known field with the operator and value allowed for it
+func (c TxCondition) Validate() error {
+	rule, ok := txFieldRules[c.Field]
+	if !ok {
+		return fmt.Errorf("%w: unknown field %q", ErrInvalidFilter, c.Field)
+	}
+
+	var allowed bool
+	for _, op := range rule.operators {
+		if op == c.Op {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("%w: operator %q is not allowed for field %q", ErrInvalidFilter, c.Op, c.Field)
+	}
+
+	if !rule.kind.matches(c.Value, c.Op == OpIn) {
+		return fmt.Errorf("%w: unexpected value %T of field %q", ErrInvalidFilter, c.Value, c.Field)
+	}
+	return nil
+}
+
+func (k valueKind) matches(v interface{}, slice bool) bool {
+	switch k {
+	case kindString:
+		if slice {
+			s, ok := v.([]string)
+			return ok && len(s) > 0
+		}
+		_, ok := v.(string)
+		return ok
+	case kindUint:
+		if slice {
+			s, ok := v.([]uint64)
+			return ok && len(s) > 0
+		}
+		_, ok := v.(uint64)
+		return ok
+	case kindTime:
+		_, ok := v.(time.Time)
+		return ok && !slice
+	}
+	return false
+}
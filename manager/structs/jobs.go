@@ -0,0 +1,36 @@
+package structs
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type JobStatus string
+
+const (
+	JobStatusRunning   JobStatus = "running"
+	JobStatusPaused    JobStatus = "paused"
+	JobStatusCancelled JobStatus = "cancelled"
+	JobStatusFinished  JobStatus = "finished"
+)
+
+// BackfillJob is a request to (re)index heights [From, To] of given chain
+type BackfillJob struct {
+	ID uuid.UUID `json:"id"`
+	// Created at
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	// Updated at
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+
+	ChainID string `json:"chain_id"`
+	From    uint64 `json:"from"`
+	To      uint64 `json:"to"`
+	// Current - the highest height up to which all the heights are indexed
+	Current uint64    `json:"current"`
+	Status  JobStatus `json:"status"`
+
+	// LastError - last error that occurred while processing the job
+	LastError  string `json:"last_error,omitempty"`
+	ErrorCount uint64 `json:"error_count"`
+}
@@ -0,0 +1,69 @@
+package structs
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// Message is the decoded message of transaction
+type Message struct {
+	ChainID string `json:"chain_id"`
+	Height  uint64 `json:"height"`
+	TxHash  string `json:"tx_hash"`
+	// Index is the position of message in transaction
+	Index uint64 `json:"index"`
+	// Type is the type URL of message
+	Type string `json:"type"`
+	// Module is the module handling message, taken from the package of its type
+	Module string `json:"module"`
+	// Value is the message decoded to protobuf JSON
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// MessageQuery selects up to Limit messages of the chain ordered by height, transaction hash and index.
+// Empty Type and Module, and Height equal 0 match any message.
+type MessageQuery struct {
+	ChainID string
+	Type    string
+	Module  string
+	Height  uint64
+	Limit   uint64
+}
+
+var versionRe = regexp.MustCompile(`^v[0-9]+((alpha|beta)[0-9]*)?$`)
+
+// TransactionMessages returns the decoded messages of transaction
+func TransactionMessages(tx Transaction) []Message {
+	if len(tx.Messages) == 0 {
+		return nil
+	}
+
+	msgs := make([]Message, len(tx.Messages))
+	for i, m := range tx.Messages {
+		msgs[i] = Message{
+			ChainID: tx.ChainID,
+			Height:  tx.Height,
+			TxHash:  tx.Hash,
+			Index:   uint64(i),
+			Type:    m.TypeURL,
+			Module:  MessageModule(m.TypeURL),
+		}
+		if json.Valid(m.Value) {
+			msgs[i].Value = json.RawMessage(m.Value)
+		}
+	}
+	return msgs
+}
+
+// MessageModule returns the module of message type URL, the last segment of its package that is not a version
+// ("/cosmos.bank.v1beta1.MsgSend" is "bank", "/ibc.applications.transfer.v1.MsgTransfer" is "transfer")
+func MessageModule(typeURL string) string {
+	segments := strings.Split(strings.TrimPrefix(typeURL, "/"), ".")
+	for i := len(segments) - 2; i >= 0; i-- {
+		if !versionRe.MatchString(segments[i]) {
+			return segments[i]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,42 @@
+package structs
+
+import "errors"
+
+// ErrPruned is returned for the heights removed from storage by retention policy
+var ErrPruned = errors.New("height is pruned")
+
+// RetentionPolicy describes which heights of the chain are kept in storage, relative to the latest processed height.
+// Zero values disable the rules, heights kept by either KeepLast or KeepFrom are kept.
+type RetentionPolicy struct {
+	// KeepLast - number of the latest heights kept
+	KeepLast uint64 `json:"keep_last"`
+	// KeepFrom - the lowest height kept
+	KeepFrom uint64 `json:"keep_from"`
+	// SlimAfter - number of the latest heights kept with heavy columns (last commit of blocks, raw log of transactions)
+	SlimAfter uint64 `json:"slim_after"`
+}
+
+// PruneBelow returns the height below which the data is removed, 0 if nothing is
+func (rp RetentionPolicy) PruneBelow(latest uint64) uint64 {
+	if rp.KeepLast == 0 {
+		return rp.KeepFrom
+	}
+
+	if latest <= rp.KeepLast {
+		return 0
+	}
+
+	below := latest - rp.KeepLast + 1
+	if rp.KeepFrom > 0 && rp.KeepFrom < below {
+		return rp.KeepFrom
+	}
+	return below
+}
+
+// SlimBelow returns the height below which heavy columns are dropped, 0 if nothing is
+func (rp RetentionPolicy) SlimBelow(latest uint64) uint64 {
+	if rp.SlimAfter == 0 || latest <= rp.SlimAfter {
+		return 0
+	}
+	return latest - rp.SlimAfter + 1
+}
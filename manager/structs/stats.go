@@ -0,0 +1,38 @@
+package structs
+
+// StatsGroup is the grouping of transaction statistics
+type StatsGroup string
+
+const (
+	StatsByHour        StatsGroup = "HOUR"
+	StatsByDay         StatsGroup = "DAY"
+	StatsByMessageType StatsGroup = "MESSAGE_TYPE"
+)
+
+// StatsQuery selects statistics of transactions of the chain from heights [From, To] (To equal 0 leaves the range open),
+// grouped by GroupBy and ordered by group. At most Limit groups are returned.
+type StatsQuery struct {
+	ChainID string
+	From    uint64
+	To      uint64
+	GroupBy StatsGroup
+	Limit   uint64
+}
+
+// TransactionStats is the aggregate of the transactions of one group. Grouped by message type, a transaction
+// is counted in every group of its messages' types with its whole gas and fees, so the groups overlap.
+type TransactionStats struct {
+	// Time is the start of the hour or day in unix seconds, set for HOUR and DAY groups
+	Time int64 `json:"time,omitempty"`
+	// MessageType is the type URL of messages, set for MESSAGE_TYPE groups
+	MessageType string `json:"message_type,omitempty"`
+
+	TxCount       uint64 `json:"tx_count"`
+	FailedTxCount uint64 `json:"failed_tx_count"`
+	// MessageCount is the number of messages, of the type of the group for MESSAGE_TYPE groups
+	MessageCount uint64 `json:"message_count"`
+	GasWanted    uint64 `json:"gas_wanted"`
+	GasUsed      uint64 `json:"gas_used"`
+	// Fees are the sums of fees paid per denomination, ordered by denomination
+	Fees []Coin `json:"fees"`
+}
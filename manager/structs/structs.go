@@ -12,7 +12,27 @@ var (
 
 type BlockEvidence string
 
-type QueriesResp map[string]map[uint64]BlockAndTx
+// Cursor is the position in the list of records ordered by height and hash
+type Cursor struct {
+	Height uint64
+	Hash   string
+}
+
+// RangeQuery selects up to Limit records of the chain from heights [From, To] ordered by height.
+// To equal 0 leaves the range open, the records up to the After cursor are skipped.
+// Non empty Address selects only the transactions involving it, non zero TimeFrom and TimeTo
+// select only the records of that time range (inclusive).
+type RangeQuery struct {
+	ChainID  string
+	From     uint64
+	To       uint64
+	TimeFrom time.Time
+	TimeTo   time.Time
+	After    *Cursor
+	Limit    uint64
+	Address  string
+}
+
 type BlockAndTx struct {
 	BlockID      BlockID       `json:"block_id,omitempty"`
 	Block        Block         `json:"block"`
@@ -154,6 +174,9 @@ type Transaction struct {
 	RawLog []byte `json:"raw_log,omitempty"`
 	// TxRaw - Raw transaction bytes
 	TxRaw Any `json:"tx_raw,omitempty"`
+
+	// Parties - addresses involved in transaction
+	Parties []string `json:"parties,omitempty"`
 }
 
 type Log struct {
@@ -184,9 +207,15 @@ type SignerInfo struct {
 }
 
 type Fee struct {
-	Amount    *big.Int `json:"amount,omitempty"`
-	Currency  string   `json:"currency"`
-	GasLimit  uint64   `json:"gas_limit"`
-	Sender    string   `json:"payer"`
-	Recipient string   `json:"grater"`
+	// Amount - fee paid in every denomination
+	Amount    []Coin `json:"amount,omitempty"`
+	GasLimit  uint64 `json:"gas_limit"`
+	Sender    string `json:"payer"`
+	Recipient string `json:"grater"`
+}
+
+// Coin is an amount of given denomination
+type Coin struct {
+	Denom  string   `json:"denom"`
+	Amount *big.Int `json:"amount"`
 }
@@ -11,21 +11,32 @@ import (
 
 type Sub interface {
 	Send(ctx context.Context, height uint64, name string, resp json.RawMessage) error
+	// SendBatch delivers events of the same height at once
+	SendBatch(ctx context.Context, height uint64, events []Payload) error
 
 	ID() string
+	// ChainID of events the subscriber receives, empty for all chains
+	ChainID() string
 
 	FromHeight() uint64
 	CurrentHeight() uint64
 }
 
+// Payload is the encoded event delivered to subscriber
+type Payload struct {
+	Name string
+	Data json.RawMessage
+}
+
 type Evt struct {
-	EvType string
-	Height uint64
-	Data   interface{}
+	EvType  string
+	ChainID string
+	Height  uint64
+	Data    interface{}
 }
 
 type Handle struct {
-	in        chan Evt
+	in        chan []Evt
 	l         sync.RWMutex
 	log       *zap.Logger
 	endpoints map[string]Sub
@@ -36,27 +47,40 @@ func NewHandle() *Handle {
 	return &Handle{
 		endpoints: make(map[string]Sub),
 		finish:    make(chan struct{}),
-		in:        make(chan Evt, 10),
+		in:        make(chan []Evt, 10),
 	}
 }
 
 func (h *Handle) AddEndpoint(s Sub) {
 	h.l.Lock()
 	defer h.l.Unlock()
-	h.endpoints[s.ID()] = s
+	h.endpoints[s.ID()+"/"+s.ChainID()] = s
 }
 
 func (h *Handle) RemoveEndpoint(id string) {
 	h.l.Lock()
 	defer h.l.Unlock()
-	delete(h.endpoints, id)
+	for k, s := range h.endpoints {
+		if s.ID() == id {
+			delete(h.endpoints, k)
+		}
+	}
 }
 
 func (h *Handle) Send(ctx context.Context, ev Evt) error {
+	return h.SendBatch(ctx, []Evt{ev})
+}
+
+// SendBatch sends events that are delivered to subscribers together, i.e. events of a whole block
+func (h *Handle) SendBatch(ctx context.Context, evts []Evt) error {
+	if len(evts) == 0 {
+		return nil
+	}
+
 	select {
 	case <-ctx.Done():
 		return errors.New("error sending event context done")
-	case h.in <- ev:
+	case h.in <- evts:
 	}
 	return nil
 }
@@ -69,20 +93,35 @@ func (h *Handle) Run(ctx context.Context) {
 			return
 		case <-ctx.Done():
 			return
-		case evt := <-h.in:
-			mD, err := json.Marshal(evt.Data)
-			if err != nil {
-				h.log.Error("error marshaing response", zap.Any("data", evt.Data))
+		case evts := <-h.in:
+			payloads := make([]Payload, 0, len(evts))
+			for _, evt := range evts {
+				mD, err := json.Marshal(evt.Data)
+				if err != nil {
+					h.log.Error("error marshaing response", zap.Any("data", evt.Data))
+					continue
+				}
+				payloads = append(payloads, Payload{Name: evt.EvType, Data: mD})
+			}
+			if len(payloads) == 0 {
 				continue
 			}
+
 			h.l.RLock()
 			for _, sub := range h.endpoints {
+				if c := sub.ChainID(); c != "" && c != evts[0].ChainID {
+					continue
+				}
 				select {
 				case <-ctx.Done():
 					h.l.RUnlock()
 					return
 				default:
-					sub.Send(ctx, evt.Height, evt.EvType, mD)
+					if len(payloads) == 1 {
+						sub.Send(ctx, evts[0].Height, payloads[0].Name, payloads[0].Data)
+					} else {
+						sub.SendBatch(ctx, evts[0].Height, payloads)
+					}
 				}
 			}
 			h.l.RUnlock()
@@ -103,7 +142,7 @@ func NewSubscriptions() *Subscriptions {
 
 // PopulateEvent - We populate events using heights, only to indicate a point time.
 // It might be something else in different networks
-func (s *Subscriptions) PopulateEvent(ctx context.Context, evType string, height uint64, data interface{}) error {
+func (s *Subscriptions) PopulateEvent(ctx context.Context, evType, chainID string, height uint64, data interface{}) error {
 	s.l.RLock()
 	defer s.l.RUnlock()
 
@@ -112,7 +151,34 @@ func (s *Subscriptions) PopulateEvent(ctx context.Context, evType string, height
 		return nil
 	}
 
-	return t.Send(ctx, Evt{EvType: evType, Height: height, Data: data})
+	return t.Send(ctx, Evt{EvType: evType, ChainID: chainID, Height: height, Data: data})
+}
+
+// PopulateBatch populates events of the same chain and height, events of each type are delivered
+// to subscribers in a single batch
+func (s *Subscriptions) PopulateBatch(ctx context.Context, chainID string, height uint64, evts []Evt) error {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	byType := make(map[string][]Evt)
+	var order []string
+	for _, e := range evts {
+		if _, ok := s.types[e.EvType]; !ok { // noone is subscribed
+			continue
+		}
+		if _, ok := byType[e.EvType]; !ok {
+			order = append(order, e.EvType)
+		}
+		e.ChainID, e.Height = chainID, height
+		byType[e.EvType] = append(byType[e.EvType], e)
+	}
+
+	for _, evType := range order {
+		if err := s.types[evType].SendBatch(ctx, byType[evType]); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *Subscriptions) Add(ctx context.Context, ev string, sub Sub) error {
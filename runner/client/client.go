@@ -14,8 +14,14 @@ type EventClient interface {
 	NewEvent(typ string, data map[string]interface{}) error
 }
 
+// Tracker is notified about every successfully processed event
+type Tracker interface {
+	Processed(event, chainID string, height uint64)
+}
+
 type NetworkGraphClient struct {
 	ec EventClient
+	t  Tracker
 
 	registry     map[string]connectivity.Handler
 	registrySync sync.RWMutex
@@ -32,6 +38,10 @@ func NewNetworkGraphClient(l *zap.Logger, ec EventClient) *NetworkGraphClient {
 	return ph
 }
 
+func (ng *NetworkGraphClient) LinkTracker(t Tracker) {
+	ng.t = t
+}
+
 func (ng *NetworkGraphClient) EventHandler(ctx context.Context, req connectivity.Request, resp connectivity.Response) {
 	args := req.Arguments()
 	data := make(map[string]interface{})
@@ -40,8 +50,14 @@ func (ng *NetworkGraphClient) EventHandler(ctx context.Context, req connectivity
 		ng.l.Error("unmarshal error", zap.Error(err))
 	}
 
-	if err := ng.ec.NewEvent(strings.Replace(string(args[0]), `"`, "", -1), data); err != nil {
+	name := strings.Replace(string(args[0]), `"`, "", -1)
+	if err := ng.ec.NewEvent(name, data); err != nil {
 		ng.l.Error("new event error", zap.Error(err))
+	} else if ng.t != nil {
+		chainID, _ := data["chain_id"].(string)
+		if height, ok := data["height"].(float64); ok {
+			ng.t.Processed(name, chainID, uint64(height))
+		}
 	}
 
 	resp.Send(json.RawMessage([]byte(`"ACK"`)), nil)
@@ -11,6 +11,7 @@ import (
 type GQLPayload struct {
 	Query     string                 `json:"query"`
 	Variables map[string]interface{} `json:"variables"`
+	Version   string                 `json:"version,omitempty"`
 }
 
 type GQLResponse struct {
@@ -34,7 +35,7 @@ func (ng *NetworkGraphHTTPTransport) CallGQL(ctx context.Context, name string, q
 	buff := new(bytes.Buffer)
 	defer buff.Reset()
 	enc := json.NewEncoder(buff)
-	if err := enc.Encode(GQLPayload{query, variables}); err != nil {
+	if err := enc.Encode(GQLPayload{query, variables, version}); err != nil {
 		return nil, err
 	}
 
@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"sync"
 
 	"github.com/figment-networks/graph-demo/connectivity"
+	"github.com/figment-networks/graph-demo/connectivity/handshake"
 	wsapi "github.com/figment-networks/graph-demo/connectivity/ws"
 	"github.com/figment-networks/graph-demo/runner/structs"
-	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 )
 
@@ -22,32 +24,121 @@ type GQLResponse struct {
 	Errors []interface{} `json:"errors"`
 }
 
+// EventSchemaVersions are the versions of event payload schemas runner understands, by event name
+var EventSchemaVersions = map[string][]string{
+	"newBlock":       {"1"},
+	"newTransaction": {"1"},
+}
+
+// managerMethods are the methods runner calls in manager
+var managerMethods = []string{"query", "subscribe", "unsubscribe"}
+
 type NetworkGraphWSTransport struct {
-	c    *websocket.Conn
-	sess *wsapi.Session
-	l    *zap.Logger
+	cli *wsapi.Client
+	l   *zap.Logger
+
+	// query schema versions served by manager, nil if manager doesn't do handshake
+	queryVersions []string
+	qvL           sync.RWMutex
+
+	// subscriptions to restore after reconnect, by event name and chain id
+	subs  map[string]structs.Subs
+	subsL sync.Mutex
 }
 
 func NewNetworkGraphWSTransport(l *zap.Logger) *NetworkGraphWSTransport {
 	ph := &NetworkGraphWSTransport{
-		l: l,
+		l:    l,
+		subs: make(map[string]structs.Subs),
 	}
 	return ph
 }
 
-func (ng *NetworkGraphWSTransport) Connect(ctx context.Context, address string, RH connectivity.FunctionCallHandler) (err error) {
-	ng.c, _, err = websocket.DefaultDialer.DialContext(ctx, address, nil)
+// Connect connects to the manager. Connection is restored after it's lost,
+// re-issuing all the subscriptions starting from the height following the last processed one.
+func (ng *NetworkGraphWSTransport) Connect(ctx context.Context, address string, RH connectivity.FunctionCallHandler, opts wsapi.DialOptions) error {
+	ng.cli = wsapi.NewClient(ng.l, address, RH, wsapi.DefaultReconnectConfig)
+	ng.cli.Apply(opts)
+	ng.cli.OnConnect(func(ctx context.Context, ss wsapi.SyncSender) error {
+		return ng.handshake(ctx, ss, opts.Version)
+	})
+	ng.cli.OnConnect(ng.resubscribe)
+
+	return ng.cli.Connect(ctx)
+}
+
+// handshake checks if manager speaks compatible protocol and event schemas, and learns its query schema versions
+func (ng *NetworkGraphWSTransport) handshake(ctx context.Context, ss wsapi.SyncSender, version string) error {
+	res, legacy, err := handshake.Do(ctx, ss, handshake.Hello{
+		Component: "runner",
+		Version:   version,
+		Protocol:  handshake.ProtocolVersion,
+		Methods:   []string{"event"},
+		Events:    EventSchemaVersions,
+	}, managerMethods)
 	if err != nil {
 		return err
 	}
-	ng.sess = wsapi.NewSession(ctx, ng.c, ng.l, RH)
-	go ng.sess.Recv()
-	go ng.sess.Req()
 
+	if legacy {
+		ng.l.Warn("manager doesn't support handshake, protocol compatibility is not checked")
+		return nil
+	}
+
+	ng.l.Info("handshake with manager", zap.String("version", res.Remote.Version), zap.String("protocol", res.Remote.Protocol), zap.Strings("query_versions", res.Remote.QueryVersions))
+	ng.qvL.Lock()
+	ng.queryVersions = res.Remote.QueryVersions
+	ng.qvL.Unlock()
 	return nil
 }
 
+// checkQueryVersion fails fast for the query schema versions manager is known not to serve
+func (ng *NetworkGraphWSTransport) checkQueryVersion(version string) error {
+	ng.qvL.RLock()
+	defer ng.qvL.RUnlock()
+
+	if version == "" || ng.queryVersions == nil || handshake.Supports(ng.queryVersions, version) {
+		return nil
+	}
+	return fmt.Errorf("query schema version %q is not served by manager, supported versions: %v", version, ng.queryVersions)
+}
+
+func (ng *NetworkGraphWSTransport) resubscribe(ctx context.Context, ss wsapi.SyncSender) error {
+	ng.subsL.Lock()
+	events := make([]structs.Subs, 0, len(ng.subs))
+	for _, sub := range ng.subs {
+		events = append(events, sub)
+	}
+	ng.subsL.Unlock()
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	return ng.subscribe(ctx, ss, events)
+}
+
+// Processed marks the height of event as processed, so it's not requested again after reconnect
+func (ng *NetworkGraphWSTransport) Processed(event, chainID string, height uint64) {
+	ng.subsL.Lock()
+	defer ng.subsL.Unlock()
+
+	for k, sub := range ng.subs {
+		if sub.Name != event || (sub.ChainID != "" && sub.ChainID != chainID) {
+			continue
+		}
+		if height+1 > sub.StartingHeight {
+			sub.StartingHeight = height + 1
+			ng.subs[k] = sub
+		}
+	}
+}
+
 func (ng *NetworkGraphWSTransport) CallGQL(ctx context.Context, name string, query string, variables map[string]interface{}, version string) ([]byte, error) {
+	if err := ng.checkQueryVersion(version); err != nil {
+		return nil, err
+	}
+
 	buff := new(bytes.Buffer)
 	defer buff.Reset()
 	enc := json.NewEncoder(buff)
@@ -64,12 +155,25 @@ func (ng *NetworkGraphWSTransport) CallGQL(ctx context.Context, name string, que
 		return nil, err
 	}
 
-	resp, err := ng.sess.SendSync("query", []json.RawMessage{q, buff.Bytes(), v})
+	resp, err := ng.cli.SendSync(ctx, "query", []json.RawMessage{q, buff.Bytes(), v})
 	buff.Reset()
 	return resp.Result, err
 }
 
 func (ng *NetworkGraphWSTransport) Subscribe(ctx context.Context, events []structs.Subs) error {
+	if err := ng.subscribe(ctx, ng.cli, events); err != nil {
+		return err
+	}
+
+	ng.subsL.Lock()
+	for _, ev := range events {
+		ng.subs[ev.Name+"/"+ev.ChainID] = ev
+	}
+	ng.subsL.Unlock()
+	return nil
+}
+
+func (ng *NetworkGraphWSTransport) subscribe(ctx context.Context, ss wsapi.SyncSender, events []structs.Subs) error {
 	buff := new(bytes.Buffer)
 	defer buff.Reset()
 	enc := json.NewEncoder(buff)
@@ -77,7 +181,7 @@ func (ng *NetworkGraphWSTransport) Subscribe(ctx context.Context, events []struc
 		return err
 	}
 
-	_, err := ng.sess.SendSync("subscribe", []json.RawMessage{buff.Bytes()})
+	_, err := ss.SendSync(ctx, "subscribe", []json.RawMessage{buff.Bytes()})
 	buff.Reset()
 
 	return err
@@ -91,8 +195,21 @@ func (ng *NetworkGraphWSTransport) Unsubscribe(ctx context.Context, events []str
 		return err
 	}
 
-	_, err := ng.sess.SendSync("unsubscribe", []json.RawMessage{buff.Bytes()})
+	_, err := ng.cli.SendSync(ctx, "unsubscribe", []json.RawMessage{buff.Bytes()})
 	buff.Reset()
+	if err != nil {
+		return err
+	}
 
-	return err
+	ng.subsL.Lock()
+	for k, sub := range ng.subs {
+		for _, ev := range events {
+			if sub.Name == ev {
+				delete(ng.subs, k)
+			}
+		}
+	}
+	ng.subsL.Unlock()
+
+	return nil
 }
@@ -52,6 +52,8 @@ type DataSourcesMapping struct {
 }
 
 type DataSourcesSource struct {
+	// ChainID - chain the events are received from, all chains if empty
+	ChainID    string `yaml:"chainId"`
 	StartBlock uint64 `yaml:"startBlock"`
 }
 
@@ -106,7 +108,7 @@ func (s *Schemas) LoadFromSubgraphYaml(fpath string) error {
 		ms := make(map[string]string)
 
 		for _, evh := range sourc.Mapping.EventHandlers {
-			subs = append(subs, structs.Subs{Name: evh.Event, StartingHeight: sourc.Source.StartBlock})
+			subs = append(subs, structs.Subs{Name: evh.Event, ChainID: sourc.Source.ChainID, StartingHeight: sourc.Source.StartBlock})
 			ms[evh.Event] = evh.Handler
 		}
 
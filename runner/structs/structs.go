@@ -2,5 +2,6 @@ package structs
 
 type Subs struct {
 	Name           string
+	ChainID        string
 	StartingHeight uint64
 }
@@ -28,10 +28,12 @@ export interface BlockResponse {
 }
 
 export interface BlockEvent {
+    chain_id: string;
     height: number;
 }
 
 export interface TransactionEvent {
+    chain_id: string;
     hash: string,
     height: number;
 }
@@ -45,7 +45,7 @@ var GET_BLOCK = "query GetBlock($height: Int = 0, $chain_id: String = \"mainnet\
  */
 function handleBlock(newBlockEvent) {
     graph_1.log.debug('newBlockEvent: ' + JSON.stringify(newBlockEvent));
-    var _a = graph_1.graphql.call("cosmos", GET_BLOCK, { height: newBlockEvent.height, chain_id: "cosmoshub-4" }, "0.0.1"), error = _a.error, data = _a.data;
+    var _a = graph_1.graphql.call("cosmos", GET_BLOCK, { height: newBlockEvent.height, chain_id: newBlockEvent.chain_id }, "0.0.1"), error = _a.error, data = _a.data;
     if (error) {
         graph_1.log.debug('GQL call error: ' + JSON.stringify(error));
         return;
@@ -68,7 +68,7 @@ function handleBlock(newBlockEvent) {
 var GET_TRANSACTIONS = "query GetTransactions($hash: String, $chain_id: String = \"mainnet\") {\n  transaction(hash: $hash, chain_id: $chain_id) {\n    hash\n    height\n    time\n  }\n}";
 function handleTransaction(newTxnEvent) {
     graph_1.log.debug('newTxnEvent: ' + JSON.stringify(newTxnEvent));
-    var _a = graph_1.graphql.call("cosmos", GET_TRANSACTIONS, { hash: newTxnEvent.hash, chain_id: "cosmoshub-4" }, "0.0.1"), error = _a.error, data = _a.data;
+    var _a = graph_1.graphql.call("cosmos", GET_TRANSACTIONS, { hash: newTxnEvent.hash, chain_id: newTxnEvent.chain_id }, "0.0.1"), error = _a.error, data = _a.data;
     if (error) {
         graph_1.log.debug('GQL call error: ' + JSON.stringify(error));
         return;